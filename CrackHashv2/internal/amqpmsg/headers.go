@@ -0,0 +1,71 @@
+// Package amqpmsg — общие для менеджера и воркера части сообщений RabbitMQ: подпись,
+// dead-letter очереди, заголовки корреляции и контекст трассы.
+package amqpmsg
+
+import (
+	"context"
+	"log/slog"
+
+	"CrackHash/internal/logging"
+
+	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+)
+
+// Заголовки AMQP, по которым строки лога одного запроса связываются между менеджером
+// и воркерами, даже если тело сообщения не удалось проверить или разобрать.
+const (
+	AMQPRequestID  = "x-request-id"
+	AMQPPartNumber = "x-part-number"
+)
+
+// WithCorrelation добавляет в сообщение ID запроса, номер части и контекст трассы из ctx.
+func WithCorrelation(ctx context.Context, p amqp091.Publishing, requestID string, partNumber int) amqp091.Publishing {
+	if p.Headers == nil {
+		p.Headers = amqp091.Table{}
+	}
+	p.Headers[AMQPRequestID] = requestID
+	p.Headers[AMQPPartNumber] = int32(partNumber)
+	otel.GetTextMapPropagator().Inject(ctx, amqpCarrier(p.Headers))
+	return p
+}
+
+// DeliveryContext восстанавливает контекст трассы отправителя из заголовков сообщения.
+func DeliveryContext(ctx context.Context, d amqp091.Delivery) context.Context {
+	if d.Headers == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, amqpCarrier(d.Headers))
+}
+
+// amqpCarrier даёт пропагатору OpenTelemetry читать и писать заголовки AMQP (traceparent и др.).
+type amqpCarrier amqp091.Table
+
+func (c amqpCarrier) Get(key string) string {
+	v, _ := c[key].(string)
+	return v
+}
+
+func (c amqpCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c amqpCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// DeliveryLogger — логгер компонента с ID запроса и номером части из заголовков сообщения.
+func DeliveryLogger(component string, d amqp091.Delivery) *slog.Logger {
+	logger := logging.Component(component)
+	if id, ok := d.Headers[AMQPRequestID].(string); ok {
+		logger = logger.With(logging.KeyRequestID, id)
+	}
+	if part, ok := d.Headers[AMQPPartNumber].(int32); ok {
+		logger = logger.With(logging.KeyPartNumber, int(part))
+	}
+	return logger
+}
@@ -0,0 +1,53 @@
+package amqpmsg
+
+import (
+	"fmt"
+
+	"CrackHash/internal/signing"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// DeadLetterQueue — очередь для отклонённых сообщений: неподписанных, с неверной подписью
+// или неразборчивых. Сообщения там лежат для разбора вручную и никем не потребляются.
+func DeadLetterQueue(queue string) string {
+	return queue + ".dead"
+}
+
+func SignedPublishing(keys *signing.Keyring, body []byte) amqp091.Publishing {
+	keyID, signature := keys.Sign(body)
+	return amqp091.Publishing{
+		ContentType:  "application/xml",
+		Body:         body,
+		DeliveryMode: amqp091.Persistent,
+		Headers: amqp091.Table{
+			signing.AMQPKeyID:     keyID,
+			signing.AMQPSignature: signature,
+		},
+	}
+}
+
+func VerifyDelivery(keys *signing.Keyring, d amqp091.Delivery) error {
+	keyID, _ := d.Headers[signing.AMQPKeyID].(string)
+	signature, _ := d.Headers[signing.AMQPSignature].(string)
+	return keys.Verify(keyID, signature, d.Body)
+}
+
+// DeadLetter перекладывает сообщение в <queue>.dead с причиной отказа в заголовке.
+func DeadLetter(ch *amqp091.Channel, queue string, d amqp091.Delivery, reason error) error {
+	headers := amqp091.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[signing.AMQPRejectReason] = reason.Error()
+	err := ch.Publish("", DeadLetterQueue(queue), false, false, amqp091.Publishing{
+		ContentType:  d.ContentType,
+		Body:         d.Body,
+		DeliveryMode: amqp091.Persistent,
+		Headers:      headers,
+	})
+	if err != nil {
+		return fmt.Errorf("не удалось отправить сообщение в %s: %w", DeadLetterQueue(queue), err)
+	}
+	return nil
+}
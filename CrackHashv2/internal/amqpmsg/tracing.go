@@ -0,0 +1,30 @@
+package amqpmsg
+
+import (
+	"context"
+
+	"CrackHash/internal/logging"
+	"CrackHash/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartSpan открывает спан операции с сообщением по соглашениям OpenTelemetry для
+// очередей: имя "<destination> <operation>", producer для publish и consumer для process.
+func StartSpan(ctx context.Context, operation, destination string, requestID string, partNumber int) (context.Context, trace.Span) {
+	kind := trace.SpanKindConsumer
+	if operation == "publish" {
+		kind = trace.SpanKindProducer
+	}
+	return tracing.Tracer().Start(ctx, destination+" "+operation,
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitmq,
+			semconv.MessagingOperationName(operation),
+			semconv.MessagingDestinationName(destination),
+			attribute.String(logging.KeyRequestID, requestID),
+			attribute.Int(logging.KeyPartNumber, partNumber),
+		))
+}
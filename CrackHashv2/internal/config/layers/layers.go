@@ -0,0 +1,326 @@
+// Package layers собирает конфигурацию менеджера и воркера из слоёв: значения по
+// умолчанию, YAML-файл, переменные окружения и флаги.
+package layers
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Field описывает один параметр конфигурации во всех слоях: ключ YAML-файла,
+// переменную окружения и флаг (ключ в kebab-case). ptr — *string, *int,
+// *time.Duration или *[]string. Redact скрывает секрет в --print-config.
+type Field struct {
+	Key    string
+	Env    string
+	Usage  string
+	Ptr    any
+	Redact func(string) string
+}
+
+const redacted = "***"
+
+// HideValue заменяет непустой секрет целиком.
+func HideValue(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// HidePassword оставляет в URI всё, кроме пароля. url.Parse не подходит: URI MongoDB
+// со списком хостов он не разбирает.
+func HidePassword(s string) string {
+	scheme, rest, ok := strings.Cut(s, "://")
+	if !ok {
+		return s
+	}
+	authority, path, _ := strings.Cut(rest, "/")
+	userinfo, hosts, ok := strings.Cut(authority, "@")
+	if !ok {
+		return s
+	}
+	user, _, hasPassword := strings.Cut(userinfo, ":")
+	if !hasPassword {
+		return s
+	}
+	out := scheme + "://" + user + ":" + redacted + "@" + hosts
+	if strings.Contains(rest, "/") {
+		out += "/" + path
+	}
+	return out
+}
+
+func flagName(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+			b.WriteByte('-')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// set разбирает строковое значение из окружения или флага; source попадает в текст ошибки.
+func (f Field) set(source, raw string) error {
+	switch p := f.Ptr.(type) {
+	case *string:
+		*p = raw
+	case *int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%s: %q не целое число", source, raw)
+		}
+		*p = n
+	case *time.Duration:
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%s: %q не длительность (например 30s, 5m)", source, raw)
+		}
+		*p = d
+	case *[]string:
+		var list []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		*p = list
+	default:
+		panic(fmt.Sprintf("layers: неподдерживаемый тип поля %s", f.Key))
+	}
+	return nil
+}
+
+// decode читает значение из YAML-узла; строки разбираются так же, как в окружении.
+func (f Field) decode(path string, node *yaml.Node) error {
+	source := fmt.Sprintf("%s:%d: %s", path, node.Line, f.Key)
+	if list, ok := f.Ptr.(*[]string); ok && node.Kind == yaml.SequenceNode {
+		var items []string
+		if err := node.Decode(&items); err != nil {
+			return fmt.Errorf("%s: ожидается список строк", source)
+		}
+		*list = items
+		return nil
+	}
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("%s: ожидается скалярное значение", source)
+	}
+	return f.set(source, node.Value)
+}
+
+func (f Field) value() any {
+	switch p := f.Ptr.(type) {
+	case *string:
+		if f.Redact != nil {
+			return f.Redact(*p)
+		}
+		return *p
+	case *int:
+		return *p
+	case *time.Duration:
+		return p.String()
+	case *[]string:
+		return *p
+	}
+	return nil
+}
+
+// applyFile накладывает YAML-файл. Неизвестные ключи — ошибка, чтобы опечатка
+// не превращалась в молча применённое значение по умолчанию.
+func applyFile(path string, fields []Field) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("не удалось прочитать файл конфигурации: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s: ожидается словарь параметров", path)
+	}
+	byKey := make(map[string]Field, len(fields))
+	for _, f := range fields {
+		byKey[f.Key] = f
+	}
+	var errs []error
+	for i := 0; i < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		f, ok := byKey[key.Value]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s:%d: неизвестный параметр %q", path, key.Line, key.Value))
+			continue
+		}
+		if err := f.decode(path, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// applyEnv накладывает переменные окружения; пустая переменная считается незаданной.
+func applyEnv(fields []Field) error {
+	var errs []error
+	for _, f := range fields {
+		if raw := os.Getenv(f.Env); raw != "" {
+			if err := f.set(f.Env, raw); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Cmdline — разобранная командная строка. Значения флагов применяются последними,
+// поэтому они сначала только запоминаются.
+type Cmdline struct {
+	ConfigFile  string
+	PrintConfig bool
+	values      []func() error
+}
+
+func parseFlags(name string, args []string, fields []Field) (Cmdline, error) {
+	var c Cmdline
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&c.ConfigFile, "config", "", "YAML-файл конфигурации (или CONFIG_FILE)")
+	fs.BoolVar(&c.PrintConfig, "print-config", false, "вывести итоговую конфигурацию без секретов и выйти")
+	for _, f := range fields {
+		f := f
+		name := flagName(f.Key)
+		fs.Func(name, f.Usage+" ("+f.Env+")", func(raw string) error {
+			c.values = append(c.values, func() error { return f.set("--"+name, raw) })
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return Cmdline{}, err
+	}
+	if fs.NArg() > 0 {
+		return Cmdline{}, fmt.Errorf("лишние аргументы: %s", strings.Join(fs.Args(), " "))
+	}
+	return c, nil
+}
+
+// Load накладывает слои по возрастанию приоритета: файл < окружение < флаги.
+// Значения по умолчанию уже должны быть в полях.
+func Load(name string, args []string, fields []Field) (Cmdline, error) {
+	c, err := parseFlags(name, args, fields)
+	if err != nil {
+		return Cmdline{}, err
+	}
+	if c.ConfigFile == "" {
+		c.ConfigFile = os.Getenv("CONFIG_FILE")
+	}
+	if c.ConfigFile != "" {
+		if err := applyFile(c.ConfigFile, fields); err != nil {
+			return Cmdline{}, err
+		}
+	}
+	if err := applyEnv(fields); err != nil {
+		return Cmdline{}, err
+	}
+	var errs []error
+	for _, apply := range c.values {
+		errs = append(errs, apply())
+	}
+	return c, errors.Join(errs...)
+}
+
+// Changed возвращает ключи полей, значения которых в a и b различаются; a и b —
+// списки fields() двух конфигураций.
+func Changed(a, b []Field) []string {
+	var keys []string
+	for i := range a {
+		prev := reflect.ValueOf(a[i].Ptr).Elem().Interface()
+		next := reflect.ValueOf(b[i].Ptr).Elem().Interface()
+		if !reflect.DeepEqual(prev, next) {
+			keys = append(keys, a[i].Key)
+		}
+	}
+	return keys
+}
+
+// WriteYAML выводит конфигурацию YAML-документом, который можно использовать как файл --config.
+func WriteYAML(w io.Writer, fields []Field) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, f := range fields {
+		var value yaml.Node
+		if err := value.Encode(f.value()); err != nil {
+			return err
+		}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: f.Key}, &value)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Checks собирает ошибки проверки значений, чтобы при старте показать все сразу.
+type Checks []error
+
+func (c *Checks) Add(ok bool, format string, args ...any) {
+	if !ok {
+		*c = append(*c, fmt.Errorf(format, args...))
+	}
+}
+
+func (c *Checks) Port(env, value string) {
+	n, err := strconv.Atoi(value)
+	c.Add(err == nil && n > 0 && n < 65536, "%s: %q не номер порта", env, value)
+}
+
+func (c *Checks) Positive(env string, d time.Duration) {
+	c.Add(d > 0, "%s: длительность должна быть больше нуля", env)
+}
+
+func (c *Checks) NonEmpty(env, value string) {
+	c.Add(strings.TrimSpace(value) != "", "%s: значение не может быть пустым", env)
+}
+
+func (c *Checks) Scheme(env, value string, schemes ...string) {
+	for _, s := range schemes {
+		if rest, ok := strings.CutPrefix(value, s+"://"); ok && rest != "" {
+			return
+		}
+	}
+	c.Add(false, "%s: %q — ожидается адрес со схемой %s", env, HidePassword(value), strings.Join(schemes, " или "))
+}
+
+func (c *Checks) OneOf(env, value string, allowed ...string) {
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return
+		}
+	}
+	c.Add(false, "%s: %q — допустимо %s", env, value, strings.Join(allowed, ", "))
+}
+
+// Pair требует задать файлы сертификата и ключа вместе.
+func (c *Checks) Pair(certEnv, cert, keyEnv, key string) {
+	c.Add((cert == "") == (key == ""), "%s и %s задаются только вместе", certEnv, keyEnv)
+}
+
+func (c Checks) Err() error {
+	return errors.Join(c...)
+}
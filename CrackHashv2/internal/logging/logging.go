@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Ключи атрибутов, по которым строки одного запроса связываются между менеджером и воркерами.
+const (
+	KeyComponent  = "component"
+	KeyRequestID  = "requestId"
+	KeyPartNumber = "partNumber"
+
+	// HeaderRequestID передаёт ID запроса в HTTP-ответе воркера менеджеру.
+	HeaderRequestID = "X-Request-Id"
+)
+
+// ParseLevel разбирает LOG_LEVEL: debug, info, warn или error.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("неизвестный уровень логирования %q", s)
+}
+
+// Setup делает JSON-логгер уровня level логгером по умолчанию. Стандартный log тоже
+// пишет через него, поэтому оставшиеся log.Printf выводятся JSON-строками уровня INFO.
+func Setup(w io.Writer, level string) error {
+	if err := SetLevel(level); err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: &minLevel})))
+	return nil
+}
+
+// minLevel общий для логгеров Setup, поэтому уровень меняется без пересоздания логгера.
+var minLevel slog.LevelVar
+
+// SetLevel меняет уровень логгера Setup на лету.
+func SetLevel(level string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	minLevel.Set(lvl)
+	return nil
+}
+
+func Component(name string) *slog.Logger {
+	return slog.Default().With(KeyComponent, name)
+}
+
+// Request — логгер компонента с ID запроса.
+func Request(component, requestID string) *slog.Logger {
+	return Component(component).With(KeyRequestID, requestID)
+}
+
+// Part — логгер компонента с ID запроса и номером части задачи.
+func Part(component, requestID string, partNumber int) *slog.Logger {
+	return Request(component, requestID).With(KeyPartNumber, partNumber)
+}
+
+type ctxKey struct{}
+
+// WithLogger кладёт логгер в контекст, чтобы код без ID запроса в параметрах писал с ним.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext возвращает логгер из контекста или логгер по умолчанию.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,47 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/internal/logging"
+)
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{
+		"":        slog.LevelInfo,
+		"debug":   slog.LevelDebug,
+		"INFO":    slog.LevelInfo,
+		"warning": slog.LevelWarn,
+		" error ": slog.LevelError,
+	} {
+		got, err := logging.ParseLevel(in)
+		require.NoError(t, err, in)
+		require.Equal(t, want, got, in)
+	}
+	_, err := logging.ParseLevel("verbose")
+	require.Error(t, err)
+}
+
+func TestSetup_WritesCorrelatedJSON(t *testing.T) {
+	prev := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	var buf bytes.Buffer
+	require.NoError(t, logging.Setup(&buf, "info"))
+
+	logging.Part("managerService", "req-1", 2).Debug("не попадёт в лог")
+	logging.Part("managerService", "req-1", 2).Info("Задача отправлена")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	require.Equal(t, "INFO", line["level"])
+	require.Equal(t, "Задача отправлена", line["msg"])
+	require.Equal(t, "managerService", line[logging.KeyComponent])
+	require.Equal(t, "req-1", line[logging.KeyRequestID])
+	require.Equal(t, float64(2), line[logging.KeyPartNumber])
+}
@@ -0,0 +1,95 @@
+// Package signing подписывает сообщения задач и ответов воркеров HMAC-SHA256.
+// Подпись передаётся вместе с ID ключа, что позволяет ротировать ключи: новый ключ
+// добавляется первым и подписывает сообщения, старые продолжают проверять подписи.
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// HTTP-заголовки и ключи заголовков AMQP-сообщения.
+	HeaderKeyID      = "X-Signature-Key-Id"
+	HeaderSignature  = "X-Signature"
+	AMQPKeyID        = "x-signature-key-id"
+	AMQPSignature    = "x-signature"
+	AMQPRejectReason = "x-rejection-reason"
+
+	minSecretLength = 16
+)
+
+var (
+	ErrUnsigned     = errors.New("сообщение не подписано")
+	ErrUnknownKey   = errors.New("неизвестный ключ подписи")
+	ErrBadSignature = errors.New("подпись не совпадает")
+	ErrNoKeys       = errors.New("ключи подписи не настроены")
+)
+
+type Keyring struct {
+	keys   map[string][]byte
+	active string
+}
+
+// ParseKeys разбирает MESSAGE_SIGNING_KEYS: "id:секрет,id2:секрет2", первый ключ активный.
+// Пустая строка означает, что ключи не настроены: Sign ничего не подписывает,
+// а Verify отклоняет все сообщения.
+func ParseKeys(spec string) (*Keyring, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	k := &Keyring{keys: make(map[string][]byte)}
+	for _, part := range strings.Split(spec, ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("ключ подписи %q: ожидается формат id:секрет", part)
+		}
+		if len(secret) < minSecretLength {
+			return nil, fmt.Errorf("ключ подписи %q: секрет короче %d символов", id, minSecretLength)
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("ключ подписи %q указан повторно", id)
+		}
+		k.keys[id] = []byte(secret)
+		if k.active == "" {
+			k.active = id
+		}
+	}
+	return k, nil
+}
+
+// Sign возвращает ID активного ключа и hex HMAC-SHA256 от тела сообщения.
+func (k *Keyring) Sign(body []byte) (keyID, signature string) {
+	if k == nil {
+		return "", ""
+	}
+	return k.active, hex.EncodeToString(mac(k.keys[k.active], body))
+}
+
+func (k *Keyring) Verify(keyID, signature string, body []byte) error {
+	if k == nil {
+		return ErrNoKeys
+	}
+	if keyID == "" || signature == "" {
+		return ErrUnsigned
+	}
+	secret, ok := k.keys[keyID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, mac(secret, body)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+func mac(secret, body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(body)
+	return h.Sum(nil)
+}
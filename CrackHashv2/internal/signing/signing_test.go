@@ -0,0 +1,58 @@
+package signing_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/internal/signing"
+)
+
+// Известный вектор: подпись не должна меняться, иначе менеджер и воркеры разных версий
+// перестанут понимать друг друга.
+const (
+	vectorKeys      = "k1:0123456789abcdef"
+	vectorBody      = "<CrackHashWorkerResponse></CrackHashWorkerResponse>"
+	vectorSignature = "7aeb94f357c8131a0e1ff97feb109bbfa1bc7965ef9ab5f18a2fc6cb236abea2"
+)
+
+func TestSignVerify(t *testing.T) {
+	keys, err := signing.ParseKeys(vectorKeys)
+	require.NoError(t, err)
+
+	keyID, signature := keys.Sign([]byte(vectorBody))
+	require.Equal(t, "k1", keyID)
+	require.Equal(t, vectorSignature, signature)
+	require.NoError(t, keys.Verify(keyID, signature, []byte(vectorBody)))
+
+	require.ErrorIs(t, keys.Verify(keyID, signature, []byte(vectorBody+" ")), signing.ErrBadSignature)
+	require.ErrorIs(t, keys.Verify("", "", []byte(vectorBody)), signing.ErrUnsigned)
+	require.ErrorIs(t, keys.Verify("k9", signature, []byte(vectorBody)), signing.ErrUnknownKey)
+	require.ErrorIs(t, keys.Verify(keyID, "zz", []byte(vectorBody)), signing.ErrBadSignature)
+}
+
+func TestRotation(t *testing.T) {
+	old, err := signing.ParseKeys("k1:old-secret-0123456")
+	require.NoError(t, err)
+	keyID, signature := old.Sign([]byte("body"))
+
+	rotated, err := signing.ParseKeys("k2:new-secret-0123456,k1:old-secret-0123456")
+	require.NoError(t, err)
+	require.NoError(t, rotated.Verify(keyID, signature, []byte("body")))
+	newID, _ := rotated.Sign([]byte("body"))
+	require.Equal(t, "k2", newID)
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := signing.ParseKeys("")
+	require.NoError(t, err)
+	require.ErrorIs(t, keys.Verify("k1", "00", nil), signing.ErrNoKeys)
+	keyID, signature := keys.Sign(nil)
+	require.Empty(t, keyID)
+	require.Empty(t, signature)
+
+	for _, spec := range []string{"k1", ":0123456789abcdef", "k1:short", "k1:0123456789abcdef,k1:0123456789abcdef"} {
+		_, err := signing.ParseKeys(spec)
+		require.Error(t, err, spec)
+	}
+}
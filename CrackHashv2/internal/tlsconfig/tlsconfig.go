@@ -0,0 +1,94 @@
+// Package tlsconfig собирает *tls.Config менеджера и воркера из PEM-файлов, заданных в config.Config.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Files — пути к PEM-файлам: CA для проверки собеседника и пара сертификат/ключ
+// для предъявления собственного сертификата.
+type Files struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+}
+
+func (f Files) Enabled() bool {
+	return f.CAFile != "" || f.CertFile != "" || f.KeyFile != ""
+}
+
+// Client возвращает настройки клиента: сервер проверяется по CAFile (или системным CA),
+// при заданных CertFile/KeyFile клиент предъявляет сертификат (mutual TLS).
+func Client(f Files) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if f.CAFile != "" {
+		pool, err := loadPool(f.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	cert, ok, err := loadKeyPair(f)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// Server возвращает настройки сервера с сертификатом CertFile/KeyFile. Если задан CAFile,
+// клиенты обязаны предъявить сертификат, подписанный этим CA.
+func Server(f Files) (*tls.Config, error) {
+	cert, ok, err := loadKeyPair(f)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("для TLS-сервера нужны сертификат и ключ")
+	}
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if f.CAFile != "" {
+		pool, err := loadPool(f.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+func loadKeyPair(f Files) (tls.Certificate, bool, error) {
+	if f.CertFile == "" && f.KeyFile == "" {
+		return tls.Certificate{}, false, nil
+	}
+	if f.CertFile == "" || f.KeyFile == "" {
+		return tls.Certificate{}, false, errors.New("сертификат и ключ задаются только вместе")
+	}
+	cert, err := tls.LoadX509KeyPair(f.CertFile, f.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, false, fmt.Errorf("не удалось загрузить сертификат %s: %w", f.CertFile, err)
+	}
+	return cert, true, nil
+}
+
+func loadPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("в %s нет PEM-сертификатов", caFile)
+	}
+	return pool, nil
+}
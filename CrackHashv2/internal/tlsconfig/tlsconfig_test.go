@@ -0,0 +1,57 @@
+package tlsconfig_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/internal/tlsconfig"
+	"CrackHash/internal/tlsconfig/tlstest"
+)
+
+func TestMutualTLS(t *testing.T) {
+	certs := tlstest.Generate(t)
+
+	serverCfg, err := tlsconfig.Server(certs.Server)
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = serverCfg
+	server.StartTLS()
+	defer server.Close()
+
+	clientCfg, err := tlsconfig.Client(certs.Client)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientCfg}}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Без клиентского сертификата сервер с CA обрывает рукопожатие.
+	anonymousCfg, err := tlsconfig.Client(tlsconfig.Files{CAFile: certs.CAFile})
+	require.NoError(t, err)
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: anonymousCfg}}
+	_, err = anonymous.Get(server.URL)
+	require.Error(t, err)
+
+	// Без CA клиент не доверяет самоподписанному серверу.
+	untrusted := &http.Client{Transport: &http.Transport{}}
+	_, err = untrusted.Get(server.URL)
+	require.Error(t, err)
+}
+
+func TestConfigErrors(t *testing.T) {
+	certs := tlstest.Generate(t)
+
+	_, err := tlsconfig.Client(tlsconfig.Files{CertFile: certs.Client.CertFile})
+	require.Error(t, err, "сертификат без ключа")
+	_, err = tlsconfig.Server(tlsconfig.Files{CAFile: certs.CAFile})
+	require.Error(t, err, "сервер без сертификата")
+	_, err = tlsconfig.Client(tlsconfig.Files{CAFile: certs.Client.KeyFile})
+	require.Error(t, err, "в CA-файле нет сертификатов")
+	require.False(t, tlsconfig.Files{}.Enabled())
+}
@@ -0,0 +1,144 @@
+// Package tlstest генерирует для тестов локальный CA и подписанные им сертификаты
+// сервера (localhost, 127.0.0.1) и клиента.
+package tlstest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"CrackHash/internal/tlsconfig"
+)
+
+type Certs struct {
+	CAFile string
+	Server tlsconfig.Files
+	Client tlsconfig.Files
+}
+
+// Generate записывает PEM-файлы во временный каталог теста.
+func Generate(t *testing.T) Certs {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CrackHash test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs := Certs{CAFile: filepath.Join(dir, "ca.pem")}
+	writePEM(t, certs.CAFile, "CERTIFICATE", caDER)
+
+	issue := func(name string, serial int64, usage x509.ExtKeyUsage) tlsconfig.Files {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(24 * time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			DNSNames:     []string{"localhost"},
+			IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := tlsconfig.Files{
+			CAFile:   certs.CAFile,
+			CertFile: filepath.Join(dir, name+".pem"),
+			KeyFile:  filepath.Join(dir, name+"-key.pem"),
+		}
+		writePEM(t, files.CertFile, "CERTIFICATE", der)
+		writePEM(t, files.KeyFile, "PRIVATE KEY", keyDER)
+		return files
+	}
+	certs.Server = issue("server", 2, x509.ExtKeyUsageServerAuth)
+	certs.Client = issue("client", 3, x509.ExtKeyUsageClientAuth)
+	return certs
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Listener — TLS-сервер, требующий клиентский сертификат. Он принимает соединения,
+// завершает рукопожатие и сообщает CommonName клиента; прикладной протокол не реализует,
+// поэтому подходит для проверки TLS-настроек клиентов AMQP и MongoDB без брокера.
+type Listener struct {
+	net.Listener
+	Clients chan string
+}
+
+func Listen(t *testing.T, certs Certs) *Listener {
+	t.Helper()
+	cfg, err := tlsconfig.Server(certs.Server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &Listener{Listener: ln, Clients: make(chan string, 16)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				tlsConn := tls.Server(conn, cfg)
+				if err := tlsConn.Handshake(); err != nil {
+					return
+				}
+				if peers := tlsConn.ConnectionState().PeerCertificates; len(peers) > 0 {
+					select {
+					case l.Clients <- peers[0].Subject.CommonName:
+					default:
+					}
+				}
+			}()
+		}
+	}()
+	return l
+}
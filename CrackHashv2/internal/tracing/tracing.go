@@ -0,0 +1,78 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортёры TRACING_EXPORTER. Адрес коллектора для otlp задаётся стандартными
+// переменными OTEL_EXPORTER_OTLP_ENDPOINT / OTEL_EXPORTER_OTLP_TRACES_ENDPOINT.
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+const instrumentationName = "CrackHash"
+
+var propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Start делает провайдер с опциями opts (экспортёр передаётся через WithBatcher или
+// WithSyncer) глобальным и включает W3C-пропагацию trace context и baggage.
+func Start(service string, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(service))),
+	}, opts...)
+	tp := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagator)
+	return tp
+}
+
+// Setup настраивает трассировку по TRACING_EXPORTER и возвращает функцию, выгружающую
+// оставшиеся спаны при остановке. С none спаны не пишутся, но контекст трассы, пришедший
+// по HTTP или в заголовках RabbitMQ, передаётся дальше.
+func Setup(ctx context.Context, service, exporter string) (func(context.Context) error, error) {
+	switch strings.ToLower(strings.TrimSpace(exporter)) {
+	case "", ExporterNone:
+		otel.SetTextMapPropagator(propagator)
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exp, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("не удалось создать OTLP-экспортёр: %w", err)
+		}
+		return Start(service, sdktrace.WithBatcher(exp)).Shutdown, nil
+	case ExporterStdout:
+		exp, err := stdouttrace.New()
+		if err != nil {
+			return nil, fmt.Errorf("не удалось создать stdout-экспортёр: %w", err)
+		}
+		return Start(service, sdktrace.WithSyncer(exp)).Shutdown, nil
+	}
+	return nil, fmt.Errorf("неизвестный экспортёр трассировки %q", exporter)
+}
+
+// RecordError отмечает спан ошибкой; nil игнорируется.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
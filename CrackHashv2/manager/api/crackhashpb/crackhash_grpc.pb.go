@@ -0,0 +1,245 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: manager/api/crackhashpb/crackhash.proto
+
+package crackhashpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CrackHash_SubmitCrack_FullMethodName = "/crackhash.v1.CrackHash/SubmitCrack"
+	CrackHash_GetStatus_FullMethodName   = "/crackhash.v1.CrackHash/GetStatus"
+	CrackHash_Cancel_FullMethodName      = "/crackhash.v1.CrackHash/Cancel"
+	CrackHash_WatchStatus_FullMethodName = "/crackhash.v1.CrackHash/WatchStatus"
+)
+
+// CrackHashClient is the client API for CrackHash service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CrackHash — gRPC-версия публичного API менеджера. Семантика совпадает с HTTP API:
+// /api/hash/crack, /api/hash/status, /api/hash/cancel и /api/hash/status/stream.
+type CrackHashClient interface {
+	SubmitCrack(ctx context.Context, in *SubmitCrackRequest, opts ...grpc.CallOption) (*SubmitCrackResponse, error)
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+	Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*StatusResponse, error)
+	WatchStatus(ctx context.Context, in *WatchStatusRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusEvent], error)
+}
+
+type crackHashClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCrackHashClient(cc grpc.ClientConnInterface) CrackHashClient {
+	return &crackHashClient{cc}
+}
+
+func (c *crackHashClient) SubmitCrack(ctx context.Context, in *SubmitCrackRequest, opts ...grpc.CallOption) (*SubmitCrackResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubmitCrackResponse)
+	err := c.cc.Invoke(ctx, CrackHash_SubmitCrack_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crackHashClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, CrackHash_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crackHashClient) Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*StatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatusResponse)
+	err := c.cc.Invoke(ctx, CrackHash_Cancel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *crackHashClient) WatchStatus(ctx context.Context, in *WatchStatusRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CrackHash_ServiceDesc.Streams[0], CrackHash_WatchStatus_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchStatusRequest, StatusEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CrackHash_WatchStatusClient = grpc.ServerStreamingClient[StatusEvent]
+
+// CrackHashServer is the server API for CrackHash service.
+// All implementations must embed UnimplementedCrackHashServer
+// for forward compatibility.
+//
+// CrackHash — gRPC-версия публичного API менеджера. Семантика совпадает с HTTP API:
+// /api/hash/crack, /api/hash/status, /api/hash/cancel и /api/hash/status/stream.
+type CrackHashServer interface {
+	SubmitCrack(context.Context, *SubmitCrackRequest) (*SubmitCrackResponse, error)
+	GetStatus(context.Context, *GetStatusRequest) (*StatusResponse, error)
+	Cancel(context.Context, *CancelRequest) (*StatusResponse, error)
+	WatchStatus(*WatchStatusRequest, grpc.ServerStreamingServer[StatusEvent]) error
+	mustEmbedUnimplementedCrackHashServer()
+}
+
+// UnimplementedCrackHashServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCrackHashServer struct{}
+
+func (UnimplementedCrackHashServer) SubmitCrack(context.Context, *SubmitCrackRequest) (*SubmitCrackResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitCrack not implemented")
+}
+func (UnimplementedCrackHashServer) GetStatus(context.Context, *GetStatusRequest) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedCrackHashServer) Cancel(context.Context, *CancelRequest) (*StatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cancel not implemented")
+}
+func (UnimplementedCrackHashServer) WatchStatus(*WatchStatusRequest, grpc.ServerStreamingServer[StatusEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStatus not implemented")
+}
+func (UnimplementedCrackHashServer) mustEmbedUnimplementedCrackHashServer() {}
+func (UnimplementedCrackHashServer) testEmbeddedByValue()                   {}
+
+// UnsafeCrackHashServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CrackHashServer will
+// result in compilation errors.
+type UnsafeCrackHashServer interface {
+	mustEmbedUnimplementedCrackHashServer()
+}
+
+func RegisterCrackHashServer(s grpc.ServiceRegistrar, srv CrackHashServer) {
+	// If the following call pancis, it indicates UnimplementedCrackHashServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CrackHash_ServiceDesc, srv)
+}
+
+func _CrackHash_SubmitCrack_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitCrackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrackHashServer).SubmitCrack(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CrackHash_SubmitCrack_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrackHashServer).SubmitCrack(ctx, req.(*SubmitCrackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CrackHash_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrackHashServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CrackHash_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrackHashServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CrackHash_Cancel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CrackHashServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CrackHash_Cancel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CrackHashServer).Cancel(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CrackHash_WatchStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchStatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CrackHashServer).WatchStatus(m, &grpc.GenericServerStream[WatchStatusRequest, StatusEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CrackHash_WatchStatusServer = grpc.ServerStreamingServer[StatusEvent]
+
+// CrackHash_ServiceDesc is the grpc.ServiceDesc for CrackHash service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CrackHash_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "crackhash.v1.CrackHash",
+	HandlerType: (*CrackHashServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitCrack",
+			Handler:    _CrackHash_SubmitCrack_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _CrackHash_GetStatus_Handler,
+		},
+		{
+			MethodName: "Cancel",
+			Handler:    _CrackHash_Cancel_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchStatus",
+			Handler:       _CrackHash_WatchStatus_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "manager/api/crackhashpb/crackhash.proto",
+}
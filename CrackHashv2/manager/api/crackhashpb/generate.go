@@ -0,0 +1,4 @@
+// Package crackhashpb содержит сгенерированные типы и stubs gRPC API менеджера.
+package crackhashpb
+
+//go:generate protoc -I ../../.. --go_out=../../.. --go_opt=paths=source_relative --go-grpc_out=../../.. --go-grpc_opt=paths=source_relative manager/api/crackhashpb/crackhash.proto
@@ -0,0 +1,334 @@
+// Package client — Go SDK для публичного HTTP API менеджера CrackHash (см. /api/openapi.json).
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type APIError struct {
+	StatusCode int
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Field      string `json:"field,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	if e.Field != "" {
+		return fmt.Sprintf("crackhash: %d %s (%s): %s", e.StatusCode, e.Code, e.Field, e.Message)
+	}
+	return fmt.Sprintf("crackhash: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+type Client struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) {
+		cl.httpClient = c
+	}
+}
+
+// WithAPIKey передаёт ключ в заголовке X-API-Key каждого запроса.
+func WithAPIKey(key string) Option {
+	return func(cl *Client) {
+		cl.apiKey = key
+	}
+}
+
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) Crack(ctx context.Context, req CrackRequest) (string, error) {
+	var resp struct {
+		RequestID string `json:"requestId"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, "/api/hash/crack", nil, req, &resp); err != nil {
+		return "", err
+	}
+	return resp.RequestID, nil
+}
+
+func (c *Client) Status(ctx context.Context, requestID string) (StatusResponse, error) {
+	var resp StatusResponse
+	err := c.doJSON(ctx, http.MethodGet, "/api/hash/status", url.Values{"requestId": {requestID}}, nil, &resp)
+	return resp, err
+}
+
+// ListRequests возвращает запросы тенанта ключа; status и limit необязательны (пустая строка и 0).
+func (c *Client) ListRequests(ctx context.Context, status string, limit int) ([]RequestSummary, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var resp struct {
+		Requests []RequestSummary `json:"requests"`
+	}
+	err := c.doJSON(ctx, http.MethodGet, "/api/hash/requests", query, nil, &resp)
+	return resp.Requests, err
+}
+
+func (c *Client) Cancel(ctx context.Context, requestID string) (StatusResponse, error) {
+	var resp StatusResponse
+	err := c.doJSON(ctx, http.MethodPost, "/api/hash/cancel", url.Values{"requestId": {requestID}}, nil, &resp)
+	return resp, err
+}
+
+// StreamStatus читает SSE-поток и вызывает fn для каждого события, пока fn не вернёт ошибку или поток не закроется.
+func (c *Client) StreamStatus(ctx context.Context, requestID string, fn func(StatusEvent) error) error {
+	httpReq, err := c.newRequest(ctx, http.MethodGet, "/api/hash/status/stream", url.Values{"requestId": {requestID}}, nil, "")
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+	streamClient := *c.httpClient
+	streamClient.Timeout = 0
+	resp, err := streamClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		case line == "" && data.Len() > 0:
+			var e StatusEvent
+			if err := json.Unmarshal([]byte(data.String()), &e); err != nil {
+				return fmt.Errorf("crackhash: некорректное событие: %w", err)
+			}
+			data.Reset()
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (c *Client) SubmitBatch(ctx context.Context, items []BatchItem) (string, error) {
+	var resp struct {
+		BatchID string `json:"batchId"`
+	}
+	if err := c.doJSON(ctx, http.MethodPost, "/api/hash/crack/batch", nil, items, &resp); err != nil {
+		return "", err
+	}
+	return resp.BatchID, nil
+}
+
+// SubmitHashList отправляет список хэшей в формате hashcat ("hash" или "user:hash" на строку).
+func (c *Client) SubmitHashList(ctx context.Context, list io.Reader, maxLength int, algorithm string) (string, error) {
+	query := url.Values{"maxLength": {strconv.Itoa(maxLength)}}
+	if algorithm != "" {
+		query.Set("algorithm", algorithm)
+	}
+	httpReq, err := c.newRequest(ctx, http.MethodPost, "/api/hash/crack/batch", query, list, "text/plain")
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		BatchID string `json:"batchId"`
+	}
+	if err := c.do(httpReq, &resp); err != nil {
+		return "", err
+	}
+	return resp.BatchID, nil
+}
+
+func (c *Client) BatchStatus(ctx context.Context, batchID string) (BatchStatusResponse, error) {
+	var resp BatchStatusResponse
+	err := c.doJSON(ctx, http.MethodGet, "/api/hash/batch/status", url.Values{"batchId": {batchID}}, nil, &resp)
+	return resp, err
+}
+
+// BatchResults возвращает тело выгрузки; format — "csv" или "potfile". Вызывающий закрывает ReadCloser.
+func (c *Client) BatchResults(ctx context.Context, batchID, format string) (io.ReadCloser, error) {
+	return c.download(ctx, "/api/hash/batch/results", url.Values{"batchId": {batchID}, "format": {format}})
+}
+
+func (c *Client) WebhookDeliveries(ctx context.Context, requestID string) ([]WebhookDelivery, error) {
+	var resp []WebhookDelivery
+	err := c.doJSON(ctx, http.MethodGet, "/api/hash/webhooks", url.Values{"requestId": {requestID}}, nil, &resp)
+	return resp, err
+}
+
+func (c *Client) ExportPotfile(ctx context.Context, algorithm string) (io.ReadCloser, error) {
+	return c.download(ctx, "/api/potfile", algorithmQuery(algorithm))
+}
+
+func (c *Client) ImportPotfile(ctx context.Context, algorithm string, potfile io.Reader) (int, error) {
+	httpReq, err := c.newRequest(ctx, http.MethodPost, "/api/potfile", algorithmQuery(algorithm), potfile, "text/plain")
+	if err != nil {
+		return 0, err
+	}
+	var resp struct {
+		Imported int `json:"imported"`
+	}
+	if err := c.do(httpReq, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Imported, nil
+}
+
+// AuditEvents возвращает не более limit событий журнала аудита (нужна роль admin).
+func (c *Client) AuditEvents(ctx context.Context, filter AuditFilter, limit int) ([]AuditEvent, error) {
+	query := auditQuery(filter)
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var events []AuditEvent
+	err := c.doJSON(ctx, http.MethodGet, "/api/admin/audit", query, nil, &events)
+	return events, err
+}
+
+// ExportAudit выгружает журнал аудита в формате JSONL (нужна роль admin).
+func (c *Client) ExportAudit(ctx context.Context, filter AuditFilter) (io.ReadCloser, error) {
+	return c.download(ctx, "/api/admin/audit/export", auditQuery(filter))
+}
+
+func auditQuery(filter AuditFilter) url.Values {
+	query := url.Values{}
+	for name, value := range map[string]string{
+		"action":    filter.Action,
+		"requestId": filter.RequestID,
+		"keyId":     filter.KeyID,
+		"tenant":    filter.Tenant,
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	if !filter.From.IsZero() {
+		query.Set("from", filter.From.Format(time.RFC3339))
+	}
+	if !filter.To.IsZero() {
+		query.Set("to", filter.To.Format(time.RFC3339))
+	}
+	return query
+}
+
+func algorithmQuery(algorithm string) url.Values {
+	if algorithm == "" {
+		return nil
+	}
+	return url.Values{"algorithm": {algorithm}}
+}
+
+func (c *Client) download(ctx context.Context, path string, query url.Values) (io.ReadCloser, error) {
+	httpReq, err := c.newRequest(ctx, http.MethodGet, path, query, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+	httpReq, err := c.newRequest(ctx, method, path, query, reader, contentType)
+	if err != nil {
+		return err
+	}
+	return c.do(httpReq, out)
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string) (*http.Request, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("X-API-Key", c.apiKey)
+	}
+	return httpReq, nil
+}
+
+func (c *Client) do(httpReq *http.Request, out interface{}) error {
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	var envelope struct {
+		Error *APIError `json:"error"`
+	}
+	envelope.Error = apiErr
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err := json.Unmarshal(body, &envelope); err != nil || apiErr.Code == "" {
+		apiErr.Code = http.StatusText(resp.StatusCode)
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	return apiErr
+}
@@ -0,0 +1,103 @@
+package client
+
+import "time"
+
+type CrackRequest struct {
+	Hash        string `json:"hash"`
+	MaxLength   int    `json:"maxLength"`
+	Algorithm   string `json:"algorithm,omitempty"`
+	CallbackURL string `json:"callbackUrl,omitempty"`
+}
+
+type StatusResponse struct {
+	Status   string   `json:"status"`
+	Data     []string `json:"data,omitempty"`
+	Progress int      `json:"progress"`
+}
+
+type RequestSummary struct {
+	RequestID string    `json:"requestId"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Status    string    `json:"status"`
+	Hash      string    `json:"hash"`
+	MaxLength int       `json:"maxLength"`
+	Algorithm string    `json:"algorithm"`
+	Progress  int       `json:"progress"`
+	StartTime time.Time `json:"startTime"`
+}
+
+type StatusEvent struct {
+	Type       string    `json:"type"`
+	RequestID  string    `json:"requestId"`
+	Status     string    `json:"status"`
+	Progress   int       `json:"progress"`
+	PartNumber int       `json:"partNumber,omitempty"`
+	Words      []string  `json:"words,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+type BatchItem struct {
+	User      string `json:"user,omitempty"`
+	Hash      string `json:"hash"`
+	MaxLength int    `json:"maxLength"`
+	Algorithm string `json:"algorithm,omitempty"`
+}
+
+type BatchItemStatus struct {
+	User      string   `json:"user,omitempty"`
+	Hash      string   `json:"hash"`
+	RequestID string   `json:"requestId,omitempty"`
+	Status    string   `json:"status"`
+	Data      []string `json:"data,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+type BatchStatusResponse struct {
+	BatchID    string            `json:"batchId"`
+	Status     string            `json:"status"`
+	Total      int               `json:"total"`
+	Ready      int               `json:"ready"`
+	InProgress int               `json:"inProgress"`
+	Failed     int               `json:"failed"`
+	Rejected   int               `json:"rejected"`
+	Items      []BatchItemStatus `json:"items"`
+}
+
+type DeliveryAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID          string            `json:"id"`
+	RequestID   string            `json:"requestId"`
+	URL         string            `json:"url"`
+	Event       string            `json:"event"`
+	State       string            `json:"state"`
+	Attempts    []DeliveryAttempt `json:"attempts"`
+	NextAttempt time.Time         `json:"nextAttempt,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+}
+
+type AuditEvent struct {
+	ID        string            `json:"id"`
+	Time      time.Time         `json:"time"`
+	Action    string            `json:"action"`
+	KeyID     string            `json:"keyId,omitempty"`
+	KeyName   string            `json:"keyName,omitempty"`
+	Tenant    string            `json:"tenant,omitempty"`
+	Role      string            `json:"role,omitempty"`
+	RequestID string            `json:"requestId,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// AuditFilter — фильтр журнала аудита; пустые поля не фильтруют.
+type AuditFilter struct {
+	Action    string
+	RequestID string
+	KeyID     string
+	Tenant    string
+	From      time.Time
+	To        time.Time
+}
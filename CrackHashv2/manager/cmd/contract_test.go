@@ -0,0 +1,684 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/internal/signing"
+	"CrackHash/manager/client"
+	"CrackHash/manager/internal/audit"
+	"CrackHash/manager/internal/auth"
+	"CrackHash/manager/internal/events"
+	"CrackHash/manager/internal/openapi"
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/store"
+	"CrackHash/manager/internal/types"
+)
+
+const (
+	hashA      = "0cc175b9c0f1b6a831c399e269772661"
+	hashAbc    = "900150983cd24fb0d6963f7d28e17f72"
+	adminToken = "admin-secret"
+)
+
+type contractFixture struct {
+	spec     map[string]interface{}
+	svc      service.ManagerServiceImpl
+	apiKeys  store.APIKeyStore
+	audit    store.AuditStore
+	signing  *signing.Keyring
+	apiKey   string
+	adminKey string
+	server   *httptest.Server
+}
+
+func newContractFixture(t *testing.T) contractFixture {
+	var spec map[string]interface{}
+	require.NoError(t, json.Unmarshal(openapi.Spec, &spec))
+
+	reqStore := store.NewRequestStore()
+	potfile := store.NewPotfileStore()
+	webhooks := store.NewWebhookStore()
+	apiKeys := store.NewAPIKeyStore()
+	bus := events.NewBus(16)
+	signingKeys, err := signing.ParseKeys("k1:contract-signing-secret")
+	require.NoError(t, err)
+	auditStore := store.NewAuditStore()
+	auditLog := audit.NewLogger(auditStore)
+	workers := service.NewWorkerTracker(2, time.Hour)
+	svc := service.NewManagerService(reqStore, nil, time.Minute,
+		service.WithWorkerTracker(workers),
+		service.WithKeyspaceQuota(apiKeys),
+		service.WithAuditLog(auditLog),
+		service.WithPotfile(potfile),
+		service.WithEventPublisher(bus),
+		service.WithBatchStore(store.NewBatchStore()))
+
+	server := httptest.NewServer(newRouter(context.Background(), routerDeps{
+		service:        svc,
+		requests:       reqStore,
+		potfile:        potfile,
+		webhooks:       webhooks,
+		apiKeys:        apiKeys,
+		auditEvents:    auditStore,
+		auditLog:       auditLog,
+		workers:        workers,
+		bus:            bus,
+		streamInterval: 50 * time.Millisecond,
+		adminToken:     adminToken,
+		internalToken:  "internal-secret",
+		signingKeys:    signingKeys,
+	}))
+	t.Cleanup(server.Close)
+
+	f := contractFixture{spec: spec, svc: svc, apiKeys: apiKeys, audit: auditStore, signing: signingKeys, server: server}
+	f.apiKey = f.createKey(t, "contract", "team-a", auth.RoleSubmitter)
+	f.adminKey = f.createKey(t, "ops", "ops", auth.RoleAdmin)
+	return f
+}
+
+func (f contractFixture) createKey(t *testing.T, id, tenant, role string) string {
+	raw, keyHash, err := auth.GenerateKey()
+	require.NoError(t, err)
+	f.apiKeys.Create(store.APIKey{ID: id, Name: id, KeyHash: keyHash, Tenant: tenant, Role: role, CreatedAt: time.Now()})
+	return raw
+}
+
+// call выполняет запрос и проверяет, что код ответа описан в спецификации для операции,
+// а JSON-тело соответствует схеме этого ответа. Возвращает тело ответа.
+func (f contractFixture) call(t *testing.T, method, specPath, query, contentType, body string) (int, []byte) {
+	t.Helper()
+	return f.callAs(t, f.apiKey, method, specPath, query, contentType, body)
+}
+
+func (f contractFixture) callAs(t *testing.T, token, method, specPath, query, contentType, body string) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, f.server.URL+specPath+query, strings.NewReader(body))
+	require.NoError(t, err)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	op := f.operation(t, specPath, method)
+	responses := op["responses"].(map[string]interface{})
+	documented, ok := responses[strconv.Itoa(resp.StatusCode)].(map[string]interface{})
+	require.Truef(t, ok, "%s %s: код %d не описан в спецификации (тело: %s)", method, specPath, resp.StatusCode, data)
+
+	content, _ := documented["content"].(map[string]interface{})
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	media, ok := content[mediaType].(map[string]interface{})
+	require.Truef(t, ok, "%s %s: тип %q для кода %d не описан в спецификации", method, specPath, mediaType, resp.StatusCode)
+
+	if mediaType == "application/json" {
+		var value interface{}
+		require.NoError(t, json.Unmarshal(data, &value))
+		require.NoError(t, f.validate(media["schema"].(map[string]interface{}), value, "$"),
+			"%s %s -> %d: %s", method, specPath, resp.StatusCode, data)
+	}
+	return resp.StatusCode, data
+}
+
+func (f contractFixture) operation(t *testing.T, path, method string) map[string]interface{} {
+	t.Helper()
+	item, ok := f.spec["paths"].(map[string]interface{})[path].(map[string]interface{})
+	require.Truef(t, ok, "путь %s отсутствует в спецификации", path)
+	op, ok := item[strings.ToLower(method)].(map[string]interface{})
+	require.Truef(t, ok, "операция %s %s отсутствует в спецификации", method, path)
+	return op
+}
+
+func (f contractFixture) resolve(schema map[string]interface{}) map[string]interface{} {
+	ref, ok := schema["$ref"].(string)
+	if !ok {
+		return schema
+	}
+	name := strings.TrimPrefix(ref, "#/components/schemas/")
+	return f.spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})[name].(map[string]interface{})
+}
+
+// validate проверяет подмножество JSON Schema, которое используется в спецификации:
+// type, nullable, enum, properties, required, additionalProperties и items.
+func (f contractFixture) validate(schema map[string]interface{}, value interface{}, at string) error {
+	schema = f.resolve(schema)
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: null не допускается", at)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, v := range enum {
+			if v == value {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: значение %v не входит в enum %v", at, value, enum)
+		}
+	}
+
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: ожидался объект", at)
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, r := range asSlice(schema["required"]) {
+			if _, ok := obj[r.(string)]; !ok {
+				return fmt.Errorf("%s: нет обязательного поля %q", at, r)
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := props[k].(map[string]interface{})
+			if !ok {
+				prop, ok = schema["additionalProperties"].(map[string]interface{})
+			}
+			if !ok {
+				if extra, _ := schema["additionalProperties"].(bool); !extra && schema["additionalProperties"] != nil {
+					return fmt.Errorf("%s: поле %q не описано в схеме", at, k)
+				}
+				continue
+			}
+			if err := f.validate(prop, obj[k], at+"."+k); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: ожидался массив", at)
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, v := range arr {
+			if items == nil {
+				break
+			}
+			if err := f.validate(items, v, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: ожидалась строка", at)
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: ожидалось целое число", at)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: ожидалось число", at)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: ожидалось логическое значение", at)
+		}
+	}
+	return nil
+}
+
+func asSlice(v interface{}) []interface{} {
+	s, _ := v.([]interface{})
+	return s
+}
+
+// Каждая операция из спецификации должна быть зарегистрирована в роутере с тем же методом.
+func TestContract_AllOperationsRouted(t *testing.T) {
+	f := newContractFixture(t)
+	for path, item := range f.spec["paths"].(map[string]interface{}) {
+		for method := range item.(map[string]interface{}) {
+			if method == "parameters" {
+				continue
+			}
+			req, err := http.NewRequest(strings.ToUpper(method), f.server.URL+path, nil)
+			require.NoError(t, err)
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			// 404 от самого ServeMux отдаётся как text/plain, а обработчики отвечают JSON-ошибкой.
+			muxNotFound := resp.StatusCode == http.StatusNotFound &&
+				strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain")
+			require.Falsef(t, muxNotFound, "%s %s не зарегистрирован", method, path)
+			require.NotEqualf(t, http.StatusMethodNotAllowed, resp.StatusCode, "%s %s: метод не поддерживается", method, path)
+		}
+	}
+}
+
+func TestContract_ResponsesMatchSpec(t *testing.T) {
+	f := newContractFixture(t)
+
+	_, body := f.call(t, http.MethodPost, "/api/hash/crack", "", "application/json",
+		`{"hash":"`+hashAbc+`","maxLength":3}`)
+	var created types.RequestResponse
+	require.NoError(t, json.Unmarshal(body, &created))
+
+	f.call(t, http.MethodPost, "/api/hash/crack", "", "application/json", `{"hash":"xyz","maxLength":3}`)
+	f.call(t, http.MethodPost, "/api/hash/crack", "", "application/json", `{"hash":"`+hashAbc+`","maxLength":3,"extra":1}`)
+
+	f.call(t, http.MethodGet, "/api/hash/status", "?requestId="+created.RequestID, "", "")
+	f.call(t, http.MethodGet, "/api/hash/status", "", "", "")
+	f.call(t, http.MethodGet, "/api/hash/status", "?requestId=missing", "", "")
+	f.call(t, http.MethodGet, "/api/hash/status/stream", "?requestId=missing", "", "")
+	f.call(t, http.MethodGet, "/api/hash/status/ws", "", "", "")
+
+	_, body = f.call(t, http.MethodPost, "/api/hash/crack/batch", "", "application/json",
+		`[{"user":"alice","hash":"`+hashA+`","maxLength":1},{"hash":"bad","maxLength":1}]`)
+	var batch types.BatchResponse
+	require.NoError(t, json.Unmarshal(body, &batch))
+	f.call(t, http.MethodPost, "/api/hash/crack/batch", "?maxLength=2", "text/plain", "bob:"+hashAbc+"\n")
+	f.call(t, http.MethodPost, "/api/hash/crack/batch", "", "application/json", `[]`)
+	f.call(t, http.MethodGet, "/api/hash/batch/status", "?batchId="+batch.BatchID, "", "")
+	f.call(t, http.MethodGet, "/api/hash/batch/status", "?batchId=missing", "", "")
+	f.call(t, http.MethodGet, "/api/hash/batch/results", "?format=csv&batchId="+batch.BatchID, "", "")
+	f.call(t, http.MethodGet, "/api/hash/batch/results", "?format=potfile&batchId="+batch.BatchID, "", "")
+	f.call(t, http.MethodGet, "/api/hash/batch/results", "?format=xml&batchId="+batch.BatchID, "", "")
+
+	workerResp := types.CrackHashWorkerResponse{RequestId: created.RequestID}
+	workerResp.Answers.Words = []string{"abc"}
+	f.svc.HandleWorkerResponse(context.Background(), workerResp)
+	code, _ := f.call(t, http.MethodGet, "/api/hash/status", "?requestId="+created.RequestID, "", "")
+	require.Equal(t, http.StatusOK, code)
+	f.call(t, http.MethodPost, "/api/hash/cancel", "?requestId="+created.RequestID, "", "")
+	f.call(t, http.MethodPost, "/api/hash/cancel", "?requestId=missing", "", "")
+
+	f.call(t, http.MethodGet, "/api/hash/webhooks", "?requestId="+created.RequestID, "", "")
+	f.call(t, http.MethodGet, "/api/hash/webhooks", "", "", "")
+
+	f.call(t, http.MethodGet, "/api/hash/requests", "", "", "")
+	f.call(t, http.MethodGet, "/api/hash/requests", "?limit=0", "", "")
+	f.callAs(t, f.adminKey, http.MethodGet, "/api/hash/requests", "?status=READY&tenant=team-a", "", "")
+
+	f.call(t, http.MethodPost, "/api/potfile", "", "text/plain", hashA+":a\n")
+	f.callAs(t, f.adminKey, http.MethodPost, "/api/potfile", "", "text/plain", hashA+":a\n")
+	f.callAs(t, f.adminKey, http.MethodPost, "/api/potfile", "", "text/plain", "nothex:a\n")
+	f.callAs(t, f.adminKey, http.MethodGet, "/api/potfile", "", "", "")
+	f.callAs(t, f.adminKey, http.MethodGet, "/api/potfile", "?algorithm=sha1", "", "")
+	f.callAs(t, f.adminKey, http.MethodGet, "/api/admin/audit", "?action=submit&limit=5", "", "")
+	f.callAs(t, f.adminKey, http.MethodGet, "/api/admin/audit", "?from=yesterday", "", "")
+	f.callAs(t, f.adminKey, http.MethodGet, "/api/admin/audit/export", "?requestId="+created.RequestID, "", "")
+	f.callAs(t, f.adminKey, http.MethodGet, "/api/admin/audit/export", "?action=delete", "", "")
+	f.call(t, http.MethodGet, "/api/admin/audit", "", "", "")
+
+	f.call(t, http.MethodGet, "/api/openapi.json", "", "", "")
+}
+
+func TestContract_Client(t *testing.T) {
+	f := newContractFixture(t)
+	c := client.New(f.server.URL, client.WithAPIKey(f.apiKey))
+	ctx := context.Background()
+
+	_, err := c.Crack(ctx, client.CrackRequest{Hash: "xyz", MaxLength: 3})
+	var apiErr *client.APIError
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	require.Equal(t, types.ErrCodeInvalidHash, apiErr.Code)
+	require.Equal(t, "hash", apiErr.Field)
+
+	_, err = c.ImportPotfile(ctx, "", strings.NewReader(hashA+":a\n"))
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	admin := client.New(f.server.URL, client.WithAPIKey(f.adminKey))
+	imported, err := admin.ImportPotfile(ctx, "", strings.NewReader(hashA+":a\n"))
+	require.NoError(t, err)
+	require.Equal(t, 1, imported)
+
+	id, err := c.Crack(ctx, client.CrackRequest{Hash: hashAbc, MaxLength: 3})
+	require.NoError(t, err)
+	status, err := c.Status(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, service.StatusInProgress, status.Status)
+
+	streamCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var last client.StatusEvent
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		resp := types.CrackHashWorkerResponse{RequestId: id}
+		resp.Answers.Words = []string{"abc"}
+		f.svc.HandleWorkerResponse(ctx, resp)
+	}()
+	done := fmt.Errorf("done")
+	err = c.StreamStatus(streamCtx, id, func(e client.StatusEvent) error {
+		last = e
+		if e.Status == service.StatusReady {
+			return done
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, done)
+	require.Equal(t, []string{"abc"}, last.Words)
+
+	_, err = c.Cancel(ctx, id)
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusConflict, apiErr.StatusCode)
+
+	batchID, err := c.SubmitHashList(ctx, strings.NewReader("alice:"+hashA+"\n"), 1, "")
+	require.NoError(t, err)
+	batch, err := c.BatchStatus(ctx, batchID)
+	require.NoError(t, err)
+	require.Equal(t, service.StatusReady, batch.Status)
+	require.Equal(t, []string{"a"}, batch.Items[0].Data)
+
+	results, err := c.BatchResults(ctx, batchID, "potfile")
+	require.NoError(t, err)
+	data, err := io.ReadAll(results)
+	results.Close()
+	require.NoError(t, err)
+	require.Equal(t, hashA+":a\n", string(data))
+
+	listed, err := c.ListRequests(ctx, service.StatusReady, 10)
+	require.NoError(t, err)
+	require.Len(t, listed, 2)
+
+	deliveries, err := c.WebhookDeliveries(ctx, id)
+	require.NoError(t, err)
+	require.Empty(t, deliveries)
+
+	audited, err := admin.AuditEvents(ctx, client.AuditFilter{Action: "submit", RequestID: id}, 10)
+	require.NoError(t, err)
+	require.Len(t, audited, 1)
+	_, err = c.AuditEvents(ctx, client.AuditFilter{}, 10)
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	exported, err := admin.ExportAudit(ctx, client.AuditFilter{RequestID: id})
+	require.NoError(t, err)
+	data, err = io.ReadAll(exported)
+	exported.Close()
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"action":"submit"`)
+}
+
+func TestContract_AuthAndQuotas(t *testing.T) {
+	f := newContractFixture(t)
+	crack := `{"hash":"` + hashAbc + `","maxLength":2}`
+
+	code, _ := f.callAs(t, "", http.MethodPost, "/api/hash/crack", "", "application/json", crack)
+	require.Equal(t, http.StatusUnauthorized, code)
+	code, _ = f.callAs(t, "", http.MethodGet, "/api/openapi.json", "", "", "")
+	require.Equal(t, http.StatusOK, code)
+	code, _ = f.callAs(t, f.apiKey, http.MethodGet, "/api/admin/keys", "", "", "")
+	require.Equal(t, http.StatusForbidden, code)
+
+	code, body := f.callAs(t, adminToken, http.MethodPost, "/api/admin/keys", "", "application/json",
+		`{"name":"team-a","maxConcurrent":1,"dailyKeyspace":2000}`)
+	require.Equal(t, http.StatusCreated, code)
+	var created types.APIKeyCreatedResponse
+	require.NoError(t, json.Unmarshal(body, &created))
+	require.True(t, strings.HasPrefix(created.Key, created.Prefix))
+
+	// 36 + 36^2 = 1332 строк: первый запрос укладывается в дневной лимит.
+	code, _ = f.callAs(t, created.Key, http.MethodPost, "/api/hash/crack", "", "application/json", crack)
+	require.Equal(t, http.StatusOK, code)
+	code, body = f.callAs(t, created.Key, http.MethodPost, "/api/hash/crack", "", "application/json",
+		`{"hash":"`+hashA+`","maxLength":2}`)
+	require.Equal(t, http.StatusTooManyRequests, code)
+	require.Contains(t, string(body), types.ErrCodeConcurrentQuota)
+
+	code, _ = f.callAs(t, adminToken, http.MethodPatch, "/api/admin/keys", "?id="+created.ID, "application/json",
+		`{"maxConcurrent":0}`)
+	require.Equal(t, http.StatusOK, code)
+	code, body = f.callAs(t, created.Key, http.MethodPost, "/api/hash/crack", "", "application/json",
+		`{"hash":"`+hashA+`","maxLength":2}`)
+	require.Equal(t, http.StatusTooManyRequests, code)
+	require.Contains(t, string(body), types.ErrCodeKeyspaceQuota)
+
+	_, body = f.callAs(t, adminToken, http.MethodGet, "/api/admin/keys", "", "", "")
+	var keys []types.APIKeyInfo
+	require.NoError(t, json.Unmarshal(body, &keys))
+	require.Len(t, keys, 3)
+	require.Equal(t, int64(1332), keys[2].KeyspaceUsedToday)
+	require.Equal(t, auth.DefaultTenant, keys[2].Tenant)
+	require.Equal(t, auth.RoleSubmitter, keys[2].Role)
+
+	code, _ = f.callAs(t, adminToken, http.MethodDelete, "/api/admin/keys", "?id="+created.ID, "", "")
+	require.Equal(t, http.StatusOK, code)
+	code, _ = f.callAs(t, created.Key, http.MethodGet, "/api/hash/status", "?requestId=x", "", "")
+	require.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestInternalEndpointRequiresToken(t *testing.T) {
+	f := newContractFixture(t)
+	const body = `<CrackHashWorkerResponse><RequestId>x</RequestId></CrackHashWorkerResponse>`
+	patch := func(token string, sign bool) *http.Response {
+		req, err := http.NewRequest(http.MethodPatch, f.server.URL+"/internal/api/manager/hash/crack/request", strings.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set(auth.HeaderInternalToken, token)
+		}
+		if sign {
+			keyID, signature := f.signing.Sign([]byte(body))
+			req.Header.Set(signing.HeaderKeyID, keyID)
+			req.Header.Set(signing.HeaderSignature, signature)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	require.Equal(t, http.StatusForbidden, patch("", true).StatusCode)
+	require.Equal(t, http.StatusOK, patch("internal-secret", true).StatusCode)
+
+	// Знания внутреннего токена недостаточно: ответ без подписи отклоняется.
+	require.Equal(t, http.StatusForbidden, patch("internal-secret", false).StatusCode)
+}
+
+func TestContract_RolesAndTenantIsolation(t *testing.T) {
+	f := newContractFixture(t)
+	otherTeam := f.createKey(t, "team-b", "team-b", auth.RoleSubmitter)
+	teamViewer := f.createKey(t, "team-a-viewer", "team-a", auth.RoleViewer)
+
+	_, body := f.call(t, http.MethodPost, "/api/hash/crack", "", "application/json", `{"hash":"`+hashAbc+`","maxLength":3}`)
+	var created types.RequestResponse
+	require.NoError(t, json.Unmarshal(body, &created))
+
+	code, _ := f.callAs(t, teamViewer, http.MethodPost, "/api/hash/crack", "", "application/json", `{"hash":"`+hashA+`","maxLength":1}`)
+	require.Equal(t, http.StatusForbidden, code)
+	code, _ = f.callAs(t, teamViewer, http.MethodGet, "/api/hash/status", "?requestId="+created.RequestID, "", "")
+	require.Equal(t, http.StatusOK, code)
+
+	code, _ = f.callAs(t, otherTeam, http.MethodGet, "/api/hash/status", "?requestId="+created.RequestID, "", "")
+	require.Equal(t, http.StatusNotFound, code)
+	code, _ = f.callAs(t, otherTeam, http.MethodGet, "/api/hash/webhooks", "?requestId="+created.RequestID, "", "")
+	require.Equal(t, http.StatusNotFound, code)
+	code, _ = f.callAs(t, otherTeam, http.MethodPost, "/api/hash/cancel", "?requestId="+created.RequestID, "", "")
+	require.Equal(t, http.StatusNotFound, code)
+
+	// Одинаковый хэш другой команды присоединяется к той же задаче, но остаётся её собственным запросом.
+	_, body = f.callAs(t, otherTeam, http.MethodPost, "/api/hash/crack", "", "application/json", `{"hash":"`+hashAbc+`","maxLength":3}`)
+	var shared types.RequestResponse
+	require.NoError(t, json.Unmarshal(body, &shared))
+
+	list := func(token, query string) []types.RequestSummary {
+		_, body := f.callAs(t, token, http.MethodGet, "/api/hash/requests", query, "", "")
+		var resp types.RequestListResponse
+		require.NoError(t, json.Unmarshal(body, &resp))
+		return resp.Requests
+	}
+	ownB := list(otherTeam, "?tenant=team-a")
+	require.Len(t, ownB, 1)
+	require.Equal(t, shared.RequestID, ownB[0].RequestID)
+	require.Len(t, list(teamViewer, ""), 1)
+	require.Len(t, list(f.adminKey, ""), 2)
+	require.Len(t, list(f.adminKey, "?tenant=team-b"), 1)
+
+	code, _ = f.callAs(t, f.adminKey, http.MethodPost, "/api/hash/cancel", "?requestId="+created.RequestID, "", "")
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestContract_AuditLog(t *testing.T) {
+	f := newContractFixture(t)
+
+	_, body := f.call(t, http.MethodPost, "/api/hash/crack", "", "application/json", `{"hash":"`+hashAbc+`","maxLength":3}`)
+	var created types.RequestResponse
+	require.NoError(t, json.Unmarshal(body, &created))
+
+	// Статус без найденных слов не раскрывает результат и в журнал не попадает.
+	f.call(t, http.MethodGet, "/api/hash/status", "?requestId="+created.RequestID, "", "")
+	workerResp := types.CrackHashWorkerResponse{RequestId: created.RequestID}
+	workerResp.Answers.Words = []string{"abc"}
+	f.svc.HandleWorkerResponse(context.Background(), workerResp)
+	f.call(t, http.MethodGet, "/api/hash/status", "?requestId="+created.RequestID, "", "")
+
+	_, body = f.call(t, http.MethodPost, "/api/hash/crack", "", "application/json", `{"hash":"`+hashA+`","maxLength":1}`)
+	var cancelled types.RequestResponse
+	require.NoError(t, json.Unmarshal(body, &cancelled))
+	f.call(t, http.MethodPost, "/api/hash/cancel", "?requestId="+cancelled.RequestID, "", "")
+
+	_, body = f.callAs(t, f.adminKey, http.MethodGet, "/api/admin/audit", "?requestId="+created.RequestID, "", "")
+	var events []store.AuditEvent
+	require.NoError(t, json.Unmarshal(body, &events))
+	require.Len(t, events, 2)
+	require.Equal(t, store.AuditSubmit, events[0].Action)
+	require.Equal(t, "contract", events[0].KeyID)
+	require.Equal(t, "team-a", events[0].Tenant)
+	require.Equal(t, hashAbc, events[0].Details["hash"])
+	require.Equal(t, store.AuditStatusRead, events[1].Action)
+	require.Equal(t, "http", events[1].Details["channel"])
+
+	_, body = f.callAs(t, f.adminKey, http.MethodGet, "/api/admin/audit", "?action=cancel", "", "")
+	require.NoError(t, json.Unmarshal(body, &events))
+	require.Len(t, events, 1)
+	require.Equal(t, cancelled.RequestID, events[0].RequestID)
+
+	code, body := f.callAs(t, adminToken, http.MethodGet, "/api/admin/audit/export", "", "", "")
+	require.Equal(t, http.StatusOK, code)
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	require.Len(t, lines, 5)
+	for _, line := range lines {
+		var e store.AuditEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+	}
+
+	// Выгрузка журнала сама фиксируется от имени ADMIN_TOKEN.
+	_, body = f.callAs(t, f.adminKey, http.MethodGet, "/api/admin/audit", "?action=export", "", "")
+	require.NoError(t, json.Unmarshal(body, &events))
+	require.Len(t, events, 1)
+	require.Equal(t, auth.AdminTokenPrincipal.Name, events[0].KeyName)
+	require.Equal(t, "audit", events[0].Details["resource"])
+}
+
+func TestContract_WorkerQuarantine(t *testing.T) {
+	f := newContractFixture(t)
+
+	_, body := f.call(t, http.MethodPost, "/api/hash/crack", "", "application/json", `{"hash":"`+hashAbc+`","maxLength":3}`)
+	var created types.RequestResponse
+	require.NoError(t, json.Unmarshal(body, &created))
+
+	// Два неверных ответа подряд отправляют воркер в карантин; после этого
+	// даже верный ответ от него не принимается.
+	for _, word := range []string{"abd", "xyz", "abc"} {
+		resp := types.CrackHashWorkerResponse{RequestId: created.RequestID, WorkerId: "worker1", SigningKeyID: "worker1"}
+		resp.Answers.Words = []string{word}
+		f.svc.HandleWorkerResponse(context.Background(), resp)
+	}
+	_, body = f.call(t, http.MethodGet, "/api/hash/status", "?requestId="+created.RequestID, "", "")
+	var status types.StatusResponse
+	require.NoError(t, json.Unmarshal(body, &status))
+	require.Equal(t, service.StatusInProgress, status.Status)
+
+	code, _ := f.call(t, http.MethodGet, "/api/admin/workers", "", "", "")
+	require.Equal(t, http.StatusForbidden, code)
+	_, body = f.callAs(t, f.adminKey, http.MethodGet, "/api/admin/workers", "", "", "")
+	var workers []service.WorkerStats
+	require.NoError(t, json.Unmarshal(body, &workers))
+	require.Len(t, workers, 1)
+	require.Equal(t, "worker1", workers[0].WorkerID)
+	require.Equal(t, 2, workers[0].RejectedWords)
+	require.True(t, workers[0].Quarantined)
+
+	code, _ = f.callAs(t, adminToken, http.MethodDelete, "/api/admin/workers", "?id=worker9", "", "")
+	require.Equal(t, http.StatusNotFound, code)
+	code, body = f.callAs(t, adminToken, http.MethodDelete, "/api/admin/workers", "?id=worker1", "", "")
+	require.Equal(t, http.StatusOK, code)
+	var released service.WorkerStats
+	require.NoError(t, json.Unmarshal(body, &released))
+	require.False(t, released.Quarantined)
+
+	resp := types.CrackHashWorkerResponse{RequestId: created.RequestID, WorkerId: "worker1", SigningKeyID: "worker1"}
+	resp.Answers.Words = []string{"abc"}
+	f.svc.HandleWorkerResponse(context.Background(), resp)
+	_, body = f.call(t, http.MethodGet, "/api/hash/status", "?requestId="+created.RequestID, "", "")
+	require.NoError(t, json.Unmarshal(body, &status))
+	require.Equal(t, service.StatusReady, status.Status)
+	require.Equal(t, []string{"abc"}, status.Data)
+}
+
+func TestContract_Settings(t *testing.T) {
+	f := newContractFixture(t)
+
+	code, _ := f.call(t, http.MethodGet, "/api/admin/settings", "", "", "")
+	require.Equal(t, http.StatusForbidden, code)
+	_, body := f.callAs(t, f.adminKey, http.MethodGet, "/api/admin/settings", "", "", "")
+	var settings types.Settings
+	require.NoError(t, json.Unmarshal(body, &settings))
+	require.Equal(t, types.Settings{ResponseTimeout: "1m0s", MaxQueueSize: service.DefaultMaxQueueSize, MaxLengthLimit: 6}, settings)
+
+	code, _ = f.callAs(t, adminToken, http.MethodPatch, "/api/admin/settings", "", "application/json", `{"responseTimeout":"скоро"}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	code, body = f.callAs(t, adminToken, http.MethodPatch, "/api/admin/settings", "", "application/json", `{"maxQueueSize":0}`)
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Contains(t, string(body), `"field":"maxQueueSize"`)
+
+	code, body = f.callAs(t, adminToken, http.MethodPatch, "/api/admin/settings", "", "application/json", `{"responseTimeout":"5m","maxQueueSize":1}`)
+	require.Equal(t, http.StatusOK, code)
+	require.NoError(t, json.Unmarshal(body, &settings))
+	require.Equal(t, types.Settings{ResponseTimeout: "5m0s", MaxQueueSize: 1, MaxLengthLimit: 6}, settings)
+
+	// Новый лимит действует сразу: вторая задача не помещается в очередь.
+	code, _ = f.call(t, http.MethodPost, "/api/hash/crack", "", "application/json", `{"hash":"`+hashAbc+`","maxLength":3}`)
+	require.Equal(t, http.StatusOK, code)
+	code, _ = f.call(t, http.MethodPost, "/api/hash/crack", "", "application/json", `{"hash":"`+hashA+`","maxLength":1}`)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+
+	_, body = f.callAs(t, f.adminKey, http.MethodGet, "/api/admin/audit", "?action="+store.AuditSettings, "", "")
+	var events []store.AuditEvent
+	require.NoError(t, json.Unmarshal(body, &events))
+	require.Len(t, events, 1)
+	require.Equal(t, auth.AdminTokenPrincipal.Name, events[0].KeyName)
+	require.Equal(t, "1m0s -> 5m0s", events[0].Details["responseTimeout"])
+	require.Equal(t, "100 -> 1", events[0].Details["maxQueueSize"])
+	require.Equal(t, "api", events[0].Details["source"])
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	f := newContractFixture(t)
+	f.call(t, http.MethodPost, "/api/hash/crack", "", "application/json", `{"hash":"`+hashAbc+`","maxLength":3}`)
+
+	resp, err := http.Get(f.server.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(data), "crackhash_manager_rabbitmq_connected")
+	require.Contains(t, string(data), "crackhash_manager_pending_retries_total")
+}
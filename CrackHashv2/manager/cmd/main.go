@@ -2,92 +2,259 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"CrackHash/internal/logging"
+	"CrackHash/internal/signing"
+	"CrackHash/internal/tlsconfig"
+	"CrackHash/internal/tracing"
+	"CrackHash/manager/api/crackhashpb"
+	"CrackHash/manager/internal/audit"
 	"CrackHash/manager/internal/config"
+	"CrackHash/manager/internal/envelope"
+	"CrackHash/manager/internal/events"
+	"CrackHash/manager/internal/grpcapi"
 	"CrackHash/manager/internal/handlers"
+	"CrackHash/manager/internal/metrics"
 	"CrackHash/manager/internal/queue"
 	"CrackHash/manager/internal/service"
 	"CrackHash/manager/internal/store"
+	"CrackHash/manager/internal/webhook"
 )
 
 func main() {
 	ctx := context.Background()
 
-	cfg, err := config.LoadConfig()
+	cfg, printConfig, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Ошибка конфигурации:\n%v", err)
+	}
+	if printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Ошибка вывода конфигурации: %v", err)
+		}
+		return
+	}
+	if err := logging.Setup(os.Stdout, cfg.LogLevel); err != nil {
+		log.Fatalf("Ошибка в LOG_LEVEL: %v", err)
+	}
+	logger := logging.Component("main")
+	shutdownTracing, err := tracing.Setup(ctx, "crackhash-manager", cfg.TracingExporter)
+	if err != nil {
+		fatal(logger, "Ошибка в TRACING_EXPORTER", err)
+	}
+
+	var storeOpts []store.MongoOption
+	var keyring *envelope.Keyring
+	if cfg.EncryptionKeyFile != "" {
+		keyring, err = envelope.LoadKeyring(cfg.EncryptionKeyFile)
+		if err != nil {
+			fatal(logger, "Ошибка загрузки файла ключей шифрования", err)
+		}
+		storeOpts = append(storeOpts, store.WithKeyring(keyring))
+		logger.Info("Шифрование найденных слов включено", "activeKey", keyring.ActiveKeyID())
+	} else {
+		logger.Warn("ENCRYPTION_KEY_FILE не задан: найденные слова хранятся в MongoDB открыто")
+	}
+
+	mongoStore, err := store.NewMongoRequestStore(cfg, storeOpts...)
 	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+		fatal(logger, "Ошибка инициализации MongoStore", err)
 	}
 
-	mongoStore, err := store.NewMongoRequestStore(cfg)
+	signingKeys, err := signing.ParseKeys(cfg.MessageSigningKeys)
 	if err != nil {
-		log.Fatalf("Ошибка инициализации MongoStore: %v", err)
+		fatal(logger, "Ошибка в MESSAGE_SIGNING_KEYS", err)
 	}
 
-	rabbitClient, err := queue.NewRabbitClient(cfg)
+	rabbitClient, err := queue.NewRabbitClient(cfg, signingKeys)
 	if err != nil {
-		log.Printf("Не удалось подключиться к RabbitMQ при старте: %v", err)
+		logger.Error("Не удалось подключиться к RabbitMQ при старте", "error", err)
 	}
 
-	mgrService := service.NewManagerService(mongoStore, rabbitClient, cfg.ResponseTimeout)
+	if err := metrics.RegisterRequests(mongoStore.CountByStatus); err != nil {
+		fatal(logger, "Ошибка регистрации метрик", err)
+	}
 
-	go func() {
-		for {
-			time.Sleep(5 * time.Second)
-			if rabbitClient != nil && rabbitClient.IsConnected() {
-				err := mgrService.RetryPendingTasks(context.Background())
-				if err != nil {
-					log.Printf("Ошибка при повторной отправке зависших задач: %v", err)
-				}
+	potfileStore := store.NewMongoPotfileStore(mongoStore.Database(), keyring)
+	webhookStore := store.NewMongoWebhookStore(mongoStore.Database(), keyring)
+	callbackGuard, err := webhook.NewGuard(cfg.WebhookAllowedNetworks)
+	if err != nil {
+		fatal(logger, "Некорректный WEBHOOK_ALLOWED_NETWORKS", err)
+	}
+	dispatcher := webhook.NewDispatcher(webhookStore, cfg.WebhookSecret, cfg.WebhookMaxAttempts, cfg.WebhookBaseDelay,
+		webhook.WithGuard(callbackGuard))
+	batchStore := store.NewMongoBatchStore(mongoStore.Database())
+	apiKeyStore := store.NewMongoAPIKeyStore(mongoStore.Database())
+	auditStore := store.NewMongoAuditStore(mongoStore.Database())
+	auditLog := audit.NewLogger(auditStore)
+	bus := events.NewBus(16)
+	workerTracker := service.NewWorkerTracker(cfg.QuarantineThreshold, cfg.QuarantineDuration)
+	mgrService := service.NewManagerService(mongoStore, rabbitClient, cfg.ResponseTimeout,
+		service.WithPotfile(potfileStore),
+		service.WithCompletionNotifier(dispatcher),
+		service.WithCallbackGuard(callbackGuard),
+		service.WithEventPublisher(bus),
+		service.WithBatchStore(batchStore),
+		service.WithMaxLengthLimit(cfg.MaxLengthLimit),
+		service.WithMaxQueueSize(cfg.MaxQueueSize),
+		service.WithKeyspaceQuota(apiKeyStore),
+		service.WithAuditLog(auditLog),
+		service.WithWorkerTracker(workerTracker))
+
+	// background останавливает фоновые циклы при завершении.
+	background, stopBackground := context.WithCancel(ctx)
+	every(background, 5*time.Second, func() {
+		dispatcher.ProcessDue(context.Background())
+	})
+	every(background, 5*time.Second, func() {
+		if rabbitClient != nil && rabbitClient.IsConnected() {
+			err := mgrService.RetryPendingTasks(context.Background())
+			if err != nil {
+				logger.Error("Ошибка при повторной отправке зависших задач", "error", err)
 			}
 		}
-	}()
+	})
 
+	// responsesDone закрывается, когда обработан последний ответ воркера.
+	responsesDone := make(chan struct{})
 	if rabbitClient != nil && rabbitClient.IsConnected() {
 		respCh, err := rabbitClient.StartConsumeResponses()
 		if err != nil {
-			log.Printf("Не удалось подписаться на worker_responses: %v", err)
+			logger.Error("Не удалось подписаться на worker_responses", "error", err)
+			close(responsesDone)
 		} else {
 			go func() {
+				defer close(responsesDone)
 				for workerResp := range respCh {
-					state, ok := mongoStore.Get(workerResp.RequestId)
-					if ok {
-						if state.Data == nil {
-							state.Data = []string{}
-						}
-						state.Data = append(state.Data, workerResp.Answers.Words...)
-						if len(state.Data) > 0 {
-							state.Status = service.StatusReady
-							if state.Timer != nil {
-								state.Timer.Stop()
-							}
-						}
-						mongoStore.Update(workerResp.RequestId, state)
-						if state.Status != "IN_PROGRESS" {
-							mongoStore.MarkPending(workerResp.RequestId, false)
-						}
-					}
+					mgrService.HandleWorkerResponse(ctx, workerResp)
 					rabbitClient.AckMessage(workerResp)
-
 				}
 			}()
 		}
+	} else {
+		close(responsesDone)
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/api/hash/crack", handlers.CrackHandler(ctx, mgrService))
-	mux.HandleFunc("/api/hash/status", handlers.StatusHandler(ctx, mongoStore))
-	mux.HandleFunc("/internal/api/manager/hash/crack/request", handlers.WorkerResponseHandler(ctx, mongoStore))
+	mux := newRouter(ctx, routerDeps{
+		service:        mgrService,
+		requests:       mongoStore,
+		potfile:        potfileStore,
+		webhooks:       webhookStore,
+		apiKeys:        apiKeyStore,
+		auditEvents:    auditStore,
+		auditLog:       auditLog,
+		workers:        workerTracker,
+		bus:            bus,
+		streamInterval: cfg.StreamInterval,
+		adminToken:     cfg.AdminToken,
+		internalToken:  cfg.InternalToken,
+		signingKeys:    signingKeys,
+		readiness: []handlers.ReadinessCheck{
+			{Name: "mongodb", Check: mongoStore.Ping},
+			{Name: "rabbitmq", Check: func(ctx context.Context) error {
+				if rabbitClient == nil || !rabbitClient.IsConnected() {
+					return errors.New("нет соединения с RabbitMQ")
+				}
+				return nil
+			}},
+		},
+	})
+	if cfg.AdminToken == "" {
+		logger.Warn("ADMIN_TOKEN не задан: ключами могут управлять только API-ключи с ролью admin")
+	}
+	if cfg.InternalToken == "" {
+		logger.Warn("INTERNAL_TOKEN не задан: внутренний HTTP API менеджера недоступен")
+	}
 
 	srv := &http.Server{
 		Addr:         ":" + cfg.ManagerPort,
-		Handler:      mux,
+		Handler:      otelhttp.NewHandler(mux, "manager", otelhttp.WithSpanNameFormatter(spanName)),
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
+	if cfg.TLSCertFile != "" || cfg.TLSKeyFile != "" {
+		srv.TLSConfig, err = tlsconfig.Server(tlsconfig.Files{CertFile: cfg.TLSCertFile, KeyFile: cfg.TLSKeyFile})
+		if err != nil {
+			fatal(logger, "Ошибка настройки TLS", err)
+		}
+	}
+
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+	if err != nil {
+		fatal(logger, "Не удалось открыть порт gRPC", err, "port", cfg.GRPCPort)
+	}
+	grpcOpts := grpcapi.AuthInterceptors(apiKeyStore)
+	if srv.TLSConfig != nil {
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(srv.TLSConfig)))
+	}
+	grpcSrv := grpc.NewServer(grpcOpts...)
+	crackhashpb.RegisterCrackHashServer(grpcSrv, grpcapi.NewServer(mgrService, mongoStore, bus, cfg.StreamInterval, auditLog))
+	go func() {
+		logger.Info("gRPC API запускается", "port", cfg.GRPCPort)
+		if err := grpcSrv.Serve(grpcListener); err != nil {
+			fatal(logger, "Ошибка gRPC-сервера", err)
+		}
+	}()
+
+	// SSE и WebSocket живут долго: при остановке их контекст отменяется, иначе Shutdown
+	// ждал бы их до дедлайна.
+	streams, cancelStreams := context.WithCancel(ctx)
+	srv.BaseContext = func(net.Listener) context.Context { return streams }
+	srv.RegisterOnShutdown(cancelStreams)
+	go func() {
+		var err error
+		if srv.TLSConfig != nil {
+			logger.Info("Менеджер запускается", "port", cfg.ManagerPort, "tls", true)
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			logger.Info("Менеджер запускается", "port", cfg.ManagerPort, "tls", false)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal(logger, "Ошибка HTTP-сервера", err)
+		}
+	}()
+
+	watchReload(background, cfg, mgrService)
+
+	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	<-sigCtx.Done()
+	stop()
+	logger.Info("Получен сигнал остановки, завершаем работу", "timeout", cfg.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(ctx, cfg.ShutdownTimeout)
+	defer cancel()
+	stopBackground()
+	gracefulShutdown(shutdownCtx, shutdownDeps{
+		http:          srv,
+		grpc:          grpcSrv,
+		service:       mgrService,
+		queue:         rabbitClient,
+		responsesDone: responsesDone,
+		store:         mongoStore,
+		tracing:       shutdownTracing,
+	})
+}
 
-	log.Printf("Менеджер запускается на порту %s", cfg.ManagerPort)
-	log.Fatal(srv.ListenAndServe())
+// fatal пишет в лог ошибку, после которой менеджер не может работать, и завершает процесс.
+// До logging.Setup вместо него используется log.Fatalf.
+func fatal(logger *slog.Logger, msg string, err error, args ...any) {
+	logger.Error(msg, append([]any{"error", err}, args...)...)
+	os.Exit(1)
 }
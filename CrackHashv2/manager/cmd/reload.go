@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"CrackHash/internal/logging"
+	"CrackHash/manager/internal/config"
+	"CrackHash/manager/internal/service"
+)
+
+// reloadable — параметры, которые применяются по SIGHUP без перезапуска.
+var reloadable = map[string]bool{
+	"responseTimeout": true,
+	"maxQueueSize":    true,
+	"maxLengthLimit":  true,
+	"logLevel":        true,
+}
+
+// applyChanged переносит в действующие настройки s только параметры из changed: значения,
+// которые не менялись в файле, могли быть заданы через PATCH /api/admin/settings, и
+// SIGHUP не должен их откатывать.
+func applyChanged(s *service.Settings, next *config.Config, changed []string) {
+	for _, key := range changed {
+		switch key {
+		case "responseTimeout":
+			s.ResponseTimeout = next.ResponseTimeout
+		case "maxQueueSize":
+			s.MaxQueueSize = next.MaxQueueSize
+		case "maxLengthLimit":
+			s.MaxLengthLimit = next.MaxLengthLimit
+		}
+	}
+}
+
+// watchReload по SIGHUP перечитывает конфигурацию теми же слоями, что и при старте,
+// пока ctx не отменён.
+func watchReload(ctx context.Context, cfg *config.Config, svc service.ManagerServiceImpl) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				reload(ctx, cfg, svc, os.Args[1:])
+			}
+		}
+	}()
+}
+
+// reload применяет изменяемые на лету параметры, значения которых отличаются от
+// прочитанных в прошлый раз (cfg), и переносит их в cfg. Если новая конфигурация не
+// проходит проверку, остаются прежние настройки; изменения остальных параметров только
+// логируются — для них нужен перезапуск.
+func reload(ctx context.Context, cfg *config.Config, svc service.ManagerServiceImpl, args []string) {
+	logger := logging.Component("config")
+	next, _, err := config.Load(args)
+	if err != nil {
+		logger.Error("Конфигурация не перечитана, действуют прежние настройки", "error", err)
+		return
+	}
+	changed := cfg.Changed(next)
+	update := func(s *service.Settings) { applyChanged(s, next, changed) }
+	if err := svc.UpdateSettings(ctx, update, "sighup"); err != nil {
+		logger.Error("Настройки не применены", "error", err)
+		return
+	}
+	if next.LogLevel != cfg.LogLevel {
+		// Уровень уже проверен config.Load.
+		logging.SetLevel(next.LogLevel)
+		logger.Info("Настройка изменена", "setting", "logLevel", "change", cfg.LogLevel+" -> "+next.LogLevel, "source", "sighup")
+	}
+	var restart []string
+	for _, key := range changed {
+		if !reloadable[key] {
+			restart = append(restart, key)
+		}
+	}
+	if len(restart) > 0 {
+		logger.Warn("Параметры изменены, но применятся только после перезапуска", "settings", restart)
+	}
+	cfg.ResponseTimeout, cfg.MaxQueueSize, cfg.MaxLengthLimit = next.ResponseTimeout, next.MaxQueueSize, next.MaxLengthLimit
+	cfg.LogLevel = next.LogLevel
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/manager/internal/config"
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/store"
+)
+
+func TestReload_AppliesRuntimeSettings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manager.yaml")
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("MESSAGE_SIGNING_KEYS", "k1:reload-test-secret")
+	t.Setenv("WEBHOOK_SECRET", "reload-test-webhook")
+	cfg := config.Default()
+	svc := service.NewManagerService(store.NewRequestStore(), nil, cfg.ResponseTimeout,
+		service.WithMaxQueueSize(cfg.MaxQueueSize))
+
+	require.NoError(t, os.WriteFile(path, []byte("responseTimeout: 5m\nmaxQueueSize: 7\nmongoDatabase: other\n"), 0o600))
+	reload(context.Background(), cfg, svc, nil)
+
+	want := service.Settings{ResponseTimeout: 5 * time.Minute, MaxQueueSize: 7, MaxLengthLimit: 6}
+	require.Equal(t, want, svc.Settings())
+	require.Equal(t, 5*time.Minute, cfg.ResponseTimeout)
+	require.Equal(t, "crackhash", cfg.MongoDatabase, "MONGO_DB применяется только после перезапуска")
+
+	// Ошибка в файле не сбрасывает действующие настройки.
+	require.NoError(t, os.WriteFile(path, []byte("maxQueueSize: 0\n"), 0o600))
+	reload(context.Background(), cfg, svc, nil)
+	require.Equal(t, want, svc.Settings())
+}
+
+func TestReload_KeepsSettingsChangedThroughAPI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manager.yaml")
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("MESSAGE_SIGNING_KEYS", "k1:reload-test-secret")
+	t.Setenv("WEBHOOK_SECRET", "reload-test-webhook")
+	require.NoError(t, os.WriteFile(path, []byte("responseTimeout: 5m\n"), 0o600))
+	cfg, _, err := config.Load(nil)
+	require.NoError(t, err)
+	svc := service.NewManagerService(store.NewRequestStore(), nil, cfg.ResponseTimeout,
+		service.WithMaxQueueSize(cfg.MaxQueueSize))
+
+	patched := service.Settings{ResponseTimeout: 10 * time.Minute, MaxQueueSize: 50, MaxLengthLimit: 6}
+	require.NoError(t, svc.UpdateSettings(context.Background(), func(s *service.Settings) { *s = patched }, "api"))
+
+	// Файл не менялся: SIGHUP не откатывает значения из PATCH.
+	reload(context.Background(), cfg, svc, nil)
+	require.Equal(t, patched, svc.Settings())
+
+	// Изменённый в файле параметр применяется, остальные остаются из PATCH.
+	require.NoError(t, os.WriteFile(path, []byte("responseTimeout: 5m\nmaxQueueSize: 7\n"), 0o600))
+	reload(context.Background(), cfg, svc, nil)
+	require.Equal(t, service.Settings{ResponseTimeout: 10 * time.Minute, MaxQueueSize: 7, MaxLengthLimit: 6}, svc.Settings())
+}
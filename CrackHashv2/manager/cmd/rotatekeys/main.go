@@ -0,0 +1,76 @@
+// Команда rotatekeys перешифровывает найденные слова в коллекциях requests и potfile и
+// тела webhook в webhook_deliveries активным мастер-ключом. Запускается при остановленном
+// менеджере.
+//
+// Порядок ротации:
+//  1. rotatekeys -generate k2 >> новая строка; поставить её первой в файле ключей,
+//     старый ключ оставить ниже.
+//  2. rotatekeys -keys keys.txt — все документы перешифровываются ключом k2.
+//  3. Удалить старый ключ из файла и запустить менеджер.
+//
+// Этой же командой шифруются документы, записанные до включения шифрования.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"CrackHash/manager/internal/config"
+	"CrackHash/manager/internal/envelope"
+	"CrackHash/manager/internal/store"
+)
+
+func main() {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+	}
+	keyFile := flag.String("keys", cfg.EncryptionKeyFile, "файл мастер-ключей (по умолчанию ENCRYPTION_KEY_FILE)")
+	generate := flag.String("generate", "", "вывести строку нового мастер-ключа с указанным ID и выйти")
+	flag.StringVar(&cfg.MongoURI, "mongo-uri", cfg.MongoURI, "адрес MongoDB")
+	flag.StringVar(&cfg.MongoDatabase, "db", cfg.MongoDatabase, "база данных MongoDB")
+	flag.Parse()
+
+	if *generate != "" {
+		line, err := envelope.GenerateKeyLine(*generate)
+		if err != nil {
+			log.Fatalf("Ошибка генерации ключа: %v", err)
+		}
+		fmt.Println(line)
+		return
+	}
+	if *keyFile == "" {
+		fmt.Fprintln(os.Stderr, "Не задан файл ключей: укажите -keys или ENCRYPTION_KEY_FILE")
+		os.Exit(2)
+	}
+
+	keyring, err := envelope.LoadKeyring(*keyFile)
+	if err != nil {
+		log.Fatalf("Ошибка загрузки файла ключей: %v", err)
+	}
+	requests, err := store.NewMongoRequestStore(cfg, store.WithKeyring(keyring))
+	if err != nil {
+		log.Fatalf("Ошибка подключения к MongoDB: %v", err)
+	}
+	db := requests.Database()
+	collections := []struct {
+		name  string
+		store interface {
+			Reencrypt(ctx context.Context) (int, error)
+		}
+	}{
+		{"requests", requests},
+		{"potfile", store.NewMongoPotfileStore(db, keyring)},
+		{"webhook_deliveries", store.NewMongoWebhookStore(db, keyring)},
+	}
+	for _, c := range collections {
+		updated, err := c.store.Reencrypt(context.Background())
+		if err != nil {
+			log.Fatalf("Ротация %s прервана после %d документов: %v", c.name, updated, err)
+		}
+		log.Printf("%s: перешифровано документов: %d, активный ключ %s", c.name, updated, keyring.ActiveKeyID())
+	}
+}
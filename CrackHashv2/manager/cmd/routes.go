@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"CrackHash/internal/signing"
+	"CrackHash/manager/internal/audit"
+	"CrackHash/manager/internal/auth"
+	"CrackHash/manager/internal/events"
+	"CrackHash/manager/internal/handlers"
+	"CrackHash/manager/internal/metrics"
+	"CrackHash/manager/internal/openapi"
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/store"
+)
+
+type routerDeps struct {
+	service        service.ManagerServiceImpl
+	requests       store.RequestStore
+	potfile        store.PotfileStore
+	webhooks       store.WebhookStore
+	apiKeys        store.APIKeyStore
+	auditEvents    store.AuditStore
+	auditLog       *audit.Logger
+	workers        *service.WorkerTracker
+	bus            *events.Bus
+	streamInterval time.Duration
+	adminToken     string
+	internalToken  string
+	signingKeys    *signing.Keyring
+	readiness      []handlers.ReadinessCheck
+}
+
+// spanName называет серверный спан методом и путём: ID в API передаются параметрами
+// запроса, поэтому путей немного.
+func spanName(_ string, r *http.Request) string {
+	return r.Method + " " + r.URL.Path
+}
+
+func newRouter(ctx context.Context, d routerDeps) *http.ServeMux {
+	// Роли: viewer читает результаты своего тенанта, submitter дополнительно создаёт и отменяет
+	// запросы, admin видит все тенанты, управляет potfile и ключами.
+	withRole := func(role string) func(http.HandlerFunc) http.Handler {
+		return func(h http.HandlerFunc) http.Handler {
+			return handlers.RequireAPIKey(d.apiKeys, role, h)
+		}
+	}
+	viewer, submitter := withRole(auth.RoleViewer), withRole(auth.RoleSubmitter)
+	admin := func(h http.HandlerFunc) http.Handler {
+		return handlers.RequireAdmin(d.adminToken, d.apiKeys, h)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/hash/crack", submitter(handlers.CrackHandler(ctx, d.service)))
+	mux.Handle("/api/hash/crack/batch", submitter(handlers.BatchCrackHandler(ctx, d.service)))
+	mux.Handle("/api/hash/batch/status", viewer(handlers.BatchStatusHandler(ctx, d.service, d.auditLog)))
+	mux.Handle("/api/hash/batch/results", viewer(handlers.BatchResultsHandler(ctx, d.service, d.auditLog)))
+	mux.Handle("/api/hash/requests", viewer(handlers.RequestsHandler(ctx, d.requests)))
+	mux.Handle("/api/hash/status", viewer(handlers.StatusHandler(ctx, d.requests, d.auditLog)))
+	mux.Handle("/api/hash/status/stream", viewer(handlers.StatusStreamHandler(ctx, d.requests, d.bus, d.streamInterval, d.auditLog)))
+	mux.Handle("/api/hash/status/ws", viewer(handlers.StatusWebSocketHandler(ctx, d.requests, d.bus, d.streamInterval, d.auditLog)))
+	mux.Handle("/api/hash/cancel", submitter(handlers.CancelHandler(ctx, d.service)))
+	mux.Handle("/api/hash/webhooks", viewer(handlers.WebhookDeliveriesHandler(ctx, d.requests, d.webhooks)))
+	mux.Handle("/api/potfile", admin(handlers.PotfileHandler(ctx, d.potfile, d.auditLog)))
+	mux.HandleFunc("/api/openapi.json", handlers.OpenAPIHandler(openapi.Spec))
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/healthz", handlers.HealthHandler())
+	mux.HandleFunc("/readyz", handlers.ReadyHandler(d.readiness...))
+	mux.Handle("/api/admin/keys", admin(handlers.APIKeysHandler(ctx, d.apiKeys)))
+	mux.Handle("/api/admin/audit", admin(handlers.AuditHandler(ctx, d.auditEvents)))
+	mux.Handle("/api/admin/workers", admin(handlers.WorkersHandler(ctx, d.workers)))
+	mux.Handle("/api/admin/settings", admin(handlers.SettingsHandler(d.service)))
+	mux.Handle("/api/admin/audit/export", admin(handlers.AuditExportHandler(ctx, d.auditEvents, d.auditLog)))
+	mux.Handle("/internal/api/manager/hash/crack/request",
+		handlers.RequireInternalToken(d.internalToken, handlers.WorkerResponseHandler(ctx, d.service, d.signingKeys)))
+	return mux
+}
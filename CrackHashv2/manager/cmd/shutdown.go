@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"CrackHash/internal/logging"
+	"CrackHash/manager/internal/queue"
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/store"
+)
+
+type shutdownDeps struct {
+	http    *http.Server
+	grpc    *grpc.Server
+	service service.ManagerServiceImpl
+	// queue равен nil, если RabbitMQ был недоступен при старте.
+	queue         queue.TaskQueue
+	responsesDone <-chan struct{}
+	store         *store.MongoRequestStore
+	// tracing выгружает накопленные спаны в коллектор.
+	tracing func(context.Context) error
+}
+
+// gracefulShutdown останавливает менеджер в пределах дедлайна ctx: перестаёт принимать
+// запросы, дочитывает уже выданные брокером ответы воркеров, дожидается публикаций задач
+// и закрывает RabbitMQ и MongoDB. Шаг, не уложившийся в дедлайн, прерывается.
+func gracefulShutdown(ctx context.Context, d shutdownDeps) {
+	logger := logging.Component("shutdown")
+	grpcStopped := make(chan struct{})
+	go func() {
+		d.grpc.GracefulStop()
+		close(grpcStopped)
+	}()
+	if err := d.http.Shutdown(ctx); err != nil {
+		logger.Warn("HTTP-сервер остановлен принудительно", "error", err)
+	}
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		logger.Warn("gRPC-сервер остановлен принудительно")
+		d.grpc.Stop()
+	}
+
+	if d.queue != nil {
+		if err := d.queue.StopConsuming(); err != nil {
+			logger.Error("Ошибка остановки приёма ответов воркеров", "error", err)
+		}
+	}
+	select {
+	case <-d.responsesDone:
+	case <-ctx.Done():
+		logger.Warn("Не дождались обработки ответов воркеров, неподтверждённые вернутся в очередь")
+	}
+
+	if err := d.service.Drain(ctx); err != nil {
+		logger.Warn("Публикации задач не завершены, они останутся pending", "error", err)
+	}
+	if d.queue != nil {
+		if err := d.queue.Close(); err != nil {
+			logger.Error("Ошибка закрытия соединения с RabbitMQ", "error", err)
+		}
+	}
+
+	// Отключение от MongoDB получает отдельный короткий срок, даже если общий дедлайн истёк:
+	// иначе незавершённые операции драйвера обрываются без ответа серверу.
+	closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.store.Close(closeCtx); err != nil {
+		logger.Error("Ошибка отключения от MongoDB", "error", err)
+	}
+	if err := d.tracing(closeCtx); err != nil {
+		logger.Error("Ошибка выгрузки спанов", "error", err)
+	}
+	logger.Info("Менеджер остановлен")
+}
+
+// every вызывает fn с периодом interval, пока ctx не отменён.
+func every(ctx context.Context, interval time.Duration, fn func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+}
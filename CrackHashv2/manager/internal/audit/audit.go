@@ -0,0 +1,45 @@
+// Package audit записывает в журнал, кто и когда отправлял хэши и получал результаты.
+package audit
+
+import (
+	"context"
+	"time"
+
+	"CrackHash/internal/logging"
+	"CrackHash/manager/internal/auth"
+	"CrackHash/manager/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// Logger дополняет события данными вызывающего из context. Методы nil-безопасны:
+// без настроенного журнала аудит просто не ведётся.
+type Logger struct {
+	store store.AuditStore
+	now   func() time.Time
+}
+
+func NewLogger(s store.AuditStore) *Logger {
+	return &Logger{store: s, now: time.Now}
+}
+
+// Record добавляет событие action по запросу requestID. Ошибка записи не прерывает
+// обработку запроса, но логируется.
+func (l *Logger) Record(ctx context.Context, action, requestID string, details map[string]string) {
+	if l == nil {
+		return
+	}
+	e := store.AuditEvent{
+		ID:        uuid.New().String(),
+		Time:      l.now().UTC(),
+		Action:    action,
+		RequestID: requestID,
+		Details:   details,
+	}
+	if p, ok := auth.PrincipalFrom(ctx); ok {
+		e.KeyID, e.KeyName, e.Tenant, e.Role = p.KeyID, p.Name, p.Tenant, p.Role
+	}
+	if err := l.store.Append(e); err != nil {
+		logging.Request("audit", requestID).Error("Ошибка записи события", "action", action, "error", err)
+	}
+}
@@ -0,0 +1,166 @@
+// Package auth — API-ключи: генерация, хранение в виде хэша и передача владельца ключа через context.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"CrackHash/manager/internal/store"
+)
+
+const (
+	KeyPrefix    = "chk_"
+	HeaderAPIKey = "X-API-Key"
+	// HeaderInternalToken передаёт общий секрет воркеров для внутренних эндпоинтов.
+	HeaderInternalToken = "X-Internal-Token"
+)
+
+// Роли упорядочены по возрастанию прав: каждая следующая включает возможности предыдущей.
+const (
+	RoleViewer    = "viewer"
+	RoleSubmitter = "submitter"
+	RoleAdmin     = "admin"
+
+	DefaultRole   = RoleSubmitter
+	DefaultTenant = "default"
+)
+
+var roleRank = map[string]int{
+	RoleViewer:    1,
+	RoleSubmitter: 2,
+	RoleAdmin:     3,
+}
+
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// Principal — владелец API-ключа, от имени которого выполняется запрос.
+type Principal struct {
+	KeyID         string
+	Name          string
+	Tenant        string
+	Role          string
+	MaxConcurrent int
+	DailyKeyspace int64
+}
+
+// AdminTokenPrincipal — владелец запросов с ADMIN_TOKEN, чтобы они тоже попадали в аудит.
+var AdminTokenPrincipal = Principal{Name: "ADMIN_TOKEN", Role: RoleAdmin}
+
+// Has сообщает, покрывает ли роль владельца ключа требуемую роль.
+func (p Principal) Has(role string) bool {
+	return roleRank[p.Role] >= roleRank[role]
+}
+
+// CanAccess разрешает доступ к данным тенанта: администратору — к любым, остальным — только к своим.
+// Вызовы без владельца ключа (внутренние, фоновые) не ограничиваются: публичные маршруты
+// всегда проходят через проверку ключа.
+func CanAccess(ctx context.Context, tenant string) bool {
+	p, ok := PrincipalFrom(ctx)
+	if !ok || p.Has(RoleAdmin) {
+		return true
+	}
+	return p.Tenant == tenant
+}
+
+// TenantScope возвращает тенант, которым нужно ограничить выборку, или "" для выборки без ограничения.
+func TenantScope(ctx context.Context) string {
+	p, ok := PrincipalFrom(ctx)
+	if !ok || p.Has(RoleAdmin) {
+		return ""
+	}
+	return p.Tenant
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// GenerateKey возвращает новый ключ и его хэш для хранения.
+func GenerateKey() (raw, keyHash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	raw = KeyPrefix + base64.RawURLEncoding.EncodeToString(buf)
+	return raw, HashKey(raw), nil
+}
+
+func HashKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// DisplayPrefix — начало ключа, по которому его можно узнать в списке, не раскрывая целиком.
+func DisplayPrefix(raw string) string {
+	if len(raw) <= len(KeyPrefix)+6 {
+		return raw
+	}
+	return raw[:len(KeyPrefix)+6]
+}
+
+// Authenticate ищет неотозванный ключ по его хэшу.
+func Authenticate(keys store.APIKeyStore, raw string) (Principal, bool) {
+	if raw == "" {
+		return Principal{}, false
+	}
+	key, ok := keys.GetByHash(HashKey(raw))
+	if !ok || key.Revoked {
+		return Principal{}, false
+	}
+	// Ключи, созданные до появления ролей, работают как submitter тенанта default.
+	role, tenant := key.Role, key.Tenant
+	if role == "" {
+		role = DefaultRole
+	}
+	if tenant == "" {
+		tenant = DefaultTenant
+	}
+	return Principal{
+		KeyID:         key.ID,
+		Name:          key.Name,
+		Tenant:        tenant,
+		Role:          role,
+		MaxConcurrent: key.MaxConcurrent,
+		DailyKeyspace: key.DailyKeyspace,
+	}, true
+}
+
+// KeyFromRequest читает ключ из X-API-Key или из Authorization: Bearer.
+func KeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get(HeaderAPIKey); key != "" {
+		return key
+	}
+	return BearerToken(r.Header.Get("Authorization"))
+}
+
+func BearerToken(header string) string {
+	const prefix = "bearer "
+	if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		return strings.TrimSpace(header[len(prefix):])
+	}
+	return ""
+}
+
+// TokenEqual сравнивает секреты за постоянное время; пустой ожидаемый токен не совпадает ни с чем.
+func TokenEqual(expected, got string) bool {
+	if expected == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(got)) == 1
+}
@@ -0,0 +1,78 @@
+package auth_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/manager/internal/auth"
+	"CrackHash/manager/internal/store"
+)
+
+func TestAuthenticate(t *testing.T) {
+	keys := store.NewAPIKeyStore()
+	raw, keyHash, err := auth.GenerateKey()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(raw, auth.KeyPrefix))
+	require.NotContains(t, keyHash, raw)
+
+	keys.Create(store.APIKey{ID: "k1", Name: "ci", KeyHash: keyHash, MaxConcurrent: 2})
+
+	p, ok := auth.Authenticate(keys, raw)
+	require.True(t, ok)
+	require.Equal(t, "k1", p.KeyID)
+	require.Equal(t, 2, p.MaxConcurrent)
+
+	_, ok = auth.Authenticate(keys, raw+"x")
+	require.False(t, ok)
+
+	key, _ := keys.Get("k1")
+	key.Revoked = true
+	keys.Update(key)
+	_, ok = auth.Authenticate(keys, raw)
+	require.False(t, ok)
+}
+
+func TestKeyFromRequest(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer chk_abc")
+	require.Equal(t, "chk_abc", auth.KeyFromRequest(r))
+
+	r.Header.Set(auth.HeaderAPIKey, "chk_header")
+	require.Equal(t, "chk_header", auth.KeyFromRequest(r))
+
+	require.False(t, auth.TokenEqual("", ""))
+	require.True(t, auth.TokenEqual("secret", "secret"))
+}
+
+func TestRolesAndTenantAccess(t *testing.T) {
+	viewer := auth.Principal{Tenant: "team-a", Role: auth.RoleViewer}
+	require.True(t, viewer.Has(auth.RoleViewer))
+	require.False(t, viewer.Has(auth.RoleSubmitter))
+	require.True(t, auth.Principal{Role: auth.RoleAdmin}.Has(auth.RoleSubmitter))
+	require.False(t, auth.Principal{}.Has(auth.RoleViewer))
+
+	ctx := auth.WithPrincipal(context.Background(), viewer)
+	require.True(t, auth.CanAccess(ctx, "team-a"))
+	require.False(t, auth.CanAccess(ctx, "team-b"))
+	require.Equal(t, "team-a", auth.TenantScope(ctx))
+
+	admin := auth.WithPrincipal(context.Background(), auth.Principal{Tenant: "ops", Role: auth.RoleAdmin})
+	require.True(t, auth.CanAccess(admin, "team-b"))
+	require.Equal(t, "", auth.TenantScope(admin))
+}
+
+func TestAuthenticate_LegacyKeyDefaults(t *testing.T) {
+	keys := store.NewAPIKeyStore()
+	raw, keyHash, err := auth.GenerateKey()
+	require.NoError(t, err)
+	keys.Create(store.APIKey{ID: "old", KeyHash: keyHash})
+
+	p, ok := auth.Authenticate(keys, raw)
+	require.True(t, ok)
+	require.Equal(t, auth.DefaultRole, p.Role)
+	require.Equal(t, auth.DefaultTenant, p.Tenant)
+}
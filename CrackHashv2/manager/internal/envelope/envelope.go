@@ -0,0 +1,156 @@
+// Package envelope реализует конвертное шифрование: данные шифруются одноразовым ключом данных
+// (AES-256-GCM), а ключ данных — мастер-ключом из локального файла.
+package envelope
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const keySize = 32
+
+var ErrUnknownKey = errors.New("мастер-ключ не найден в файле ключей")
+
+// Sealed — зашифрованное значение вместе с обёрнутым ключом данных и ID мастер-ключа.
+type Sealed struct {
+	KeyID      string `bson:"keyId" json:"keyId"`
+	WrappedKey []byte `bson:"wrappedKey" json:"wrappedKey"`
+	Ciphertext []byte `bson:"ciphertext" json:"ciphertext"`
+}
+
+// Keyring — набор мастер-ключей. Шифрование всегда идёт активным (первым в файле) ключом,
+// расшифровка — любым ключом из набора, что позволяет ротировать ключи без простоя.
+type Keyring struct {
+	keys   map[string][]byte
+	active string
+}
+
+// LoadKeyring читает файл ключей: по строке "id:base64-ключ" на мастер-ключ,
+// пустые строки и строки с # пропускаются.
+func LoadKeyring(path string) (*Keyring, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseKeyring(f)
+}
+
+func ParseKeyring(r io.Reader) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte)}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, encoded, ok := strings.Cut(line, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("строка %d: ожидается формат id:base64-ключ", lineNum)
+		}
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(secret) != keySize {
+			return nil, fmt.Errorf("строка %d: ключ %q должен быть %d байтами в base64", lineNum, id, keySize)
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("строка %d: ключ %q указан повторно", lineNum, id)
+		}
+		k.keys[id] = secret
+		if k.active == "" {
+			k.active = id
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if k.active == "" {
+		return nil, errors.New("файл ключей не содержит ни одного ключа")
+	}
+	return k, nil
+}
+
+// GenerateKeyLine создаёт новый мастер-ключ в формате строки файла ключей.
+func GenerateKeyLine(id string) (string, error) {
+	secret := make([]byte, keySize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return id + ":" + base64.StdEncoding.EncodeToString(secret), nil
+}
+
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// Seal шифрует plaintext новым ключом данных. aad (например, ID документа) не шифруется,
+// но привязывается к шифртексту: перенос значения в другой документ не расшифруется.
+func (k *Keyring) Seal(plaintext, aad []byte) (*Sealed, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	ciphertext, err := seal(dataKey, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := seal(k.keys[k.active], dataKey, []byte(k.active))
+	if err != nil {
+		return nil, err
+	}
+	return &Sealed{KeyID: k.active, WrappedKey: wrapped, Ciphertext: ciphertext}, nil
+}
+
+func (k *Keyring) Open(s *Sealed, aad []byte) ([]byte, error) {
+	master, ok := k.keys[s.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, s.KeyID)
+	}
+	dataKey, err := open(master, s.WrappedKey, []byte(s.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось развернуть ключ данных: %w", err)
+	}
+	return open(dataKey, s.Ciphertext, aad)
+}
+
+// seal возвращает nonce || AES-GCM(key, plaintext).
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("шифртекст слишком короткий")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
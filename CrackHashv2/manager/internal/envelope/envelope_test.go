@@ -0,0 +1,85 @@
+package envelope_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/manager/internal/envelope"
+)
+
+func newKeyring(t *testing.T, ids ...string) *envelope.Keyring {
+	t.Helper()
+	lines := []string{"# тестовые ключи"}
+	for _, id := range ids {
+		line, err := envelope.GenerateKeyLine(id)
+		require.NoError(t, err)
+		lines = append(lines, line)
+	}
+	k, err := envelope.ParseKeyring(strings.NewReader(strings.Join(lines, "\n")))
+	require.NoError(t, err)
+	return k
+}
+
+func TestSealOpen(t *testing.T) {
+	k := newKeyring(t, "k1")
+	sealed, err := k.Seal([]byte(`["abc"]`), []byte("req-1"))
+	require.NoError(t, err)
+	require.Equal(t, "k1", sealed.KeyID)
+	require.NotContains(t, string(sealed.Ciphertext), "abc")
+
+	plain, err := k.Open(sealed, []byte("req-1"))
+	require.NoError(t, err)
+	require.Equal(t, `["abc"]`, string(plain))
+
+	_, err = k.Open(sealed, []byte("req-2"))
+	require.Error(t, err, "шифртекст привязан к ID документа")
+
+	again, err := k.Seal([]byte(`["abc"]`), []byte("req-1"))
+	require.NoError(t, err)
+	require.NotEqual(t, sealed.WrappedKey, again.WrappedKey, "у каждого значения свой ключ данных")
+}
+
+func TestRotation(t *testing.T) {
+	oldLine, err := envelope.GenerateKeyLine("k1")
+	require.NoError(t, err)
+	newLine, err := envelope.GenerateKeyLine("k2")
+	require.NoError(t, err)
+
+	old, err := envelope.ParseKeyring(strings.NewReader(oldLine))
+	require.NoError(t, err)
+	sealed, err := old.Seal([]byte("secret"), nil)
+	require.NoError(t, err)
+
+	// Новый ключ добавлен первым и стал активным, старый остался для расшифровки.
+	both, err := envelope.ParseKeyring(strings.NewReader(newLine + "\n" + oldLine))
+	require.NoError(t, err)
+	require.Equal(t, "k2", both.ActiveKeyID())
+	plain, err := both.Open(sealed, nil)
+	require.NoError(t, err)
+	resealed, err := both.Seal(plain, nil)
+	require.NoError(t, err)
+	require.Equal(t, "k2", resealed.KeyID)
+
+	rotated, err := envelope.ParseKeyring(strings.NewReader(newLine))
+	require.NoError(t, err)
+	_, err = rotated.Open(sealed, nil)
+	require.ErrorIs(t, err, envelope.ErrUnknownKey)
+	plain, err = rotated.Open(resealed, nil)
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(plain))
+}
+
+func TestParseKeyringErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"empty":      "# только комментарий\n",
+		"no id":      ":AAAA",
+		"bad size":   "k1:AAAA",
+		"no colon":   "k1",
+		"bad base64": "k1:!!!",
+	} {
+		_, err := envelope.ParseKeyring(strings.NewReader(input))
+		require.Error(t, err, name)
+	}
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	TypeStatus   = "status"
+	TypeProgress = "progress"
+	TypePartial  = "partial"
+)
+
+type Event struct {
+	Type       string    `json:"type"`
+	RequestID  string    `json:"requestId"`
+	Status     string    `json:"status"`
+	Progress   int       `json:"progress"`
+	PartNumber int       `json:"partNumber,omitempty"`
+	Words      []string  `json:"words,omitempty"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan Event]struct{}
+	bufferSize  int
+}
+
+func NewBus(bufferSize int) *Bus {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	return &Bus{
+		subscribers: make(map[string]map[chan Event]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+// Publish не блокируется: если подписчик не успевает читать, событие для него отбрасывается.
+func (b *Bus) Publish(e Event) {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subscribers[e.RequestID] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (b *Bus) Subscribe(requestID string) (<-chan Event, func()) {
+	ch := make(chan Event, b.bufferSize)
+
+	b.mu.Lock()
+	if b.subscribers[requestID] == nil {
+		b.subscribers[requestID] = make(map[chan Event]struct{})
+	}
+	b.subscribers[requestID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers[requestID], ch)
+			if len(b.subscribers[requestID]) == 0 {
+				delete(b.subscribers, requestID)
+			}
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
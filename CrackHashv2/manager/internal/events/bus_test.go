@@ -0,0 +1,45 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/manager/internal/events"
+)
+
+func TestBus_PublishSubscribe(t *testing.T) {
+	bus := events.NewBus(4)
+	ch, unsubscribe := bus.Subscribe("req-1")
+	other, unsubscribeOther := bus.Subscribe("req-2")
+	defer unsubscribeOther()
+
+	bus.Publish(events.Event{Type: events.TypeStatus, RequestID: "req-1", Status: "READY"})
+
+	select {
+	case e := <-ch:
+		require.Equal(t, "READY", e.Status)
+		require.False(t, e.Timestamp.IsZero())
+	case <-time.After(time.Second):
+		t.Fatal("событие не получено")
+	}
+	require.Empty(t, other)
+
+	unsubscribe()
+	_, ok := <-ch
+	require.False(t, ok)
+	bus.Publish(events.Event{RequestID: "req-1"})
+}
+
+func TestBus_SlowSubscriberDoesNotBlock(t *testing.T) {
+	bus := events.NewBus(1)
+	ch, unsubscribe := bus.Subscribe("req-1")
+	defer unsubscribe()
+
+	for i := 0; i < 10; i++ {
+		bus.Publish(events.Event{RequestID: "req-1", Progress: i})
+	}
+	require.Len(t, ch, 1)
+	require.Equal(t, 0, (<-ch).Progress)
+}
@@ -0,0 +1,79 @@
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"CrackHash/manager/api/crackhashpb"
+	"CrackHash/manager/internal/auth"
+	"CrackHash/manager/internal/store"
+)
+
+// methodRoles — минимальная роль для каждого метода; методы вне списка доступны только admin.
+var methodRoles = map[string]string{
+	crackhashpb.CrackHash_SubmitCrack_FullMethodName: auth.RoleSubmitter,
+	crackhashpb.CrackHash_Cancel_FullMethodName:      auth.RoleSubmitter,
+	crackhashpb.CrackHash_GetStatus_FullMethodName:   auth.RoleViewer,
+	crackhashpb.CrackHash_WatchStatus_FullMethodName: auth.RoleViewer,
+}
+
+// AuthInterceptors проверяют API-ключ из метаданных x-api-key или authorization: Bearer
+// и роль так же, как HTTP-middleware, и кладут владельца ключа в context вызова.
+func AuthInterceptors(keys store.APIKeyStore) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			ctx, err := authenticate(ctx, keys, info.FullMethod)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.ChainStreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := authenticate(ss.Context(), keys, info.FullMethod)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+		}),
+	}
+}
+
+func authenticate(ctx context.Context, keys store.APIKeyStore, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	raw := first(md.Get("x-api-key"))
+	if raw == "" {
+		raw = auth.BearerToken(first(md.Get("authorization")))
+	}
+	principal, ok := auth.Authenticate(keys, raw)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "требуется действующий API-ключ")
+	}
+	role, ok := methodRoles[method]
+	if !ok {
+		role = auth.RoleAdmin
+	}
+	if !principal.Has(role) {
+		return nil, status.Error(codes.PermissionDenied, "недостаточно прав: требуется роль "+role)
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,160 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"CrackHash/manager/api/crackhashpb"
+	"CrackHash/manager/internal/audit"
+	"CrackHash/manager/internal/events"
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/store"
+	"CrackHash/manager/internal/types"
+)
+
+// Server реализует gRPC-сервис CrackHash поверх того же ManagerService, что и HTTP-обработчики.
+type Server struct {
+	crackhashpb.UnimplementedCrackHashServer
+
+	svc              service.ManagerService
+	reqStore         store.RequestStore
+	bus              service.EventSubscriber
+	progressInterval time.Duration
+	audit            *audit.Logger
+}
+
+func NewServer(
+	svc service.ManagerService,
+	reqStore store.RequestStore,
+	bus service.EventSubscriber,
+	progressInterval time.Duration,
+	auditLog *audit.Logger,
+) *Server {
+	return &Server{
+		svc:              svc,
+		reqStore:         reqStore,
+		bus:              bus,
+		progressInterval: progressInterval,
+		audit:            auditLog,
+	}
+}
+
+func (s *Server) SubmitCrack(ctx context.Context, req *crackhashpb.SubmitCrackRequest) (*crackhashpb.SubmitCrackResponse, error) {
+	requestID, err := s.svc.CreateTask(ctx, types.CrackRequest{
+		Hash:        req.GetHash(),
+		MaxLength:   int(req.GetMaxLength()),
+		Algorithm:   req.GetAlgorithm(),
+		CallbackURL: req.GetCallbackUrl(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &crackhashpb.SubmitCrackResponse{RequestId: requestID}, nil
+}
+
+func (s *Server) GetStatus(ctx context.Context, req *crackhashpb.GetStatusRequest) (*crackhashpb.StatusResponse, error) {
+	if req.GetRequestId() == "" {
+		return nil, missingRequestID()
+	}
+	state, ok := service.LookupRequest(ctx, s.reqStore, req.GetRequestId())
+	if !ok {
+		return nil, toStatus(service.ErrRequestNotFound)
+	}
+	if len(state.Data) > 0 {
+		s.audit.Record(ctx, store.AuditStatusRead, req.GetRequestId(), map[string]string{"channel": "grpc"})
+	}
+	return &crackhashpb.StatusResponse{
+		Status:   state.Status,
+		Data:     state.Data,
+		Progress: int32(service.Progress(state)),
+	}, nil
+}
+
+func (s *Server) Cancel(ctx context.Context, req *crackhashpb.CancelRequest) (*crackhashpb.StatusResponse, error) {
+	if req.GetRequestId() == "" {
+		return nil, missingRequestID()
+	}
+	if err := s.svc.CancelTask(ctx, req.GetRequestId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &crackhashpb.StatusResponse{Status: service.StatusCancelled, Progress: 100}, nil
+}
+
+func (s *Server) WatchStatus(req *crackhashpb.WatchStatusRequest, stream crackhashpb.CrackHash_WatchStatusServer) error {
+	if req.GetRequestId() == "" {
+		return missingRequestID()
+	}
+	if _, ok := service.LookupRequest(stream.Context(), s.reqStore, req.GetRequestId()); !ok {
+		return toStatus(service.ErrRequestNotFound)
+	}
+	var sendErr error
+	send := service.AuditedSend(stream.Context(), s.audit, req.GetRequestId(), "grpc", func(e events.Event) error {
+		sendErr = stream.Send(toEvent(e))
+		return sendErr
+	})
+	service.WatchStatus(stream.Context(), req.GetRequestId(), s.reqStore, s.bus, s.progressInterval, send)
+	if sendErr != nil {
+		return sendErr
+	}
+	return stream.Context().Err()
+}
+
+func toEvent(e events.Event) *crackhashpb.StatusEvent {
+	return &crackhashpb.StatusEvent{
+		Type:       e.Type,
+		RequestId:  e.RequestID,
+		Status:     e.Status,
+		Progress:   int32(e.Progress),
+		PartNumber: int32(e.PartNumber),
+		Words:      e.Words,
+		Timestamp:  timestamppb.New(e.Timestamp),
+	}
+}
+
+func missingRequestID() error {
+	return fieldViolation(codes.InvalidArgument, types.ErrCodeMissingParameter, "request_id", "request_id не задан")
+}
+
+func fieldViolation(code codes.Code, reason, field, message string) error {
+	st := status.New(code, message)
+	if detailed, err := st.WithDetails(
+		&errdetails.ErrorInfo{Reason: reason, Domain: "crackhash"},
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: message}}},
+	); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// toStatus переводит ошибки сервиса в gRPC-коды; машиночитаемый код из HTTP API передаётся в ErrorInfo.Reason.
+func toStatus(err error) error {
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		return fieldViolation(codes.InvalidArgument, validationErr.Code, validationErr.Field, validationErr.Message)
+	}
+
+	code, reason := codes.Internal, types.ErrCodeInternal
+	switch {
+	case errors.Is(err, service.ErrRequestNotFound):
+		code, reason = codes.NotFound, types.ErrCodeNotFound
+	case errors.Is(err, service.ErrNotCancellable):
+		code, reason = codes.FailedPrecondition, types.ErrCodeNotCancellable
+	case errors.Is(err, service.ErrQueueFull):
+		code, reason = codes.ResourceExhausted, types.ErrCodeQueueFull
+	case errors.Is(err, service.ErrConcurrentQuota):
+		code, reason = codes.ResourceExhausted, types.ErrCodeConcurrentQuota
+	case errors.Is(err, service.ErrKeyspaceQuota):
+		code, reason = codes.ResourceExhausted, types.ErrCodeKeyspaceQuota
+	}
+	st := status.New(code, err.Error())
+	if detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: "crackhash"}); detailErr == nil {
+		st = detailed
+	}
+	return st.Err()
+}
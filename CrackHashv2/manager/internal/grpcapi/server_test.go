@@ -0,0 +1,176 @@
+package grpcapi_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"CrackHash/manager/api/crackhashpb"
+	"CrackHash/manager/internal/auth"
+	"CrackHash/manager/internal/events"
+	"CrackHash/manager/internal/grpcapi"
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/store"
+	"CrackHash/manager/internal/types"
+)
+
+const hashAbc = "900150983cd24fb0d6963f7d28e17f72"
+
+type apiKeyCredentials string
+
+func (k apiKeyCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	if k == "" {
+		return nil, nil
+	}
+	return map[string]string{"x-api-key": string(k)}, nil
+}
+
+func (apiKeyCredentials) RequireTransportSecurity() bool { return false }
+
+func newClient(t *testing.T) (crackhashpb.CrackHashClient, service.ManagerServiceImpl) {
+	keys := store.NewAPIKeyStore()
+	raw, keyHash, err := auth.GenerateKey()
+	require.NoError(t, err)
+	keys.Create(store.APIKey{ID: "grpc", KeyHash: keyHash})
+	return newClientWithKey(t, keys, raw)
+}
+
+func newClientWithKey(t *testing.T, keys store.APIKeyStore, apiKey string) (crackhashpb.CrackHashClient, service.ManagerServiceImpl) {
+	reqStore := store.NewRequestStore()
+	bus := events.NewBus(16)
+	svc := service.NewManagerService(reqStore, nil, time.Minute, service.WithEventPublisher(bus))
+
+	listener := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpcapi.AuthInterceptors(keys)...)
+	crackhashpb.RegisterCrackHashServer(srv, grpcapi.NewServer(svc, reqStore, bus, 50*time.Millisecond, nil))
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(apiKeyCredentials(apiKey)))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return crackhashpb.NewCrackHashClient(conn), svc
+}
+
+func TestServer_SubmitStatusCancel(t *testing.T) {
+	client, _ := newClient(t)
+	ctx := context.Background()
+
+	submitted, err := client.SubmitCrack(ctx, &crackhashpb.SubmitCrackRequest{Hash: hashAbc, MaxLength: 3})
+	require.NoError(t, err)
+	require.NotEmpty(t, submitted.RequestId)
+
+	st, err := client.GetStatus(ctx, &crackhashpb.GetStatusRequest{RequestId: submitted.RequestId})
+	require.NoError(t, err)
+	require.Equal(t, service.StatusInProgress, st.Status)
+
+	cancelled, err := client.Cancel(ctx, &crackhashpb.CancelRequest{RequestId: submitted.RequestId})
+	require.NoError(t, err)
+	require.Equal(t, service.StatusCancelled, cancelled.Status)
+
+	_, err = client.Cancel(ctx, &crackhashpb.CancelRequest{RequestId: submitted.RequestId})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = client.GetStatus(ctx, &crackhashpb.GetStatusRequest{RequestId: "missing"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_ValidationError(t *testing.T) {
+	client, _ := newClient(t)
+
+	_, err := client.SubmitCrack(context.Background(), &crackhashpb.SubmitCrackRequest{Hash: "xyz", MaxLength: 3})
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+
+	var info *errdetails.ErrorInfo
+	var badRequest *errdetails.BadRequest
+	for _, d := range st.Details() {
+		switch d := d.(type) {
+		case *errdetails.ErrorInfo:
+			info = d
+		case *errdetails.BadRequest:
+			badRequest = d
+		}
+	}
+	require.NotNil(t, info)
+	require.Equal(t, types.ErrCodeInvalidHash, info.Reason)
+	require.NotNil(t, badRequest)
+	require.Equal(t, "hash", badRequest.FieldViolations[0].Field)
+}
+
+func TestServer_WatchStatus(t *testing.T) {
+	client, svc := newClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	submitted, err := client.SubmitCrack(ctx, &crackhashpb.SubmitCrackRequest{Hash: hashAbc, MaxLength: 3})
+	require.NoError(t, err)
+
+	stream, err := client.WatchStatus(ctx, &crackhashpb.WatchStatusRequest{RequestId: submitted.RequestId})
+	require.NoError(t, err)
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, events.TypeStatus, first.Type)
+	require.Equal(t, service.StatusInProgress, first.Status)
+
+	resp := types.CrackHashWorkerResponse{RequestId: submitted.RequestId}
+	resp.Answers.Words = []string{"abc"}
+	svc.HandleWorkerResponse(ctx, resp)
+
+	var last *crackhashpb.StatusEvent
+	for {
+		e, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		last = e
+	}
+	require.NotNil(t, last)
+	require.Equal(t, service.StatusReady, last.Status)
+	require.Equal(t, []string{"abc"}, last.Words)
+}
+
+func TestServer_RequiresAPIKey(t *testing.T) {
+	client, _ := newClientWithKey(t, store.NewAPIKeyStore(), "chk_unknown")
+
+	_, err := client.GetStatus(context.Background(), &crackhashpb.GetStatusRequest{RequestId: "x"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	stream, err := client.WatchStatus(context.Background(), &crackhashpb.WatchStatusRequest{RequestId: "x"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestServer_RolesAndTenants(t *testing.T) {
+	keys := store.NewAPIKeyStore()
+	viewerKey, viewerHash, err := auth.GenerateKey()
+	require.NoError(t, err)
+	keys.Create(store.APIKey{ID: "viewer", KeyHash: viewerHash, Tenant: "team-b", Role: auth.RoleViewer})
+	client, svc := newClientWithKey(t, keys, viewerKey)
+	ctx := context.Background()
+
+	_, err = client.SubmitCrack(ctx, &crackhashpb.SubmitCrackRequest{Hash: hashAbc, MaxLength: 3})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	teamA := auth.WithPrincipal(ctx, auth.Principal{KeyID: "a", Tenant: "team-a", Role: auth.RoleSubmitter})
+	id, err := svc.CreateTask(teamA, types.CrackRequest{Hash: hashAbc, MaxLength: 3})
+	require.NoError(t, err)
+
+	_, err = client.GetStatus(ctx, &crackhashpb.GetStatusRequest{RequestId: id})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"CrackHash/internal/logging"
+	"CrackHash/manager/internal/audit"
+	"CrackHash/manager/internal/store"
+	"CrackHash/manager/internal/types"
+)
+
+const (
+	// auditListTimeout ограничивает чтение страницы журнала, auditExportTimeout — выгрузку
+	// целиком; выгрузка тем же сроком продлевает дедлайн записи ответа.
+	auditListTimeout   = 10 * time.Second
+	auditExportTimeout = 5 * time.Minute
+)
+
+var auditActions = map[string]bool{
+	store.AuditSubmit:     true,
+	store.AuditStatusRead: true,
+	store.AuditCancel:     true,
+	store.AuditExport:     true,
+	store.AuditSettings:   true,
+}
+
+// AuditHandler возвращает события журнала аудита в хронологическом порядке (не более limit).
+func AuditHandler(ctx context.Context, events store.AuditStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		filter, ok := parseAuditFilter(w, r.URL.Query())
+		if !ok {
+			return
+		}
+		filter.Limit = defaultListLimit
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			limit, err := strconv.Atoi(raw)
+			if err != nil || limit < 1 || limit > maxListLimit {
+				writeErrorBody(w, http.StatusBadRequest, types.ErrorBody{
+					Code:    types.ErrCodeInvalidParameter,
+					Message: fmt.Sprintf("limit должен быть числом от 1 до %d", maxListLimit),
+					Field:   "limit",
+				})
+				return
+			}
+			filter.Limit = limit
+		}
+
+		queryCtx, cancel := context.WithTimeout(r.Context(), auditListTimeout)
+		defer cancel()
+		list := []store.AuditEvent{}
+		err := events.Query(queryCtx, filter, func(e store.AuditEvent) error {
+			list = append(list, e)
+			return nil
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, types.ErrCodeInternal, "Ошибка чтения журнала аудита: "+err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, list)
+	}
+}
+
+// AuditExportHandler выгружает все подходящие события в JSONL, по одному на строку.
+// Сама выгрузка тоже записывается в журнал.
+func AuditExportHandler(ctx context.Context, events store.AuditStore, auditLog *audit.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		filter, ok := parseAuditFilter(w, r.URL.Query())
+		if !ok {
+			return
+		}
+		auditLog.Record(r.Context(), store.AuditExport, filter.RequestID, map[string]string{"resource": "audit"})
+
+		// Журнал за большой период выгружается дольше стандартного WriteTimeout сервера.
+		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(auditExportTimeout))
+		queryCtx, cancel := context.WithTimeout(r.Context(), auditExportTimeout)
+		defer cancel()
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"audit.jsonl\"")
+		encoder := json.NewEncoder(w)
+		if err := events.Query(queryCtx, filter, func(e store.AuditEvent) error {
+			return encoder.Encode(e)
+		}); err != nil {
+			logging.Component("audit").Error("Ошибка выгрузки журнала аудита", "error", err)
+		}
+	}
+}
+
+func parseAuditFilter(w http.ResponseWriter, query url.Values) (store.AuditFilter, bool) {
+	filter := store.AuditFilter{
+		Action:    query.Get("action"),
+		KeyID:     query.Get("keyId"),
+		Tenant:    query.Get("tenant"),
+		RequestID: query.Get("requestId"),
+	}
+	if filter.Action != "" && !auditActions[filter.Action] {
+		writeErrorBody(w, http.StatusBadRequest, types.ErrorBody{
+			Code:    types.ErrCodeInvalidParameter,
+			Message: "Неизвестное действие: " + filter.Action,
+			Field:   "action",
+		})
+		return filter, false
+	}
+	for name, dst := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			writeErrorBody(w, http.StatusBadRequest, types.ErrorBody{
+				Code:    types.ErrCodeInvalidParameter,
+				Message: name + " должен быть временем в формате RFC 3339",
+				Field:   name,
+			})
+			return filter, false
+		}
+		*dst = t
+	}
+	return filter, true
+}
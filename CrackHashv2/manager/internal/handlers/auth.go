@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"CrackHash/internal/logging"
+	"CrackHash/manager/internal/auth"
+	"CrackHash/manager/internal/store"
+	"CrackHash/manager/internal/types"
+
+	"github.com/google/uuid"
+)
+
+// RequireAPIKey пропускает запрос только с действующим API-ключом, чья роль покрывает role,
+// и кладёт владельца ключа в context.
+func RequireAPIKey(keys store.APIKeyStore, role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.Authenticate(keys, auth.KeyFromRequest(r))
+		if !ok {
+			writeUnauthorized(w, "Требуется действующий API-ключ")
+			return
+		}
+		if !principal.Has(role) {
+			writeForbiddenRole(w, role)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// RequireAdmin пропускает запросы с ADMIN_TOKEN (нужен для создания первых ключей)
+// или с API-ключом роли admin.
+func RequireAdmin(token string, keys store.APIKeyStore, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented := auth.KeyFromRequest(r)
+		if auth.TokenEqual(token, presented) {
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), auth.AdminTokenPrincipal)))
+			return
+		}
+		principal, ok := auth.Authenticate(keys, presented)
+		if !ok {
+			writeUnauthorized(w, "Требуется токен администратора или API-ключ с ролью admin")
+			return
+		}
+		if !principal.Has(auth.RoleAdmin) {
+			writeForbiddenRole(w, auth.RoleAdmin)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func writeForbiddenRole(w http.ResponseWriter, role string) {
+	writeError(w, http.StatusForbidden, types.ErrCodeForbidden, "Недостаточно прав: требуется роль "+role)
+}
+
+// RequireInternalToken защищает внутренние эндпоинты общим секретом воркеров.
+// Без настроенного токена внутренние эндпоинты недоступны.
+func RequireInternalToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.TokenEqual(token, r.Header.Get(auth.HeaderInternalToken)) {
+			writeError(w, http.StatusForbidden, types.ErrCodeForbidden, "Доступ к внутреннему API запрещён")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="crackhash"`)
+	writeError(w, http.StatusUnauthorized, types.ErrCodeUnauthorized, message)
+}
+
+// APIKeysHandler — управление ключами: GET список, POST создание,
+// PATCH ?id= изменение лимитов, DELETE ?id= отзыв.
+func APIKeysHandler(ctx context.Context, keys store.APIKeyStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			list := keys.List()
+			infos := make([]types.APIKeyInfo, 0, len(list))
+			for _, k := range list {
+				infos = append(infos, apiKeyInfo(keys, k))
+			}
+			writeJSON(w, http.StatusOK, infos)
+		case http.MethodPost:
+			req, ok := decodeAPIKeyRequest(w, r)
+			if !ok {
+				return
+			}
+			raw, keyHash, err := auth.GenerateKey()
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+				return
+			}
+			key := store.APIKey{
+				ID:        uuid.New().String(),
+				KeyHash:   keyHash,
+				Prefix:    auth.DisplayPrefix(raw),
+				Tenant:    auth.DefaultTenant,
+				Role:      auth.DefaultRole,
+				CreatedAt: time.Now().UTC(),
+			}
+			applyAPIKeyRequest(&key, req)
+			keys.Create(key)
+			logging.Component("apiKeys").Info("Создан API-ключ", "id", key.ID, "name", key.Name)
+			writeJSON(w, http.StatusCreated, types.APIKeyCreatedResponse{Key: raw, APIKeyInfo: apiKeyInfo(keys, key)})
+		case http.MethodPatch, http.MethodDelete:
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				writeMissingParameter(w, "id")
+				return
+			}
+			key, ok := keys.Get(id)
+			if !ok {
+				writeError(w, http.StatusNotFound, types.ErrCodeNotFound, "API-ключ не найден")
+				return
+			}
+			if r.Method == http.MethodDelete {
+				key.Revoked = true
+				logging.Component("apiKeys").Info("API-ключ отозван", "id", key.ID)
+			} else {
+				req, ok := decodeAPIKeyRequest(w, r)
+				if !ok {
+					return
+				}
+				applyAPIKeyRequest(&key, req)
+			}
+			keys.Update(key)
+			writeJSON(w, http.StatusOK, apiKeyInfo(keys, key))
+		default:
+			writeMethodNotAllowed(w, strings.Join([]string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete}, ", "))
+		}
+	}
+}
+
+func decodeAPIKeyRequest(w http.ResponseWriter, r *http.Request) (types.APIKeyRequest, bool) {
+	var req types.APIKeyRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, types.ErrCodeInvalidJSON, "Некорректный JSON: "+err.Error())
+		return req, false
+	}
+	if err := validateAPIKeyRequest(req); err != nil {
+		writeErrorBody(w, http.StatusBadRequest, *err)
+		return req, false
+	}
+	return req, true
+}
+
+const negativeLimitMessage = "Лимит не может быть отрицательным (0 — без ограничения)"
+
+func validateAPIKeyRequest(req types.APIKeyRequest) *types.ErrorBody {
+	if req.MaxConcurrent != nil && *req.MaxConcurrent < 0 {
+		return &types.ErrorBody{Code: types.ErrCodeInvalidParameter, Message: negativeLimitMessage, Field: "maxConcurrent"}
+	}
+	if req.DailyKeyspace != nil && *req.DailyKeyspace < 0 {
+		return &types.ErrorBody{Code: types.ErrCodeInvalidParameter, Message: negativeLimitMessage, Field: "dailyKeyspace"}
+	}
+	if req.Role != nil && !auth.ValidRole(*req.Role) {
+		return &types.ErrorBody{
+			Code:    types.ErrCodeInvalidParameter,
+			Message: "Роль должна быть одной из: admin, submitter, viewer",
+			Field:   "role",
+		}
+	}
+	if req.Tenant != nil && strings.TrimSpace(*req.Tenant) == "" {
+		return &types.ErrorBody{Code: types.ErrCodeInvalidParameter, Message: "Тенант не может быть пустым", Field: "tenant"}
+	}
+	return nil
+}
+
+func applyAPIKeyRequest(key *store.APIKey, req types.APIKeyRequest) {
+	if req.Name != nil {
+		key.Name = *req.Name
+	}
+	if req.Tenant != nil {
+		key.Tenant = strings.TrimSpace(*req.Tenant)
+	}
+	if req.Role != nil {
+		key.Role = *req.Role
+	}
+	if req.MaxConcurrent != nil {
+		key.MaxConcurrent = *req.MaxConcurrent
+	}
+	if req.DailyKeyspace != nil {
+		key.DailyKeyspace = *req.DailyKeyspace
+	}
+}
+
+func apiKeyInfo(keys store.APIKeyStore, k store.APIKey) types.APIKeyInfo {
+	return types.APIKeyInfo{
+		ID:                k.ID,
+		Name:              k.Name,
+		Prefix:            k.Prefix,
+		Tenant:            k.Tenant,
+		Role:              k.Role,
+		MaxConcurrent:     k.MaxConcurrent,
+		DailyKeyspace:     k.DailyKeyspace,
+		KeyspaceUsedToday: keys.KeyspaceUsed(k.ID, time.Now().UTC().Format(time.DateOnly)),
+		Revoked:           k.Revoked,
+		CreatedAt:         k.CreatedAt,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"CrackHash/internal/logging"
+	"CrackHash/manager/internal/audit"
+	"CrackHash/manager/internal/potfile"
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/store"
+	"CrackHash/manager/internal/types"
+)
+
+// maxBatchBody рассчитан на MaxBatchSize элементов с запасом на имя пользователя и
+// хэш SHA-512 в каждом.
+const maxBatchBody = service.MaxBatchSize * 512
+
+type BatchService interface {
+	CreateBatch(ctx context.Context, items []types.BatchItem) (string, error)
+	BatchStatus(ctx context.Context, batchID string) (types.BatchStatusResponse, error)
+}
+
+func BatchCrackHandler(ctx context.Context, svc BatchService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, http.MethodPost)
+			return
+		}
+
+		// Большой batch создаётся дольше стандартного WriteTimeout сервера.
+		http.NewResponseController(w).SetWriteDeadline(time.Now().Add(5 * time.Minute))
+
+		var items []types.BatchItem
+		r.Body = http.MaxBytesReader(w, r.Body, maxBatchBody)
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/json" {
+			decoder := json.NewDecoder(r.Body)
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&items); err != nil {
+				writeError(w, http.StatusBadRequest, types.ErrCodeInvalidJSON, "Некорректный JSON: "+err.Error())
+				return
+			}
+		} else {
+			rawMaxLength := r.URL.Query().Get("maxLength")
+			if rawMaxLength == "" {
+				writeMissingParameter(w, "maxLength")
+				return
+			}
+			maxLength, err := strconv.Atoi(rawMaxLength)
+			if err != nil {
+				writeErrorBody(w, http.StatusBadRequest, types.ErrorBody{
+					Code:    types.ErrCodeInvalidMaxLength,
+					Message: "maxLength должен быть целым числом",
+					Field:   "maxLength",
+				})
+				return
+			}
+			items, err = parseHashList(r.Body, maxLength, r.URL.Query().Get("algorithm"))
+			if errors.Is(err, service.ErrBatchTooLarge) {
+				writeServiceError(w, err)
+				return
+			}
+			if err != nil {
+				writeError(w, http.StatusBadRequest, types.ErrCodeInvalidHashList, "Некорректный список хэшей: "+err.Error())
+				return
+			}
+		}
+
+		batchID, err := svc.CreateBatch(r.Context(), items)
+		if err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.BatchResponse{BatchID: batchID})
+	}
+}
+
+func BatchStatusHandler(ctx context.Context, svc BatchService, auditLog *audit.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, ok := batchStatus(svc, w, r)
+		if !ok {
+			return
+		}
+		auditBatchData(r.Context(), auditLog, store.AuditStatusRead, status, map[string]string{"channel": "batch"})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	}
+}
+
+func BatchResultsHandler(ctx context.Context, svc BatchService, auditLog *audit.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "potfile" {
+			writeErrorBody(w, http.StatusBadRequest, types.ErrorBody{
+				Code:    types.ErrCodeInvalidParameter,
+				Message: "Неизвестный формат: " + format,
+				Field:   "format",
+			})
+			return
+		}
+		status, ok := batchStatus(svc, w, r)
+		if !ok {
+			return
+		}
+		auditBatchData(r.Context(), auditLog, store.AuditExport, status, map[string]string{"format": format})
+
+		switch format {
+		case "csv":
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.csv\"", status.BatchID))
+			if err := writeBatchCSV(w, status); err != nil {
+				logging.Component("batch").Error("Ошибка выгрузки batch в CSV", "batchId", status.BatchID, "error", err)
+			}
+		case "potfile":
+			var entries []potfile.Entry
+			for _, item := range status.Items {
+				for _, word := range item.Data {
+					entries = append(entries, potfile.Entry{Hash: strings.ToLower(item.Hash), Plaintext: word})
+				}
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.potfile\"", status.BatchID))
+			if err := potfile.Write(w, entries); err != nil {
+				logging.Component("batch").Error("Ошибка выгрузки batch в potfile", "batchId", status.BatchID, "error", err)
+			}
+		}
+	}
+}
+
+func batchStatus(svc BatchService, w http.ResponseWriter, r *http.Request) (types.BatchStatusResponse, bool) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, http.MethodGet)
+		return types.BatchStatusResponse{}, false
+	}
+	batchID := r.URL.Query().Get("batchId")
+	if batchID == "" {
+		writeMissingParameter(w, "batchId")
+		return types.BatchStatusResponse{}, false
+	}
+	status, err := svc.BatchStatus(r.Context(), batchID)
+	if err != nil {
+		writeServiceError(w, err)
+		return types.BatchStatusResponse{}, false
+	}
+	return status, true
+}
+
+// auditBatchData записывает событие по каждому запросу batch, чьи найденные слова попали в ответ.
+func auditBatchData(ctx context.Context, auditLog *audit.Logger, action string, status types.BatchStatusResponse, details map[string]string) {
+	for _, item := range status.Items {
+		if len(item.Data) == 0 {
+			continue
+		}
+		d := map[string]string{"batchId": status.BatchID}
+		for k, v := range details {
+			d[k] = v
+		}
+		auditLog.Record(ctx, action, item.RequestID, d)
+	}
+}
+
+func writeBatchCSV(w io.Writer, status types.BatchStatusResponse) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"user", "hash", "requestId", "status", "plaintext"}); err != nil {
+		return err
+	}
+	for _, item := range status.Items {
+		if len(item.Data) == 0 {
+			if err := cw.Write([]string{item.User, item.Hash, item.RequestID, item.Status, ""}); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, word := range item.Data {
+			if err := cw.Write([]string{item.User, item.Hash, item.RequestID, item.Status, word}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// parseHashList разбирает список в формате hashcat: "hash" или "user:hash" на строку.
+func parseHashList(r io.Reader, maxLength int, algorithm string) ([]types.BatchItem, error) {
+	var items []types.BatchItem
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		item := types.BatchItem{Hash: line, MaxLength: maxLength, Algorithm: algorithm}
+		if idx := strings.LastIndex(line, ":"); idx >= 0 {
+			item.User = line[:idx]
+			item.Hash = line[idx+1:]
+		}
+		if item.Hash == "" {
+			return nil, fmt.Errorf("строка %d: пустой хэш", lineNum)
+		}
+		items = append(items, item)
+		if len(items) > service.MaxBatchSize {
+			return nil, service.ErrBatchTooLarge
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
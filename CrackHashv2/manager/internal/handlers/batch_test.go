@@ -0,0 +1,145 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/manager/internal/handlers"
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/store"
+	"CrackHash/manager/internal/types"
+)
+
+func newBatchServer(t *testing.T) (*httptest.Server, service.ManagerServiceImpl) {
+	svc := service.NewManagerService(store.NewRequestStore(), nil, time.Minute,
+		service.WithBatchStore(store.NewBatchStore()))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/hash/crack/batch", handlers.BatchCrackHandler(context.Background(), svc))
+	mux.HandleFunc("/api/hash/batch/status", handlers.BatchStatusHandler(context.Background(), svc, nil))
+	mux.HandleFunc("/api/hash/batch/results", handlers.BatchResultsHandler(context.Background(), svc, nil))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, svc
+}
+
+func TestBatchHandlers_HashListAndResults(t *testing.T) {
+	server, svc := newBatchServer(t)
+
+	body := "# leak dump\nalice:0cc175b9c0f1b6a831c399e269772661\n\n900150983cd24fb0d6963f7d28e17f72\n"
+	resp, err := http.Post(server.URL+"/api/hash/crack/batch?maxLength=3", "text/plain", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var created types.BatchResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	require.NotEmpty(t, created.BatchID)
+
+	status := getBatchStatus(t, server.URL, created.BatchID)
+	require.Equal(t, service.StatusInProgress, status.Status)
+	require.Equal(t, 2, status.Total)
+	require.Equal(t, "alice", status.Items[0].User)
+
+	workerResp := types.CrackHashWorkerResponse{RequestId: status.Items[0].RequestID}
+	workerResp.Answers.Words = []string{"a"}
+	svc.HandleWorkerResponse(context.Background(), workerResp)
+
+	status = getBatchStatus(t, server.URL, created.BatchID)
+	require.Equal(t, service.StatusInProgress, status.Status)
+	require.Equal(t, 1, status.Ready)
+
+	require.NoError(t, svc.CancelTask(context.Background(), status.Items[1].RequestID))
+	status = getBatchStatus(t, server.URL, created.BatchID)
+	require.Equal(t, service.StatusPartial, status.Status)
+
+	csvResp, err := http.Get(server.URL + "/api/hash/batch/results?format=csv&batchId=" + created.BatchID)
+	require.NoError(t, err)
+	defer csvResp.Body.Close()
+	rows, err := csv.NewReader(csvResp.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	require.Equal(t, []string{"alice", "0cc175b9c0f1b6a831c399e269772661", status.Items[0].RequestID, "READY", "a"}, rows[1])
+
+	potResp, err := http.Get(server.URL + "/api/hash/batch/results?format=potfile&batchId=" + created.BatchID)
+	require.NoError(t, err)
+	defer potResp.Body.Close()
+	pot, err := io.ReadAll(potResp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "0cc175b9c0f1b6a831c399e269772661:a\n", string(pot))
+}
+
+func TestBatchHandlers_JSONArray(t *testing.T) {
+	server, _ := newBatchServer(t)
+
+	body := `[{"user":"bob","hash":"0cc175b9c0f1b6a831c399e269772661","maxLength":1},{"hash":"900150983cd24fb0d6963f7d28e17f72","maxLength":3}]`
+	resp, err := http.Post(server.URL+"/api/hash/crack/batch", "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var created types.BatchResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	status := getBatchStatus(t, server.URL, created.BatchID)
+	require.Equal(t, 2, status.Total)
+	require.Equal(t, 2, status.InProgress)
+}
+
+func TestBatchHandlers_Errors(t *testing.T) {
+	server, _ := newBatchServer(t)
+
+	resp, err := http.Post(server.URL+"/api/hash/crack/batch", "text/plain", strings.NewReader("abc\n"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+"/api/hash/crack/batch", "application/json", strings.NewReader("[]"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Тело больше лимита отклоняется, не дочитываясь до конца.
+	huge := "[" + strings.Repeat(" ", 5<<20) + "]"
+	resp, err = http.Post(server.URL+"/api/hash/crack/batch", "application/json", strings.NewReader(huge))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(server.URL + "/api/hash/batch/status?batchId=missing")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func getBatchStatus(t *testing.T, baseURL, batchID string) types.BatchStatusResponse {
+	t.Helper()
+	resp, err := http.Get(baseURL + "/api/hash/batch/status?batchId=" + batchID)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var status types.BatchStatusResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	return status
+}
+
+func TestBatchHandlers_RejectsBatchLargerThanQueue(t *testing.T) {
+	svc := service.NewManagerService(store.NewRequestStore(), nil, time.Minute,
+		service.WithBatchStore(store.NewBatchStore()), service.WithMaxQueueSize(2))
+	server := httptest.NewServer(handlers.BatchCrackHandler(context.Background(), svc))
+	t.Cleanup(server.Close)
+
+	body := "900150983cd24fb0d6963f7d28e17f72\n0cc175b9c0f1b6a831c399e269772661\n92eb5ffee6ae2fec3ad71c777531578f\n"
+	resp, err := http.Post(server.URL+"?maxLength=3", "text/plain", strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, "в очереди нет места для всех трёх задач")
+}
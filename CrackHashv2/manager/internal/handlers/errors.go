@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/types"
+)
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeErrorBody(w, status, types.ErrorBody{Code: code, Message: message})
+}
+
+func writeErrorBody(w http.ResponseWriter, status int, body types.ErrorBody) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(types.ErrorResponse{Error: body})
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeError(w, http.StatusMethodNotAllowed, types.ErrCodeMethodNotAllowed, "Метод не поддерживается")
+}
+
+func writeMissingParameter(w http.ResponseWriter, name string) {
+	writeErrorBody(w, http.StatusBadRequest, types.ErrorBody{
+		Code:    types.ErrCodeMissingParameter,
+		Message: name + " не задан",
+		Field:   name,
+	})
+}
+
+// writeServiceError переводит ошибки сервиса в HTTP-статус и машиночитаемый код.
+func writeServiceError(w http.ResponseWriter, err error) {
+	var validationErr *service.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		writeErrorBody(w, http.StatusBadRequest, types.ErrorBody{
+			Code:    validationErr.Code,
+			Message: validationErr.Message,
+			Field:   validationErr.Field,
+		})
+	case errors.Is(err, service.ErrRequestNotFound):
+		writeError(w, http.StatusNotFound, types.ErrCodeNotFound, "Запрос не найден")
+	case errors.Is(err, service.ErrBatchNotFound):
+		writeError(w, http.StatusNotFound, types.ErrCodeNotFound, "Batch не найден")
+	case errors.Is(err, service.ErrNotCancellable):
+		writeError(w, http.StatusConflict, types.ErrCodeNotCancellable, err.Error())
+	case errors.Is(err, service.ErrQueueFull):
+		w.Header().Set("Retry-After", "5")
+		writeError(w, http.StatusServiceUnavailable, types.ErrCodeQueueFull, err.Error())
+	case errors.Is(err, service.ErrConcurrentQuota):
+		writeError(w, http.StatusTooManyRequests, types.ErrCodeConcurrentQuota, err.Error())
+	case errors.Is(err, service.ErrKeyspaceQuota):
+		writeError(w, http.StatusTooManyRequests, types.ErrCodeKeyspaceQuota, err.Error())
+	case errors.Is(err, service.ErrBatchEmpty):
+		writeError(w, http.StatusBadRequest, types.ErrCodeBatchEmpty, err.Error())
+	case errors.Is(err, service.ErrBatchTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, types.ErrCodeBatchTooLarge, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, types.ErrCodeInternal, err.Error())
+	}
+}
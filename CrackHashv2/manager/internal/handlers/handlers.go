@@ -4,40 +4,48 @@ import (
 	"context"
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"io"
 	"net/http"
-	"time"
+	"strconv"
+	"strings"
 
+	"CrackHash/internal/logging"
+	"CrackHash/internal/signing"
+	"CrackHash/manager/internal/audit"
+	"CrackHash/manager/internal/auth"
+	"CrackHash/manager/internal/hashalg"
+	"CrackHash/manager/internal/potfile"
 	"CrackHash/manager/internal/service"
 	"CrackHash/manager/internal/store"
 	"CrackHash/manager/internal/types"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
+const maxRequestBody = 1 << 20
+
 type ManagerService interface {
-	CreateTask(ctx context.Context, hash string, maxLength int) (string, error)
+	CreateTask(ctx context.Context, req types.CrackRequest) (string, error)
 }
 
 func CrackHandler(ctx context.Context, svc ManagerService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
-			http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
-			return
-		}
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			http.Error(w, "Ошибка чтения запроса", http.StatusBadRequest)
+			writeMethodNotAllowed(w, http.MethodPost)
 			return
 		}
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		decoder.DisallowUnknownFields()
 		var req types.CrackRequest
-		err = json.Unmarshal(body, &req)
-		if err != nil {
-			http.Error(w, "Некорректный JSON", http.StatusBadRequest)
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, types.ErrCodeInvalidJSON, "Некорректный JSON: "+err.Error())
 			return
 		}
 
-		requestID, err := svc.CreateTask(ctx, req.Hash, req.MaxLength)
+		requestID, err := svc.CreateTask(r.Context(), req)
 		if err != nil {
-			http.Error(w, "Ошибка создания задачи: "+err.Error(), http.StatusInternalServerError)
+			writeServiceError(w, err)
 			return
 		}
 		resp := types.RequestResponse{RequestID: requestID}
@@ -46,74 +54,253 @@ func CrackHandler(ctx context.Context, svc ManagerService) http.HandlerFunc {
 	}
 }
 
-func StatusHandler(ctx context.Context, store store.RequestStore) http.HandlerFunc {
+func StatusHandler(ctx context.Context, reqStore store.RequestStore, auditLog *audit.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
 		requestID := r.URL.Query().Get("requestId")
 		if requestID == "" {
-			http.Error(w, "requestId не задан", http.StatusBadRequest)
+			writeMissingParameter(w, "requestId")
 			return
 		}
-		state, ok := store.Get(requestID)
+		state, ok := service.LookupRequest(r.Context(), reqStore, requestID)
 		if !ok {
-			http.Error(w, "Запрос не найден", http.StatusNotFound)
-			return
-		}
-		progress := 0
-		if state.Status == "IN_PROGRESS" && !state.StartTime.IsZero() && state.Timeout > 0 {
-			elapsed := time.Since(state.StartTime)
-			if elapsed >= state.Timeout {
-				progress = 100
-			} else {
-				progress = int(float64(elapsed) / float64(state.Timeout) * 100)
-				if progress < 1 && elapsed > 0 {
-					progress = 1
-				}
-			}
-		} else if state.Status == "READY" || state.Status == "ERROR" {
-			progress = 100
+			writeServiceError(w, service.ErrRequestNotFound)
+			return
+		}
+		if len(state.Data) > 0 {
+			auditLog.Record(r.Context(), store.AuditStatusRead, requestID, map[string]string{"channel": "http"})
 		}
 		resp := types.StatusResponse{
 			Status:   state.Status,
 			Data:     state.Data,
-			Progress: progress,
+			Progress: service.Progress(state),
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(resp)
 	}
 }
 
-func WorkerResponseHandler(ctx context.Context, store store.RequestStore) http.HandlerFunc {
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// RequestsHandler — список запросов тенанта вызывающего, от новых к старым.
+// Администратор видит все тенанты и может сузить выборку параметром tenant.
+func RequestsHandler(ctx context.Context, reqStore store.RequestStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		query := r.URL.Query()
+		filter := store.RequestFilter{
+			Tenant: auth.TenantScope(r.Context()),
+			Status: query.Get("status"),
+			Limit:  defaultListLimit,
+		}
+		if filter.Tenant == "" {
+			filter.Tenant = query.Get("tenant")
+		}
+		if raw := query.Get("limit"); raw != "" {
+			limit, err := strconv.Atoi(raw)
+			if err != nil || limit < 1 || limit > maxListLimit {
+				writeErrorBody(w, http.StatusBadRequest, types.ErrorBody{
+					Code:    types.ErrCodeInvalidParameter,
+					Message: fmt.Sprintf("limit должен быть числом от 1 до %d", maxListLimit),
+					Field:   "limit",
+				})
+				return
+			}
+			filter.Limit = limit
+		}
+
+		stored := reqStore.List(filter)
+		resp := types.RequestListResponse{Requests: make([]types.RequestSummary, 0, len(stored))}
+		for _, req := range stored {
+			resp.Requests = append(resp.Requests, types.RequestSummary{
+				RequestID: req.ID,
+				Tenant:    req.State.Tenant,
+				Status:    req.State.Status,
+				Hash:      req.State.Hash,
+				MaxLength: req.State.MaxLength,
+				Algorithm: req.State.Algorithm,
+				Progress:  service.Progress(req.State),
+				StartTime: req.State.StartTime,
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+type TaskCanceller interface {
+	CancelTask(ctx context.Context, requestID string) error
+}
+
+func CancelHandler(ctx context.Context, svc TaskCanceller) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeMethodNotAllowed(w, http.MethodPost)
+			return
+		}
+		requestID := r.URL.Query().Get("requestId")
+		if requestID == "" {
+			writeMissingParameter(w, "requestId")
+			return
+		}
+		if err := svc.CancelTask(r.Context(), requestID); err != nil {
+			writeServiceError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(types.StatusResponse{Status: service.StatusCancelled, Progress: 100})
+	}
+}
+
+type WorkerResponseProcessor interface {
+	HandleWorkerResponse(ctx context.Context, resp types.CrackHashWorkerResponse)
+}
+
+// WorkerResponseHandler принимает ответ воркера только с верной HMAC-подписью тела.
+func WorkerResponseHandler(ctx context.Context, svc WorkerResponseProcessor, keys *signing.Keyring) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPatch {
-			http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+			writeMethodNotAllowed(w, http.MethodPatch)
 			return
 		}
-		body, err := io.ReadAll(r.Body)
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBody))
 		if err != nil {
-			http.Error(w, "Ошибка чтения запроса", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, types.ErrCodeInvalidXML, "Ошибка чтения запроса")
+			return
+		}
+		if err := keys.Verify(r.Header.Get(signing.HeaderKeyID), r.Header.Get(signing.HeaderSignature), body); err != nil {
+			logging.Request("workerResponse", r.Header.Get(logging.HeaderRequestID)).Warn("Ответ воркера отклонён", "error", err)
+			writeError(w, http.StatusForbidden, types.ErrCodeInvalidSignature, "Подпись ответа не прошла проверку")
 			return
 		}
 		var workerResp types.CrackHashWorkerResponse
 		err = xml.Unmarshal(body, &workerResp)
 		if err != nil {
-			http.Error(w, "Некорректный XML", http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, types.ErrCodeInvalidXML, "Некорректный XML")
 			return
 		}
+		workerResp.SigningKeyID = r.Header.Get(signing.HeaderKeyID)
 
-		state, ok := store.Get(workerResp.RequestId)
-		if ok {
-			if state.Data == nil {
-				state.Data = []string{}
+		// Обработка не должна прерываться вместе с запросом воркера, поэтому из r.Context()
+		// берётся только спан.
+		svc.HandleWorkerResponse(trace.ContextWithSpan(ctx, trace.SpanFromContext(r.Context())), workerResp)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func PotfileHandler(ctx context.Context, potStore store.PotfileStore, auditLog *audit.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		algorithm := r.URL.Query().Get("algorithm")
+		if algorithm == "" {
+			algorithm = service.AlgorithmMD5
+		}
+		alg, ok := hashalg.Lookup(algorithm)
+		if !ok {
+			writeErrorBody(w, http.StatusBadRequest, types.ErrorBody{
+				Code:    types.ErrCodeUnsupportedAlgorithm,
+				Message: "Алгоритм не поддерживается: " + algorithm,
+				Field:   "algorithm",
+			})
+			return
+		}
+		algorithm = alg.Name
+
+		switch r.Method {
+		case http.MethodGet:
+			stored := potStore.All(algorithm)
+			entries := make([]potfile.Entry, 0, len(stored))
+			for i, e := range stored {
+				// Один хэш, взломанный несколькими тенантами, выгружается один раз.
+				if i > 0 && stored[i-1].Hash == e.Hash && stored[i-1].Plaintext == e.Plaintext {
+					continue
+				}
+				entries = append(entries, potfile.Entry{Hash: e.Hash, Plaintext: e.Plaintext})
 			}
-			state.Data = append(state.Data, workerResp.Answers.Words...)
-			if len(state.Data) > 0 {
-				state.Status = service.StatusReady
-				if state.Timer != nil {
-					state.Timer.Stop()
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Header().Set("Content-Disposition", "attachment; filename=\""+algorithm+".potfile\"")
+			auditLog.Record(r.Context(), store.AuditExport, "", map[string]string{
+				"resource":  "potfile",
+				"algorithm": algorithm,
+				"entries":   strconv.Itoa(len(entries)),
+			})
+			if err := potfile.Write(w, entries); err != nil {
+				logging.Component("potfile").Error("Ошибка выгрузки potfile", "algorithm", algorithm, "error", err)
+			}
+		case http.MethodPost:
+			entries, err := potfile.Parse(http.MaxBytesReader(w, r.Body, maxRequestBody))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, types.ErrCodeInvalidPotfile, "Некорректный potfile: "+err.Error())
+				return
+			}
+			for i, e := range entries {
+				if !alg.ValidHash(e.Hash) {
+					writeError(w, http.StatusBadRequest, types.ErrCodeInvalidPotfile,
+						fmt.Sprintf("Некорректный potfile: запись %d содержит хэш не в формате %s", i+1, alg.Name))
+					return
+				}
+				// Запись из potfile сразу становится ответом READY, поэтому её проверяем так же,
+				// как слова от воркеров.
+				if !strings.EqualFold(alg.Sum(e.Plaintext), e.Hash) {
+					writeError(w, http.StatusBadRequest, types.ErrCodeInvalidPotfile,
+						fmt.Sprintf("Некорректный potfile: в записи %d слово не даёт хэш %s", i+1, e.Hash))
+					return
 				}
 			}
-			store.Update(workerResp.RequestId, state)
+			for _, e := range entries {
+				potStore.Add(store.PotfileEntry{
+					Algorithm: algorithm,
+					Hash:      e.Hash,
+					Plaintext: e.Plaintext,
+				})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(types.PotfileImportResponse{Imported: len(entries)})
+		default:
+			writeMethodNotAllowed(w, "GET, POST")
 		}
-		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func WebhookDeliveriesHandler(ctx context.Context, reqStore store.RequestStore, webhooks store.WebhookStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		requestID := r.URL.Query().Get("requestId")
+		if requestID == "" {
+			writeMissingParameter(w, "requestId")
+			return
+		}
+		if _, ok := service.LookupRequest(r.Context(), reqStore, requestID); !ok {
+			writeServiceError(w, service.ErrRequestNotFound)
+			return
+		}
+		deliveries := webhooks.ListByRequest(requestID)
+		if deliveries == nil {
+			deliveries = []store.WebhookDelivery{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(deliveries)
+	}
+}
+
+func OpenAPIHandler(spec []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
 	}
 }
@@ -0,0 +1,219 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/manager/internal/handlers"
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/store"
+	"CrackHash/manager/internal/types"
+)
+
+func TestCrackHandler_Validation(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	svc := service.NewManagerService(reqStore, nil, time.Minute, service.WithMaxLengthLimit(5))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/hash/crack", handlers.CrackHandler(context.Background(), svc))
+	mux.HandleFunc("/api/hash/status", handlers.StatusHandler(context.Background(), reqStore, nil))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantCode   string
+		wantField  string
+	}{
+		{
+			name:       "valid request",
+			method:     http.MethodPost,
+			path:       "/api/hash/crack",
+			body:       `{"hash":"0CC175B9C0F1B6A831C399E269772661","maxLength":1}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "malformed json",
+			method:     http.MethodPost,
+			path:       "/api/hash/crack",
+			body:       `{"hash":`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   types.ErrCodeInvalidJSON,
+		},
+		{
+			name:       "unknown field",
+			method:     http.MethodPost,
+			path:       "/api/hash/crack",
+			body:       `{"hash":"0cc175b9c0f1b6a831c399e269772661","maxLength":1,"extra":true}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   types.ErrCodeInvalidJSON,
+		},
+		{
+			name:       "short hash",
+			method:     http.MethodPost,
+			path:       "/api/hash/crack",
+			body:       `{"hash":"dummy","maxLength":4}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   types.ErrCodeInvalidHash,
+			wantField:  "hash",
+		},
+		{
+			name:       "non-hex hash",
+			method:     http.MethodPost,
+			path:       "/api/hash/crack",
+			body:       `{"hash":"zcc175b9c0f1b6a831c399e269772661","maxLength":1}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   types.ErrCodeInvalidHash,
+			wantField:  "hash",
+		},
+		{
+			name:       "zero maxLength",
+			method:     http.MethodPost,
+			path:       "/api/hash/crack",
+			body:       `{"hash":"0cc175b9c0f1b6a831c399e269772661","maxLength":0}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   types.ErrCodeInvalidMaxLength,
+			wantField:  "maxLength",
+		},
+		{
+			name:       "maxLength above limit",
+			method:     http.MethodPost,
+			path:       "/api/hash/crack",
+			body:       `{"hash":"0cc175b9c0f1b6a831c399e269772661","maxLength":6}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   types.ErrCodeInvalidMaxLength,
+			wantField:  "maxLength",
+		},
+		{
+			name:       "unsupported algorithm",
+			method:     http.MethodPost,
+			path:       "/api/hash/crack",
+			body:       `{"hash":"0cc175b9c0f1b6a831c399e269772661","maxLength":1,"algorithm":"sha1"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   types.ErrCodeUnsupportedAlgorithm,
+			wantField:  "algorithm",
+		},
+		{
+			name:       "bad callback",
+			method:     http.MethodPost,
+			path:       "/api/hash/crack",
+			body:       `{"hash":"0cc175b9c0f1b6a831c399e269772661","maxLength":1,"callbackUrl":"ftp://x"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   types.ErrCodeInvalidCallbackURL,
+			wantField:  "callbackUrl",
+		},
+		{
+			name:       "internal callback",
+			method:     http.MethodPost,
+			path:       "/api/hash/crack",
+			body:       `{"hash":"0cc175b9c0f1b6a831c399e269772661","maxLength":1,"callbackUrl":"http://169.254.169.254/latest/meta-data/"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   types.ErrCodeInvalidCallbackURL,
+			wantField:  "callbackUrl",
+		},
+		{
+			name:       "wrong method",
+			method:     http.MethodGet,
+			path:       "/api/hash/crack",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantCode:   types.ErrCodeMethodNotAllowed,
+		},
+		{
+			name:       "status without requestId",
+			method:     http.MethodGet,
+			path:       "/api/hash/status",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   types.ErrCodeMissingParameter,
+			wantField:  "requestId",
+		},
+		{
+			name:       "unknown request",
+			method:     http.MethodGet,
+			path:       "/api/hash/status?requestId=missing",
+			wantStatus: http.StatusNotFound,
+			wantCode:   types.ErrCodeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, tt.wantStatus, resp.StatusCode)
+			require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			if tt.wantCode == "" {
+				return
+			}
+			var errResp types.ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+			require.Equal(t, tt.wantCode, errResp.Error.Code)
+			require.Equal(t, tt.wantField, errResp.Error.Field)
+			require.NotEmpty(t, errResp.Error.Message)
+		})
+	}
+	require.Equal(t, 1, reqStore.Count())
+}
+
+func TestBatchHandlers_RejectsInvalidItems(t *testing.T) {
+	server, _ := newBatchServer(t)
+
+	body := "0cc175b9c0f1b6a831c399e269772661\nnot-a-hash\n"
+	resp, err := http.Post(server.URL+"/api/hash/crack/batch?maxLength=1", "text/plain", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var created types.BatchResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	status := getBatchStatus(t, server.URL, created.BatchID)
+	require.Equal(t, 1, status.Rejected)
+	require.Equal(t, service.StatusRejected, status.Items[1].Status)
+	require.Empty(t, status.Items[1].RequestID)
+}
+
+func TestPotfileHandler_Import(t *testing.T) {
+	potStore := store.NewPotfileStore()
+	server := httptest.NewServer(handlers.PotfileHandler(context.Background(), potStore, nil))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "valid", body: "0CC175B9C0F1B6A831C399E269772661:a\n", wantStatus: http.StatusOK},
+		{name: "plaintext does not match hash", body: "900150983cd24fb0d6963f7d28e17f72:abd\n", wantStatus: http.StatusBadRequest},
+		{name: "too large", body: strings.Repeat("0cc175b9c0f1b6a831c399e269772661:a\n", 1<<15), wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(server.URL, "text/plain", strings.NewReader(tt.body))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, tt.wantStatus, resp.StatusCode)
+			if tt.wantStatus != http.StatusOK {
+				var errResp types.ErrorResponse
+				require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+				require.Equal(t, types.ErrCodeInvalidPotfile, errResp.Error.Code)
+			}
+		})
+	}
+	// Отклонённый файл не добавляет ни одной записи.
+	require.Len(t, potStore.All(service.AlgorithmMD5), 1)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"CrackHash/manager/internal/types"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// ReadinessCheck проверяет одну зависимость; ошибка означает, что менеджер не готов.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// HealthHandler — liveness: процесс жив и обслуживает HTTP, зависимости не проверяются.
+func HealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, types.HealthResponse{Status: "ok"})
+	}
+}
+
+// ReadyHandler — readiness: 200, только если все проверки прошли, иначе 503 с причиной
+// по каждой зависимости.
+func ReadyHandler(checks ...ReadinessCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		resp := types.HealthResponse{Status: "ok", Checks: make(map[string]string, len(checks))}
+		code := http.StatusOK
+		for _, c := range checks {
+			if err := c.Check(ctx); err != nil {
+				resp.Checks[c.Name] = err.Error()
+				resp.Status = "unavailable"
+				code = http.StatusServiceUnavailable
+				continue
+			}
+			resp.Checks[c.Name] = "ok"
+		}
+		writeJSON(w, code, resp)
+	}
+}
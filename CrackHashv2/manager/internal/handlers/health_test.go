@@ -0,0 +1,47 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/manager/internal/handlers"
+	"CrackHash/manager/internal/types"
+)
+
+func TestReadyHandler(t *testing.T) {
+	connected := false
+	handler := handlers.ReadyHandler(
+		handlers.ReadinessCheck{Name: "mongodb", Check: func(context.Context) error { return nil }},
+		handlers.ReadinessCheck{Name: "rabbitmq", Check: func(context.Context) error {
+			if !connected {
+				return errors.New("нет соединения с RabbitMQ")
+			}
+			return nil
+		}},
+	)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var resp types.HealthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "unavailable", resp.Status)
+	require.Equal(t, "ok", resp.Checks["mongodb"])
+	require.Equal(t, "нет соединения с RabbitMQ", resp.Checks["rabbitmq"])
+
+	connected = true
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// Liveness не зависит от внешних сервисов.
+	rec = httptest.NewRecorder()
+	handlers.HealthHandler()(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/types"
+)
+
+type SettingsService interface {
+	Settings() service.Settings
+	UpdateSettings(ctx context.Context, update func(*service.Settings), source string) error
+}
+
+// SettingsHandler показывает изменяемые без перезапуска настройки (GET) и меняет их (PATCH).
+// Незаданные в PATCH поля не трогаются, даже если их параллельно меняет SIGHUP.
+func SettingsHandler(svc SettingsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, settingsBody(svc.Settings()))
+		case http.MethodPatch:
+			var patch types.SettingsPatch
+			decoder := json.NewDecoder(r.Body)
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(&patch); err != nil {
+				writeError(w, http.StatusBadRequest, types.ErrCodeInvalidJSON, "Некорректный JSON: "+err.Error())
+				return
+			}
+			var timeout time.Duration
+			if patch.ResponseTimeout != nil {
+				d, err := time.ParseDuration(*patch.ResponseTimeout)
+				if err != nil {
+					writeErrorBody(w, http.StatusBadRequest, types.ErrorBody{
+						Code:    types.ErrCodeInvalidParameter,
+						Message: "responseTimeout должен быть длительностью, например 3m",
+						Field:   "responseTimeout",
+					})
+					return
+				}
+				timeout = d
+			}
+			err := svc.UpdateSettings(r.Context(), func(s *service.Settings) {
+				if patch.ResponseTimeout != nil {
+					s.ResponseTimeout = timeout
+				}
+				if patch.MaxQueueSize != nil {
+					s.MaxQueueSize = *patch.MaxQueueSize
+				}
+				if patch.MaxLengthLimit != nil {
+					s.MaxLengthLimit = *patch.MaxLengthLimit
+				}
+			}, "api")
+			if err != nil {
+				writeServiceError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, settingsBody(svc.Settings()))
+		default:
+			writeMethodNotAllowed(w, strings.Join([]string{http.MethodGet, http.MethodPatch}, ", "))
+		}
+	}
+}
+
+func settingsBody(s service.Settings) types.Settings {
+	return types.Settings{
+		ResponseTimeout: s.ResponseTimeout.String(),
+		MaxQueueSize:    s.MaxQueueSize,
+		MaxLengthLimit:  s.MaxLengthLimit,
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"CrackHash/internal/logging"
+	"CrackHash/manager/internal/audit"
+	"CrackHash/manager/internal/events"
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/store"
+
+	"github.com/gorilla/websocket"
+)
+
+type EventSubscriber = service.EventSubscriber
+
+func StatusStreamHandler(ctx context.Context, reqStore store.RequestStore, bus EventSubscriber, progressInterval time.Duration, auditLog *audit.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeMethodNotAllowed(w, http.MethodGet)
+			return
+		}
+		requestID := r.URL.Query().Get("requestId")
+		if requestID == "" {
+			writeMissingParameter(w, "requestId")
+			return
+		}
+		if _, ok := service.LookupRequest(r.Context(), reqStore, requestID); !ok {
+			writeServiceError(w, service.ErrRequestNotFound)
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			logging.Request("statusStream", requestID).Warn("Не удалось снять WriteTimeout", "error", err)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		rc.Flush()
+
+		send := service.AuditedSend(r.Context(), auditLog, requestID, "sse", func(e events.Event) error {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+				return err
+			}
+			return rc.Flush()
+		})
+		service.WatchStatus(r.Context(), requestID, reqStore, bus, progressInterval, send)
+	}
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+func StatusWebSocketHandler(ctx context.Context, reqStore store.RequestStore, bus EventSubscriber, progressInterval time.Duration, auditLog *audit.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.URL.Query().Get("requestId")
+		if requestID == "" {
+			writeMissingParameter(w, "requestId")
+			return
+		}
+		if _, ok := service.LookupRequest(r.Context(), reqStore, requestID); !ok {
+			writeServiceError(w, service.ErrRequestNotFound)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			logging.Request("statusStream", requestID).Warn("Ошибка upgrade WebSocket", "error", err)
+			return
+		}
+		defer conn.Close()
+
+		streamCtx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			defer cancel()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+
+		send := service.AuditedSend(r.Context(), auditLog, requestID, "websocket", func(e events.Event) error {
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			return conn.WriteJSON(e)
+		})
+		service.WatchStatus(streamCtx, requestID, reqStore, bus, progressInterval, send)
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second))
+	}
+}
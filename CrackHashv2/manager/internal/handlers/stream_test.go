@@ -0,0 +1,137 @@
+package handlers_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/manager/internal/events"
+	"CrackHash/manager/internal/handlers"
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/store"
+	"CrackHash/manager/internal/types"
+)
+
+type streamFixture struct {
+	store  store.RequestStore
+	svc    service.ManagerServiceImpl
+	server *httptest.Server
+}
+
+func newStreamFixture(t *testing.T) streamFixture {
+	reqStore := store.NewRequestStore()
+	bus := events.NewBus(16)
+	svc := service.NewManagerService(reqStore, nil, time.Minute, service.WithEventPublisher(bus))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/hash/status/stream", handlers.StatusStreamHandler(context.Background(), reqStore, bus, 50*time.Millisecond, nil))
+	mux.HandleFunc("/api/hash/status/ws", handlers.StatusWebSocketHandler(context.Background(), reqStore, bus, 50*time.Millisecond, nil))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return streamFixture{store: reqStore, svc: svc, server: server}
+}
+
+func (f streamFixture) respond(t *testing.T, requestID string, words ...string) {
+	resp := types.CrackHashWorkerResponse{RequestId: requestID}
+	resp.Answers.Words = words
+	f.svc.HandleWorkerResponse(context.Background(), resp)
+}
+
+func TestStatusStreamHandler_SSE(t *testing.T) {
+	f := newStreamFixture(t)
+	id, err := f.svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3})
+	require.NoError(t, err)
+
+	resp, err := http.Get(f.server.URL + "/api/hash/status/stream?requestId=" + id)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	first := readSSE(t, reader)
+	require.Equal(t, events.TypeStatus, first.Type)
+	require.Equal(t, service.StatusInProgress, first.Status)
+
+	f.respond(t, id, "abc")
+
+	var got []events.Event
+	for {
+		e := readSSE(t, reader)
+		got = append(got, e)
+		if e.Type == events.TypeStatus {
+			break
+		}
+	}
+	last := got[len(got)-1]
+	require.Equal(t, service.StatusReady, last.Status)
+	require.Equal(t, []string{"abc"}, last.Words)
+
+	var partial *events.Event
+	for i := range got {
+		if got[i].Type == events.TypePartial {
+			partial = &got[i]
+		}
+	}
+	require.NotNil(t, partial)
+	require.Equal(t, []string{"abc"}, partial.Words)
+}
+
+func TestStatusStreamHandler_NotFound(t *testing.T) {
+	f := newStreamFixture(t)
+	resp, err := http.Get(f.server.URL + "/api/hash/status/stream?requestId=missing")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestStatusWebSocketHandler(t *testing.T) {
+	f := newStreamFixture(t)
+	id, err := f.svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3})
+	require.NoError(t, err)
+
+	wsURL := "ws" + strings.TrimPrefix(f.server.URL, "http") + "/api/hash/status/ws?requestId=" + id
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var e events.Event
+	require.NoError(t, conn.ReadJSON(&e))
+	require.Equal(t, service.StatusInProgress, e.Status)
+
+	require.NoError(t, f.svc.CancelTask(context.Background(), id))
+	for e.Type != events.TypeStatus || e.Status == service.StatusInProgress {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		require.NoError(t, conn.ReadJSON(&e))
+	}
+	require.Equal(t, service.StatusCancelled, e.Status)
+}
+
+func readSSE(t *testing.T, r *bufio.Reader) events.Event {
+	t.Helper()
+	var eventType, data string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		case line == "" && data != "":
+			var e events.Event
+			require.NoError(t, json.Unmarshal([]byte(data), &e))
+			require.Equal(t, eventType, e.Type)
+			return e
+		}
+	}
+}
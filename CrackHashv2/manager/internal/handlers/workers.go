@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"CrackHash/internal/logging"
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/types"
+)
+
+// WorkersHandler показывает счётчики проверки ответов по воркерам (GET)
+// и снимает карантин с воркера (DELETE ?id=).
+func WorkersHandler(ctx context.Context, tracker *service.WorkerTracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, tracker.List())
+		case http.MethodDelete:
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				writeMissingParameter(w, "id")
+				return
+			}
+			stats, ok := tracker.Release(id)
+			if !ok {
+				writeError(w, http.StatusNotFound, types.ErrCodeNotFound, "Воркер не найден")
+				return
+			}
+			logging.Component("workers").Info("Карантин воркера снят вручную", "workerId", id)
+			writeJSON(w, http.StatusOK, stats)
+		default:
+			writeMethodNotAllowed(w, strings.Join([]string{http.MethodGet, http.MethodDelete}, ", "))
+		}
+	}
+}
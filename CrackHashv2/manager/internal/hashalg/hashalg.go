@@ -0,0 +1,54 @@
+package hashalg
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+const MD5 = "md5"
+
+type Algorithm struct {
+	Name      string
+	HexLength int
+	sum       func([]byte) []byte
+}
+
+// Пока воркеры умеют считать только MD5, поэтому реестр содержит один алгоритм.
+var registry = map[string]Algorithm{
+	MD5: {
+		Name:      MD5,
+		HexLength: md5.Size * 2,
+		sum: func(b []byte) []byte {
+			s := md5.Sum(b)
+			return s[:]
+		},
+	},
+}
+
+func Lookup(name string) (Algorithm, bool) {
+	a, ok := registry[strings.ToLower(name)]
+	return a, ok
+}
+
+func Supported() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (a Algorithm) ValidHash(hash string) bool {
+	if len(hash) != a.HexLength {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+func (a Algorithm) Sum(word string) string {
+	return hex.EncodeToString(a.sum([]byte(word)))
+}
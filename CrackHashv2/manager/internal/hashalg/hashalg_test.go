@@ -0,0 +1,40 @@
+package hashalg_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/manager/internal/hashalg"
+)
+
+func TestAlgorithm_ValidHash(t *testing.T) {
+	md5, ok := hashalg.Lookup("MD5")
+	require.True(t, ok)
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{name: "lowercase", hash: "0cc175b9c0f1b6a831c399e269772661", want: true},
+		{name: "uppercase", hash: "0CC175B9C0F1B6A831C399E269772661", want: true},
+		{name: "too short", hash: "0cc175b9c0f1b6a831c399e26977266", want: false},
+		{name: "non-hex", hash: "0cc175b9c0f1b6a831c399e26977266z", want: false},
+		{name: "empty", hash: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, md5.ValidHash(tt.hash))
+		})
+	}
+}
+
+func TestAlgorithm_Sum(t *testing.T) {
+	md5, ok := hashalg.Lookup(hashalg.MD5)
+	require.True(t, ok)
+	require.Equal(t, "900150983cd24fb0d6963f7d28e17f72", md5.Sum("abc"))
+
+	_, ok = hashalg.Lookup("sha1")
+	require.False(t, ok)
+}
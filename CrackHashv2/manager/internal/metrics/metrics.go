@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "crackhash_manager"
+
+var (
+	QueuePublishFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queue_publish_failures_total",
+		Help:      "Неудачные публикации задач в RabbitMQ.",
+	})
+	QueuePublishRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queue_publish_rejections_total",
+		Help:      "Задачи, не принятые брокером: nack или возврат по mandatory.",
+	}, []string{"reason"})
+	PendingRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "pending_retries_total",
+		Help:      "Попытки переотправки pending-задач.",
+	})
+	PendingTasks = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pending_tasks",
+		Help:      "Задачи, ожидающие переотправки, на момент последнего прохода.",
+	})
+	ResponseLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "worker_response_latency_seconds",
+		Help:      "Время от создания задачи до ответа воркера.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 14),
+	})
+	StoreOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_operation_duration_seconds",
+		Help:      "Длительность операций хранилища запросов.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+	RabbitConnected = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rabbitmq_connected",
+		Help:      "1, если клиент RabbitMQ подключен.",
+	})
+)
+
+// ObserveStore записывает длительность операции хранилища; вызывается как
+// defer metrics.ObserveStore("get", time.Now()).
+func ObserveStore(operation string, start time.Time) {
+	StoreOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func SetConnected(g prometheus.Gauge, connected bool) {
+	if connected {
+		g.Set(1)
+	} else {
+		g.Set(0)
+	}
+}
+
+// requestsCollector считает запросы по статусам при каждом сборе метрик, поэтому
+// значения совпадают с хранилищем и после перезапуска менеджера.
+type requestsCollector struct {
+	desc  *prometheus.Desc
+	count func() map[string]int
+}
+
+func NewRequestsCollector(count func() map[string]int) prometheus.Collector {
+	return requestsCollector{
+		desc: prometheus.NewDesc(namespace+"_requests", "Запросы по статусам.",
+			[]string{"status"}, nil),
+		count: count,
+	}
+}
+
+func (c requestsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c requestsCollector) Collect(ch chan<- prometheus.Metric) {
+	for status, n := range c.count() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), status)
+	}
+}
+
+// RegisterRequests подключает счётчик запросов по статусам к /metrics.
+func RegisterRequests(count func() map[string]int) error {
+	return prometheus.Register(NewRequestsCollector(count))
+}
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,28 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/manager/internal/metrics"
+)
+
+func TestRequestsCollector(t *testing.T) {
+	counts := map[string]int{"IN_PROGRESS": 2, "READY": 5}
+	collector := metrics.NewRequestsCollector(func() map[string]int { return counts })
+
+	expected := `
+# HELP crackhash_manager_requests Запросы по статусам.
+# TYPE crackhash_manager_requests gauge
+crackhash_manager_requests{status="IN_PROGRESS"} 2
+crackhash_manager_requests{status="READY"} 5
+`
+	require.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+
+	// Значения читаются из хранилища при каждом сборе.
+	counts = map[string]int{"READY": 7}
+	require.Equal(t, float64(7), testutil.ToFloat64(collector))
+}
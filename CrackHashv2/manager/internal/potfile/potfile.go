@@ -0,0 +1,88 @@
+package potfile
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+type Entry struct {
+	Hash      string
+	Plaintext string
+}
+
+const hexPrefix = "$HEX["
+
+// Parse читает potfile в формате hashcat: по одной записи "hash:plaintext" на строку.
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		idx := strings.Index(line, ":")
+		if idx <= 0 {
+			return nil, fmt.Errorf("строка %d: ожидается формат hash:plaintext", lineNum)
+		}
+		plain, err := decodePlaintext(line[idx+1:])
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %w", lineNum, err)
+		}
+		entries = append(entries, Entry{
+			Hash:      strings.ToLower(line[:idx]),
+			Plaintext: plain,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func Write(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		if _, err := fmt.Fprintf(bw, "%s:%s\n", e.Hash, encodePlaintext(e.Plaintext)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func decodePlaintext(s string) (string, error) {
+	if !strings.HasPrefix(s, hexPrefix) || !strings.HasSuffix(s, "]") {
+		return s, nil
+	}
+	b, err := hex.DecodeString(s[len(hexPrefix) : len(s)-1])
+	if err != nil {
+		return "", fmt.Errorf("некорректный $HEX[]: %w", err)
+	}
+	return string(b), nil
+}
+
+func encodePlaintext(s string) string {
+	if needsHex(s) {
+		return hexPrefix + hex.EncodeToString([]byte(s)) + "]"
+	}
+	return s
+}
+
+func needsHex(s string) bool {
+	if strings.HasPrefix(s, hexPrefix) {
+		return true
+	}
+	for _, r := range s {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
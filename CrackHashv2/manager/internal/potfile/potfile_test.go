@@ -0,0 +1,69 @@
+package potfile_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/manager/internal/potfile"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []potfile.Entry
+		wantErr bool
+	}{
+		{
+			name:  "plain entries",
+			input: "0CC175B9C0F1B6A831C399E269772661:a\n\n900150983cd24fb0d6963f7d28e17f72:abc\r\n",
+			want: []potfile.Entry{
+				{Hash: "0cc175b9c0f1b6a831c399e269772661", Plaintext: "a"},
+				{Hash: "900150983cd24fb0d6963f7d28e17f72", Plaintext: "abc"},
+			},
+		},
+		{
+			name:  "plaintext with colon and hex",
+			input: "aa:b:c\nbb:$HEX[c3a4]\n",
+			want: []potfile.Entry{
+				{Hash: "aa", Plaintext: "b:c"},
+				{Hash: "bb", Plaintext: "ä"},
+			},
+		},
+		{
+			name:    "missing separator",
+			input:   "0cc175b9c0f1b6a831c399e269772661\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := potfile.Parse(strings.NewReader(tt.input))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWrite_RoundTrip(t *testing.T) {
+	entries := []potfile.Entry{
+		{Hash: "0cc175b9c0f1b6a831c399e269772661", Plaintext: "a"},
+		{Hash: "bb", Plaintext: "ä"},
+		{Hash: "cc", Plaintext: "$HEX[00]"},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, potfile.Write(&buf, entries))
+	require.Equal(t, "0cc175b9c0f1b6a831c399e269772661:a\nbb:$HEX[c3a4]\ncc:$HEX[244845585b30305d]\n", buf.String())
+
+	got, err := potfile.Parse(&buf)
+	require.NoError(t, err)
+	require.Equal(t, entries, got)
+}
@@ -1,48 +1,78 @@
 package queue
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
-	"log"
+	"strings"
 	"sync"
 	"time"
 
+	"CrackHash/internal/amqpmsg"
+	"CrackHash/internal/logging"
+	"CrackHash/internal/signing"
+	"CrackHash/internal/tlsconfig"
+	"CrackHash/internal/tracing"
 	"CrackHash/manager/internal/config"
+	"CrackHash/manager/internal/metrics"
 	"CrackHash/manager/internal/types"
 
+	"github.com/google/uuid"
 	"github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type TaskQueue interface {
 	IsConnected() bool
-	PublishTask(task types.CrackHashManagerRequest) error
+	// PublishTask передаёт контекст трассы ctx в заголовках сообщения.
+	PublishTask(ctx context.Context, task types.CrackHashManagerRequest) error
 
 	StartConsumeResponses() (<-chan types.CrackHashWorkerResponse, error)
 
 	AckMessage(resp types.CrackHashWorkerResponse)
+
+	// StopConsuming отменяет подписку на ответы: уже выданные брокером ответы дочитываются,
+	// после чего канал StartConsumeResponses закрывается.
+	StopConsuming() error
+	// Close закрывает канал и соединение без переподключения.
+	Close() error
 }
 
+const (
+	responsesConsumerTag = "crackhash-manager"
+	component            = "rabbitClient"
+)
+
 type rabbitClient struct {
 	mu        sync.Mutex
 	conn      *amqp091.Connection
 	channel   *amqp091.Channel
 	cfg       *config.Config
+	keys      *signing.Keyring
 	connected bool
 
 	exchangeTasks string
 	exchangeResps string
 	taskQueue     amqp091.Queue
 	responseQueue amqp091.Queue
+	// returns раздаёт публикациям задачи, которые брокер вернул по mandatory, потому что
+	// их не в какую очередь положить.
+	returns *returnRouter
 
 	responseChan <-chan amqp091.Delivery
 	responsesOut chan types.CrackHashWorkerResponse
 	listening    bool
+
+	stopping  bool
+	closeOnce sync.Once
 }
 
-func NewRabbitClient(cfg *config.Config) (TaskQueue, error) {
+// NewRabbitClient подписывает задачи ключами keys и принимает только ответы с верной подписью.
+func NewRabbitClient(cfg *config.Config, keys *signing.Keyring) (TaskQueue, error) {
 	client := &rabbitClient{
 		cfg:           cfg,
+		keys:          keys,
 		exchangeTasks: cfg.TaskExchange,
 		exchangeResps: cfg.ResponseExchange,
 		connected:     false,
@@ -56,8 +86,25 @@ func NewRabbitClient(cfg *config.Config) (TaskQueue, error) {
 	return client, nil
 }
 
+// dial подключается по amqps://, если в URI указана эта схема; сертификаты берутся из
+// RABBIT_*_FILE. Без amqps:// файлы не используются, поэтому такая конфигурация считается ошибкой.
+func dial(cfg *config.Config) (*amqp091.Connection, error) {
+	files := tlsconfig.Files{CAFile: cfg.RabbitCAFile, CertFile: cfg.RabbitCertFile, KeyFile: cfg.RabbitKeyFile}
+	if !strings.HasPrefix(cfg.RabbitURI, "amqps://") {
+		if files.Enabled() {
+			return nil, errors.New("заданы сертификаты RabbitMQ, но RABBIT_URI не amqps://")
+		}
+		return amqp091.Dial(cfg.RabbitURI)
+	}
+	tlsCfg, err := tlsconfig.Client(files)
+	if err != nil {
+		return nil, err
+	}
+	return amqp091.DialTLS(cfg.RabbitURI, tlsCfg)
+}
+
 func (r *rabbitClient) connectAndDeclare() error {
-	conn, err := amqp091.Dial(r.cfg.RabbitURI)
+	conn, err := dial(r.cfg)
 	if err != nil {
 		return fmt.Errorf("не удалось подключиться к RabbitMQ: %w", err)
 	}
@@ -66,6 +113,22 @@ func (r *rabbitClient) connectAndDeclare() error {
 		conn.Close()
 		return fmt.Errorf("не удалось открыть канал: %w", err)
 	}
+	// Ответы обрабатываются по одному, поэтому держать в памяти все накопившиеся в очереди
+	// незачем; остальные дождутся в брокере и достанутся другому экземпляру менеджера.
+	if err := ch.Qos(max(r.cfg.ResponsePrefetch, 1), 0, false); err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("не удалось настроить prefetch: %w", err)
+	}
+	// В режиме подтверждений брокер отвечает ack или nack на каждую публикацию задачи.
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("не удалось включить подтверждения публикации: %w", err)
+	}
+	// Библиотека отдаёт возвраты синхронно из читающей горутины соединения; returnRouter
+	// забирает их сразу, в том числе возвраты публикаций, которые уже не ждут подтверждения.
+	returns := newReturnRouter(ch.NotifyReturn(make(chan amqp091.Return)))
 
 	err = ch.ExchangeDeclare(
 		r.cfg.TaskExchange,
@@ -149,16 +212,24 @@ func (r *rabbitClient) connectAndDeclare() error {
 		return fmt.Errorf("не удалось bind очередь ответов: %w", err)
 	}
 
+	if _, err := ch.QueueDeclare(amqpmsg.DeadLetterQueue(respQ.Name), true, false, false, false, nil); err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("не удалось объявить очередь %s: %w", amqpmsg.DeadLetterQueue(respQ.Name), err)
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	r.conn = conn
 	r.channel = ch
+	r.returns = returns
 	r.taskQueue = taskQ
 	r.responseQueue = respQ
 	r.connected = true
+	metrics.SetConnected(metrics.RabbitConnected, true)
 
-	log.Println("[rabbitClient] Успешно подключился к RabbitMQ и объявил exchange/queues")
+	logging.Component(component).Info("Подключились к RabbitMQ и объявили exchange/queues")
 	return nil
 }
 
@@ -174,38 +245,49 @@ func (r *rabbitClient) startCloseWatcher() {
 	go func() {
 		err, ok := <-closeCh
 		if !ok || err == nil {
-			log.Println("[rabbitClient] NotifyClose: канал закрыт без ошибки")
+			logging.Component(component).Info("Соединение с RabbitMQ закрыто без ошибки")
 			return
 		}
-		log.Printf("[rabbitClient] Соединение с RabbitMQ закрыто: %v", err)
+		logging.Component(component).Error("Соединение с RabbitMQ закрыто", "error", err)
 		r.reconnectLoop()
 	}()
 }
 
 func (r *rabbitClient) reconnectLoop() {
 	r.mu.Lock()
+	if r.stopping {
+		r.mu.Unlock()
+		return
+	}
 	wasListening := r.listening
 	r.connected = false
+	metrics.SetConnected(metrics.RabbitConnected, false)
 	r.mu.Unlock()
 
 	for {
-		log.Println("[rabbitClient] Пытаемся переподключиться к RabbitMQ...")
+		logging.Component(component).Info("Пытаемся переподключиться к RabbitMQ")
 		err := r.connectAndDeclare()
 		if err == nil {
 			r.startCloseWatcher()
-			log.Println("[rabbitClient] Успешно переподключились к RabbitMQ!")
+			logging.Component(component).Info("Переподключились к RabbitMQ")
 
 			if wasListening {
-				log.Println("[rabbitClient] Повторный вызов consumeResponses() после reconnect")
+				logging.Component(component).Info("Повторно подписываемся на ответы после переподключения")
 				_, err2 := r.consumeResponses()
 				if err2 != nil {
-					log.Printf("[rabbitClient] Ошибка при повторном consumeResponses: %v", err2)
+					logging.Component(component).Error("Ошибка повторной подписки на ответы", "error", err2)
 				}
 			}
 			return
 		}
-		log.Printf("[rabbitClient] Ошибка при reconnect: %v. Ждем 5с и повторяем...", err)
+		logging.Component(component).Warn("Ошибка переподключения, повторим через 5с", "error", err)
 		time.Sleep(5 * time.Second)
+		r.mu.Lock()
+		stopping := r.stopping
+		r.mu.Unlock()
+		if stopping {
+			return
+		}
 	}
 }
 
@@ -215,34 +297,73 @@ func (r *rabbitClient) IsConnected() bool {
 	return r.connected
 }
 
-func (r *rabbitClient) PublishTask(task types.CrackHashManagerRequest) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	if !r.connected {
-		return errors.New("RabbitMQ не подключен")
-	}
+// PublishTask возвращает nil, только когда брокер подтвердил, что задача легла в очередь.
+// Nack, возврат по mandatory, закрытие канала и таймаут подтверждения — ошибки: задача
+// остаётся pending и будет переотправлена. После таймаута брокер мог всё же принять задачу,
+// тогда часть посчитают дважды, но повторный ответ результат не меняет.
+func (r *rabbitClient) PublishTask(ctx context.Context, task types.CrackHashManagerRequest) (err error) {
+	ctx, span := amqpmsg.StartSpan(ctx, "publish", r.exchangeTasks, task.RequestId, task.PartNumber)
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
 
 	xmlData, err := xml.MarshalIndent(task, "", "  ")
 	if err != nil {
 		return err
 	}
 	xmlData = append([]byte(xml.Header), xmlData...)
+	msg := amqpmsg.WithCorrelation(ctx, amqpmsg.SignedPublishing(r.keys, xmlData), task.RequestId, task.PartNumber)
+	// MessageId уникален для каждой публикации: возврат переотправки той же части не
+	// спутать с возвратом предыдущей попытки.
+	msg.MessageId = fmt.Sprintf("%s/%d/%s", task.RequestId, task.PartNumber, uuid.NewString())
 
-	err = r.channel.Publish(
-		r.exchangeTasks,
-		r.taskQueue.Name,
-		false,
-		false,
-		amqp091.Publishing{
-			ContentType:  "application/xml",
-			Body:         xmlData,
-			DeliveryMode: amqp091.Persistent,
-		},
-	)
+	r.mu.Lock()
+	if !r.connected {
+		r.mu.Unlock()
+		return errors.New("RabbitMQ не подключен")
+	}
+	ch, returns, queue := r.channel, r.returns, r.taskQueue.Name
+	r.mu.Unlock()
+
+	returned := returns.register(msg.MessageId)
+	defer returns.forget(msg.MessageId)
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(ctx, r.exchangeTasks, queue, true, false, msg)
 	if err != nil {
 		return fmt.Errorf("ошибка при publish в task_queue: %w", err)
 	}
+	return waitConfirm(ctx, confirm, returns, returned, r.cfg.ConfirmTimeout)
+}
+
+// confirmation — подтверждение публикации; *amqp091.DeferredConfirmation.
+type confirmation interface {
+	Done() <-chan struct{}
+	Acked() bool
+}
+
+// waitConfirm ждёт ack публикации, возврат которой придёт в returned. Брокер присылает
+// возврат раньше ack той же публикации, поэтому после returns.sync он уже в returned.
+func waitConfirm(ctx context.Context, confirm confirmation, returns *returnRouter, returned <-chan amqp091.Return, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	select {
+	case <-confirm.Done():
+	case <-ctx.Done():
+		return fmt.Errorf("брокер не подтвердил публикацию задачи за %s: %w", timeout, ctx.Err())
+	}
+	returns.sync()
+
+	select {
+	case ret := <-returned:
+		metrics.QueuePublishRejections.WithLabelValues("returned").Inc()
+		return fmt.Errorf("брокер вернул задачу: %d %s", ret.ReplyCode, ret.ReplyText)
+	default:
+	}
+	if !confirm.Acked() {
+		metrics.QueuePublishRejections.WithLabelValues("nack").Inc()
+		return errors.New("брокер не принял задачу (nack или канал закрыт)")
+	}
 	return nil
 }
 
@@ -268,7 +389,7 @@ func (r *rabbitClient) StartConsumeResponses() (<-chan types.CrackHashWorkerResp
 func (r *rabbitClient) consumeResponses() (<-chan types.CrackHashWorkerResponse, error) {
 	deliveries, err := r.channel.Consume(
 		r.responseQueue.Name,
-		"",
+		responsesConsumerTag,
 		false,
 		false,
 		false,
@@ -282,20 +403,49 @@ func (r *rabbitClient) consumeResponses() (<-chan types.CrackHashWorkerResponse,
 
 	go func() {
 		for msg := range deliveries {
+			if err := amqpmsg.VerifyDelivery(r.keys, msg); err != nil {
+				amqpmsg.DeliveryLogger(component, msg).Warn("Ответ отклонён", "error", err)
+				r.reject(msg, err)
+				continue
+			}
 			var workerResp types.CrackHashWorkerResponse
 			if unErr := xml.Unmarshal(msg.Body, &workerResp); unErr != nil {
-				log.Printf("Ошибка разбора XML из очереди worker_responses: %v", unErr)
-				msg.Nack(false, false)
+				amqpmsg.DeliveryLogger(component, msg).Warn("Ошибка разбора XML ответа", "error", unErr)
+				r.reject(msg, unErr)
 				continue
 			}
 			workerResp.DeliveryTag = msg.DeliveryTag
+			workerResp.SigningKeyID, _ = msg.Headers[signing.AMQPKeyID].(string)
+			workerResp.SpanContext = trace.SpanContextFromContext(amqpmsg.DeliveryContext(context.Background(), msg))
+			logging.Part(component, workerResp.RequestId, workerResp.PartNumber).Debug("Получен ответ воркера",
+				"workerId", workerResp.WorkerId, "words", len(workerResp.Answers.Words))
 			r.responsesOut <- workerResp
 		}
+		// Канал доставок закрывается и при обрыве соединения; наружу закрываем только при остановке.
+		r.mu.Lock()
+		stopping := r.stopping
+		r.mu.Unlock()
+		if stopping {
+			r.closeResponses()
+		}
 	}()
 
 	return r.responsesOut, nil
 }
 
+// reject перекладывает ответ в dead-letter очередь; если это не удалось, сообщение
+// отклоняется без повторной доставки, чтобы не зациклиться.
+func (r *rabbitClient) reject(msg amqp091.Delivery, reason error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := amqpmsg.DeadLetter(r.channel, r.responseQueue.Name, msg, reason); err != nil {
+		amqpmsg.DeliveryLogger(component, msg).Error("Ответ не переложен в dead-letter очередь", "error", err)
+		msg.Nack(false, false)
+		return
+	}
+	msg.Ack(false)
+}
+
 func (r *rabbitClient) AckMessage(resp types.CrackHashWorkerResponse) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -305,6 +455,41 @@ func (r *rabbitClient) AckMessage(resp types.CrackHashWorkerResponse) {
 	}
 	err := r.channel.Ack(resp.DeliveryTag, false)
 	if err != nil {
-		log.Printf("Ошибка при подтверждении сообщения RabbitMQ: %v", err)
+		logging.Part(component, resp.RequestId, resp.PartNumber).Error("Ошибка подтверждения ответа", "error", err)
+	}
+}
+
+func (r *rabbitClient) closeResponses() {
+	r.closeOnce.Do(func() { close(r.responsesOut) })
+}
+
+func (r *rabbitClient) StopConsuming() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopping = true
+	if !r.listening {
+		return nil
+	}
+	// Без соединения цикл доставок уже завершился, и закрыть канал ответов больше некому.
+	if !r.connected {
+		r.closeResponses()
+		return nil
+	}
+	if err := r.channel.Cancel(responsesConsumerTag, false); err != nil {
+		r.closeResponses()
+		return fmt.Errorf("не удалось отменить подписку на %s: %w", r.responseQueue.Name, err)
+	}
+	return nil
+}
+
+func (r *rabbitClient) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopping = true
+	r.connected = false
+	metrics.SetConnected(metrics.RabbitConnected, false)
+	if r.conn == nil {
+		return nil
 	}
+	return r.conn.Close()
 }
@@ -0,0 +1,82 @@
+package queue
+
+import (
+	"sync"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// returnRouter читает возвраты канала в своей горутине и передаёт каждый публикации с
+// тем же MessageId. Возвраты публикаций, которые уже никто не ждёт (например, после
+// таймаута подтверждения), отбрасываются, поэтому читающая горутина соединения никогда
+// не встаёт на отправке возврата.
+type returnRouter struct {
+	mu      sync.Mutex
+	waiting map[string]chan amqp091.Return
+
+	flush chan chan struct{}
+	// done закрывается, когда канал возвратов закрыт вместе с каналом AMQP.
+	done chan struct{}
+}
+
+// newReturnRouter ждёт небуферизованный канал из NotifyReturn: тогда отправка возврата
+// библиотекой завершается только после того, как его приняла горутина маршрутизатора.
+func newReturnRouter(returns <-chan amqp091.Return) *returnRouter {
+	r := &returnRouter{
+		waiting: make(map[string]chan amqp091.Return),
+		flush:   make(chan chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go r.run(returns)
+	return r
+}
+
+func (r *returnRouter) run(returns <-chan amqp091.Return) {
+	defer close(r.done)
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				return
+			}
+			r.mu.Lock()
+			if ch, ok := r.waiting[ret.MessageId]; ok {
+				select {
+				case ch <- ret:
+				default:
+				}
+			}
+			r.mu.Unlock()
+		case ack := <-r.flush:
+			close(ack)
+		}
+	}
+}
+
+// register начинает ждать возврат публикации messageID; forget вызывается, когда
+// ответ брокера получен или ждать больше незачем.
+func (r *returnRouter) register(messageID string) <-chan amqp091.Return {
+	ch := make(chan amqp091.Return, 1)
+	r.mu.Lock()
+	r.waiting[messageID] = ch
+	r.mu.Unlock()
+	return ch
+}
+
+func (r *returnRouter) forget(messageID string) {
+	r.mu.Lock()
+	delete(r.waiting, messageID)
+	r.mu.Unlock()
+}
+
+// sync возвращается, когда все возвраты, принятые до вызова, уже разосланы. Брокер
+// присылает возврат раньше ack той же публикации, поэтому после ack достаточно sync,
+// чтобы увидеть её возврат.
+func (r *returnRouter) sync() {
+	ack := make(chan struct{})
+	select {
+	case r.flush <- ack:
+		<-ack
+	case <-r.done:
+	}
+}
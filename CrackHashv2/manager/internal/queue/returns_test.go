@@ -0,0 +1,66 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeConfirmation struct {
+	done  chan struct{}
+	acked bool
+}
+
+func (c fakeConfirmation) Done() <-chan struct{} { return c.done }
+func (c fakeConfirmation) Acked() bool           { return c.acked }
+
+func confirmed(acked bool) fakeConfirmation {
+	c := fakeConfirmation{done: make(chan struct{}), acked: acked}
+	close(c.done)
+	return c
+}
+
+func TestWaitConfirm_ReturnAfterTimeout(t *testing.T) {
+	src := make(chan amqp091.Return)
+	router := newReturnRouter(src)
+
+	// Первая попытка не дождалась подтверждения.
+	first := router.register("req/0/a")
+	err := waitConfirm(context.Background(), fakeConfirmation{done: make(chan struct{})}, router, first, 10*time.Millisecond)
+	require.ErrorContains(t, err, "не подтвердил")
+	router.forget("req/0/a")
+
+	// Её возврат и пачка чужих приходят позже и не блокируют отправителя.
+	sent := make(chan struct{})
+	go func() {
+		for i := 0; i < 64; i++ {
+			src <- amqp091.Return{MessageId: "req/0/a", ReplyCode: 312, ReplyText: "NO_ROUTE"}
+		}
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("возвраты без ожидающей публикации заблокировали канал")
+	}
+
+	// Переотправка той же части не принимает старый возврат за свой.
+	retry := router.register("req/0/b")
+	require.NoError(t, waitConfirm(context.Background(), confirmed(true), router, retry, time.Second))
+	router.forget("req/0/b")
+
+	// Собственный возврат, пришедший до ack, — ошибка.
+	returned := router.register("req/0/c")
+	src <- amqp091.Return{MessageId: "req/0/c", ReplyCode: 312, ReplyText: "NO_ROUTE"}
+	err = waitConfirm(context.Background(), confirmed(true), router, returned, time.Second)
+	require.ErrorContains(t, err, "брокер вернул задачу: 312 NO_ROUTE")
+
+	require.ErrorContains(t, waitConfirm(context.Background(), confirmed(false), router, router.register("req/0/d"), time.Second), "nack")
+
+	close(src)
+	<-router.done
+	router.sync()
+}
@@ -2,83 +2,498 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
-	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"CrackHash/internal/logging"
+	"CrackHash/internal/tracing"
+	"CrackHash/manager/internal/audit"
+	"CrackHash/manager/internal/auth"
+	"CrackHash/manager/internal/events"
+	"CrackHash/manager/internal/hashalg"
+	"CrackHash/manager/internal/metrics"
 	"CrackHash/manager/internal/queue"
 	"CrackHash/manager/internal/store"
 	"CrackHash/manager/internal/types"
+	"CrackHash/manager/internal/webhook"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	StatusInProgress = "IN_PROGRESS"
 	StatusReady      = "READY"
 	StatusError      = "ERROR"
-	MaxQueueSize     = 100
+	StatusCancelled  = "CANCELLED"
+	StatusPartial    = "PARTIAL"
+	StatusRejected   = "REJECTED"
+	MaxBatchSize     = 10000
+
+	// DefaultMaxQueueSize — сколько задач может выполняться одновременно, пока лимит
+	// не изменён настройками.
+	DefaultMaxQueueSize = 100
+
+	// DefaultMaxPartRequeues — сколько раз часть возвращается в очередь после отброшенного
+	// ответа, прежде чем задача переводится в ERROR.
+	DefaultMaxPartRequeues = 5
+
+	AlgorithmMD5 = hashalg.MD5
+
+	component = "managerService"
+)
+
+var (
+	ErrRequestNotFound = errors.New("запрос не найден")
+	ErrNotCancellable  = errors.New("запрос уже завершён")
+	ErrQueueFull       = errors.New("очередь заполнена, попробуйте позже")
+	ErrBatchTooLarge   = errors.New("слишком много хэшей в batch")
+	ErrBatchEmpty      = errors.New("batch не содержит хэшей")
+	ErrBatchNotFound   = errors.New("batch не найден")
+	ErrConcurrentQuota = errors.New("превышен лимит одновременных запросов для API-ключа")
+	ErrKeyspaceQuota   = errors.New("превышен дневной лимит keyspace для API-ключа")
 )
 
 type ManagerService interface {
-	CreateTask(ctx context.Context, hash string, maxLength int) (string, error)
+	CreateTask(ctx context.Context, req types.CrackRequest) (string, error)
+	CancelTask(ctx context.Context, requestID string) error
 	RetryPendingTasks(ctx context.Context) error
+	HandleWorkerResponse(ctx context.Context, resp types.CrackHashWorkerResponse)
 }
 
 type ManagerServiceImpl struct {
-	store           store.RequestStore
-	rabbitClient    queue.TaskQueue
-	responseTimeout time.Duration
+	store        store.RequestStore
+	rabbitClient queue.TaskQueue
+	potfile      store.PotfileStore
+	notifier     CompletionNotifier
+	events       EventPublisher
+	batches      store.BatchStore
+	quota        KeyspaceQuota
+	audit        *audit.Logger
+	workers      *WorkerTracker
+	callbacks    *webhook.Guard
+	// settings общие для всех копий сервиса и меняются без перезапуска.
+	settings *atomic.Pointer[Settings]
+	// jobMu сериализует поиск и присоединение к одинаковым задачам.
+	jobMu *sync.Mutex
+	// publishes отслеживает фоновые публикации задач, чтобы дождаться их при остановке.
+	publishes *sync.WaitGroup
+	// requeues — сколько раз часть возвращалась в очередь из-за отброшенных ответов;
+	// защищён jobMu.
+	requeues    map[partKey]int
+	maxRequeues int
+}
+
+type partKey struct {
+	requestID  string
+	partNumber int
+}
+
+type CompletionNotifier interface {
+	NotifyCompletion(requestID string, state store.RequestState)
+}
+
+type EventPublisher interface {
+	Publish(e events.Event)
+}
+
+// KeyspaceQuota списывает размер пространства перебора из дневного лимита API-ключа.
+type KeyspaceQuota interface {
+	ConsumeKeyspace(keyID, day string, amount, limit int64) bool
+}
+
+type Option func(*ManagerServiceImpl)
+
+func WithKeyspaceQuota(q KeyspaceQuota) Option {
+	return func(m *ManagerServiceImpl) {
+		m.quota = q
+	}
+}
+
+func WithAuditLog(l *audit.Logger) Option {
+	return func(m *ManagerServiceImpl) {
+		m.audit = l
+	}
+}
+
+func WithWorkerTracker(t *WorkerTracker) Option {
+	return func(m *ManagerServiceImpl) {
+		m.workers = t
+	}
+}
+
+func WithMaxLengthLimit(limit int) Option {
+	return func(m *ManagerServiceImpl) {
+		s := m.Settings()
+		s.MaxLengthLimit = limit
+		m.settings.Store(&s)
+	}
+}
+
+func WithBatchStore(b store.BatchStore) Option {
+	return func(m *ManagerServiceImpl) {
+		m.batches = b
+	}
+}
+
+func WithEventPublisher(p EventPublisher) Option {
+	return func(m *ManagerServiceImpl) {
+		m.events = p
+	}
+}
+
+func WithCompletionNotifier(n CompletionNotifier) Option {
+	return func(m *ManagerServiceImpl) {
+		m.notifier = n
+	}
+}
+
+// WithCallbackGuard задаёт сети, куда можно указывать callbackUrl; без него внутренние
+// адреса запрещены.
+func WithCallbackGuard(g *webhook.Guard) Option {
+	return func(m *ManagerServiceImpl) {
+		m.callbacks = g
+	}
+}
+
+// WithMaxPartRequeues задаёт, сколько раз часть можно вернуть в очередь после ответа
+// воркера в карантине или неверного ответа.
+func WithMaxPartRequeues(n int) Option {
+	return func(m *ManagerServiceImpl) {
+		m.maxRequeues = n
+	}
+}
+
+func WithPotfile(p store.PotfileStore) Option {
+	return func(m *ManagerServiceImpl) {
+		m.potfile = p
+	}
 }
 
 func NewManagerService(
 	s store.RequestStore,
 	qc queue.TaskQueue,
 	timeout time.Duration,
+	opts ...Option,
 ) ManagerServiceImpl {
-	return ManagerServiceImpl{
-		store:           s,
-		rabbitClient:    qc,
-		responseTimeout: timeout,
+	m := ManagerServiceImpl{
+		store:        s,
+		rabbitClient: qc,
+		settings:     defaultSettings(timeout),
+		jobMu:        &sync.Mutex{},
+		publishes:    &sync.WaitGroup{},
+		requeues:     map[partKey]int{},
+		maxRequeues:  DefaultMaxPartRequeues,
 	}
+	for _, opt := range opts {
+		opt(&m)
+	}
+	return m
+}
+
+func defaultAlphabet() []string {
+	var alphabet []string
+	for _, ch := range "abcdefghijklmnopqrstuvwxyz0123456789" {
+		alphabet = append(alphabet, string(ch))
+	}
+	return alphabet
+}
+
+func TaskFingerprint(algorithm, hash string, alphabet []string, maxLength int) string {
+	canonical := strings.Join([]string{
+		strings.ToLower(algorithm),
+		strings.ToLower(hash),
+		strconv.Itoa(maxLength),
+		strings.Join(alphabet, "\x00"),
+	}, "\x1f")
+	sum := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(sum[:])
+}
+
+// Keyspace — число строк длиной от 1 до maxLength над алфавитом; при переполнении возвращает math.MaxInt64.
+func Keyspace(alphabetSize, maxLength int) int64 {
+	var total, power int64 = 0, 1
+	for l := 1; l <= maxLength; l++ {
+		if power > math.MaxInt64/int64(alphabetSize) {
+			return math.MaxInt64
+		}
+		power *= int64(alphabetSize)
+		if total > math.MaxInt64-power {
+			return math.MaxInt64
+		}
+		total += power
+	}
+	return total
 }
 
 func (m ManagerServiceImpl) CreateTask(
 	ctx context.Context,
-	hash string,
-	maxLength int,
+	req types.CrackRequest,
+) (string, error) {
+	requestID, err := m.createTask(ctx, req, true)
+	if err == nil {
+		m.audit.Record(ctx, store.AuditSubmit, requestID, submitDetails(req))
+	}
+	return requestID, err
+}
+
+func submitDetails(req types.CrackRequest) map[string]string {
+	algorithm := strings.ToLower(req.Algorithm)
+	if algorithm == "" {
+		algorithm = AlgorithmMD5
+	}
+	return map[string]string{
+		"hash":      strings.ToLower(req.Hash),
+		"algorithm": algorithm,
+		"maxLength": strconv.Itoa(req.MaxLength),
+	}
+}
+
+// CreateBatch проверяет лимит очереди один раз на весь batch: дочерние задачи ставятся в
+// очередь целиком, поэтому batch принимается, только если в очереди есть место для всех
+// его элементов.
+func (m ManagerServiceImpl) CreateBatch(ctx context.Context, items []types.BatchItem) (string, error) {
+	if m.batches == nil {
+		return "", errors.New("batch-хранилище не настроено")
+	}
+	if len(items) == 0 {
+		return "", ErrBatchEmpty
+	}
+	if len(items) > MaxBatchSize {
+		return "", ErrBatchTooLarge
+	}
+	if m.store.CountActive()+len(items) > m.Settings().MaxQueueSize {
+		return "", ErrQueueFull
+	}
+
+	principal, _ := auth.PrincipalFrom(ctx)
+	batch := store.Batch{
+		ID:        uuid.New().String(),
+		Tenant:    principal.Tenant,
+		CreatedAt: time.Now(),
+		Items:     make([]store.BatchItem, 0, len(items)),
+	}
+	for _, item := range items {
+		stored := store.BatchItem{
+			User:      item.User,
+			Hash:      item.Hash,
+			MaxLength: item.MaxLength,
+		}
+		req := types.CrackRequest{
+			Hash:      item.Hash,
+			MaxLength: item.MaxLength,
+			Algorithm: item.Algorithm,
+		}
+		requestID, err := m.createTask(ctx, req, false)
+		if err != nil {
+			stored.Error = err.Error()
+		} else {
+			stored.RequestID = requestID
+			details := submitDetails(req)
+			details["batchId"] = batch.ID
+			m.audit.Record(ctx, store.AuditSubmit, requestID, details)
+		}
+		batch.Items = append(batch.Items, stored)
+	}
+	m.batches.Create(batch)
+	logging.Component(component).Info("Создан batch", "batchId", batch.ID, "items", len(batch.Items))
+	return batch.ID, nil
+}
+
+func (m ManagerServiceImpl) BatchStatus(ctx context.Context, batchID string) (types.BatchStatusResponse, error) {
+	if m.batches == nil {
+		return types.BatchStatusResponse{}, ErrBatchNotFound
+	}
+	batch, ok := m.batches.Get(batchID)
+	if !ok || !auth.CanAccess(ctx, batch.Tenant) {
+		return types.BatchStatusResponse{}, ErrBatchNotFound
+	}
+
+	resp := types.BatchStatusResponse{
+		BatchID: batch.ID,
+		Total:   len(batch.Items),
+		Items:   make([]types.BatchItemStatus, 0, len(batch.Items)),
+	}
+	for _, item := range batch.Items {
+		status := types.BatchItemStatus{
+			User:      item.User,
+			Hash:      item.Hash,
+			RequestID: item.RequestID,
+			Error:     item.Error,
+		}
+		if item.Error != "" {
+			status.Status = StatusRejected
+			resp.Rejected++
+		} else if state, ok := m.store.Get(item.RequestID); ok {
+			status.Status = state.Status
+			status.Data = state.Data
+			switch state.Status {
+			case StatusReady:
+				resp.Ready++
+			case StatusInProgress:
+				resp.InProgress++
+			default:
+				resp.Failed++
+			}
+		} else {
+			status.Status = StatusError
+			resp.Failed++
+		}
+		resp.Items = append(resp.Items, status)
+	}
+
+	switch {
+	case resp.InProgress > 0:
+		resp.Status = StatusInProgress
+	case resp.Ready == resp.Total:
+		resp.Status = StatusReady
+	case resp.Ready > 0:
+		resp.Status = StatusPartial
+	default:
+		resp.Status = StatusError
+	}
+	return resp, nil
+}
+
+// createTask открывает спан создания задачи; записи в хранилище и публикация в RabbitMQ
+// попадают в него дочерними спанами.
+func (m ManagerServiceImpl) createTask(
+	ctx context.Context,
+	req types.CrackRequest,
+	checkAdmission bool,
 ) (string, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ManagerService.CreateTask")
+	defer span.End()
+	requestID, err := m.startTask(ctx, req, checkAdmission)
+	tracing.RecordError(span, err)
+	span.SetAttributes(attribute.String(logging.KeyRequestID, requestID))
+	return requestID, err
+}
 
-	if m.store.Count() >= MaxQueueSize {
-		return "", errors.New("очередь заполнена, попробуйте позже")
+func (m ManagerServiceImpl) startTask(
+	ctx context.Context,
+	req types.CrackRequest,
+	checkAdmission bool,
+) (string, error) {
+	if err := m.ValidateRequest(ctx, req); err != nil {
+		return "", err
+	}
+	hash, maxLength := strings.ToLower(req.Hash), req.MaxLength
+	algorithm := strings.ToLower(req.Algorithm)
+	if algorithm == "" {
+		algorithm = AlgorithmMD5
+	}
+	principal, authenticated := auth.PrincipalFrom(ctx)
+
+	if m.potfile != nil {
+		if plain, ok := m.potfile.Lookup(principal.Tenant, algorithm, hash); ok {
+			requestID := uuid.New().String()
+			logging.Request(component, requestID).Info("Хэш найден в potfile, запрос сразу READY", "hash", hash)
+			state := store.RequestState{
+				Status:      StatusReady,
+				Data:        []string{plain},
+				StartTime:   time.Now(),
+				Timeout:     m.Settings().ResponseTimeout,
+				Hash:        hash,
+				MaxLength:   maxLength,
+				Algorithm:   algorithm,
+				CallbackURL: req.CallbackURL,
+				Owner:       principal.KeyID,
+				Tenant:      principal.Tenant,
+			}
+			m.store.Set(ctx, requestID, state)
+			m.notifyCompletion(requestID, state)
+			return requestID, nil
+		}
+	}
+
+	alphabet := defaultAlphabet()
+	fingerprint := TaskFingerprint(algorithm, hash, alphabet, maxLength)
+
+	m.jobMu.Lock()
+	defer m.jobMu.Unlock()
+
+	if authenticated && principal.MaxConcurrent > 0 &&
+		m.store.CountActiveByOwner(principal.KeyID) >= principal.MaxConcurrent {
+		return "", ErrConcurrentQuota
+	}
+
+	if jobID, job, ok := m.findActiveJob(principal.Tenant, fingerprint); ok {
+		requestID := uuid.New().String()
+		logging.Request(component, requestID).Info("Запрос присоединён к выполняющейся задаче", "jobId", jobID)
+		m.store.Set(ctx, requestID, store.RequestState{
+			Status:      StatusInProgress,
+			Data:        job.Data,
+			StartTime:   job.StartTime,
+			Timeout:     job.Timeout,
+			Hash:        hash,
+			MaxLength:   maxLength,
+			Algorithm:   algorithm,
+			Fingerprint: fingerprint,
+			JobID:       jobID,
+			CallbackURL: req.CallbackURL,
+			Owner:       principal.KeyID,
+			Tenant:      principal.Tenant,
+		})
+		return requestID, nil
+	}
+
+	settings := m.Settings()
+	if checkAdmission && m.store.CountActive() >= settings.MaxQueueSize {
+		return "", ErrQueueFull
+	}
+	// Keyspace списывается только за реально запускаемый перебор: ответы из potfile и
+	// присоединение к выполняющейся задаче воркеров не нагружают.
+	if authenticated && m.quota != nil {
+		day := time.Now().UTC().Format(time.DateOnly)
+		if !m.quota.ConsumeKeyspace(principal.KeyID, day, Keyspace(len(alphabet), maxLength), principal.DailyKeyspace) {
+			return "", ErrKeyspaceQuota
+		}
 	}
 
 	requestID := uuid.New().String()
-	log.Printf("[managerService] Создаём задачу requestID=%s, hash=%s, maxLength=%d",
-		requestID, hash, maxLength)
+	logging.Request(component, requestID).Info("Создаём задачу", "hash", hash, "maxLength", maxLength)
 
 	state := store.RequestState{
-		Status:    StatusInProgress,
-		Data:      nil,
-		StartTime: time.Now(),
-		Timeout:   m.responseTimeout,
-	}
-	state.Timer = time.AfterFunc(m.responseTimeout, func() {
-		s, ok := m.store.Get(requestID)
-		if ok && s.Status == StatusInProgress {
+		Status:      StatusInProgress,
+		Data:        nil,
+		StartTime:   time.Now(),
+		Timeout:     settings.ResponseTimeout,
+		Hash:        hash,
+		MaxLength:   maxLength,
+		Algorithm:   algorithm,
+		Fingerprint: fingerprint,
+		JobID:       requestID,
+		CallbackURL: req.CallbackURL,
+		Owner:       principal.KeyID,
+		Tenant:      principal.Tenant,
+		// Задача считается отправленной только после подтверждения брокера.
+		Pending: true,
+	}
+	state.Timer = time.AfterFunc(settings.ResponseTimeout, func() {
+		m.jobMu.Lock()
+		defer m.jobMu.Unlock()
+		m.updateJob(context.Background(), requestID, func(id string, s *store.RequestState) bool {
+			if s.Status != StatusInProgress {
+				return false
+			}
 			s.Status = StatusError
-			m.store.Update(requestID, s)
-			log.Printf("[managerService] Задача requestID=%s переведена в ERROR (таймаут)", requestID)
-			m.store.MarkPending(requestID, false)
-		}
+			logging.Request(component, id).Warn("Задача переведена в ERROR по таймауту")
+			return true
+		})
+		m.store.MarkPending(context.Background(), requestID, false)
+		m.forgetRequeues(requestID)
 	})
 
-	m.store.Set(requestID, state)
-
-	var alphabet []string
-	for _, ch := range "abcdefghijklmnopqrstuvwxyz0123456789" {
-		alphabet = append(alphabet, string(ch))
-	}
+	m.store.Set(ctx, requestID, state)
 
 	task := types.CrackHashManagerRequest{
 		RequestId:  requestID,
@@ -91,40 +506,59 @@ func (m ManagerServiceImpl) CreateTask(
 		},
 	}
 
+	// Публикация переживает HTTP-запрос, поэтому от его контекста берётся только трасса.
+	pubCtx := context.WithoutCancel(ctx)
+	m.publishes.Add(1)
 	go func(reqID string, t types.CrackHashManagerRequest) {
+		defer m.publishes.Done()
+		logger := logging.Part(component, reqID, t.PartNumber)
 		if m.rabbitClient != nil && m.rabbitClient.IsConnected() {
-			log.Printf("[managerService] Публикуем задачу requestID=%s в RabbitMQ (hash=%s, maxLength=%d)",
-				reqID, t.Hash, t.MaxLength)
-			err := m.rabbitClient.PublishTask(t)
+			logger.Debug("Публикуем задачу в RabbitMQ", "hash", t.Hash, "maxLength", t.MaxLength)
+			err := m.rabbitClient.PublishTask(pubCtx, t)
 			if err != nil {
-				log.Printf("[managerService] Ошибка PublishTask для requestID=%s: %v", reqID, err)
-				m.store.MarkPending(reqID, true)
+				logger.Error("Ошибка PublishTask, задача остаётся pending", "error", err)
+				metrics.QueuePublishFailures.Inc()
 			} else {
-				m.store.MarkPending(reqID, false)
-				log.Printf("[managerService] Успешно отправили requestID=%s в очередь", reqID)
+				m.store.MarkPending(pubCtx, reqID, false)
+				logger.Info("Задача отправлена в очередь")
 			}
 		} else {
-			log.Printf("[managerService] RabbitMQ не подключен, помечаем requestID=%s как pending", reqID)
-			m.store.MarkPending(reqID, true)
+			logger.Warn("RabbitMQ не подключен, задача остаётся pending")
 		}
 	}(requestID, task)
 
 	return requestID, nil
 }
 
+// Drain дожидается фоновых публикаций задач и, если RabbitMQ доступен, последний раз
+// переотправляет pending-задачи. Задача записывается в хранилище с pending, и флаг
+// снимается только после подтверждения брокера, поэтому задачи, не отправленные к
+// остановке, RetryPendingTasks переотправит после перезапуска.
+func (m ManagerServiceImpl) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.publishes.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if m.rabbitClient != nil && m.rabbitClient.IsConnected() {
+		return m.RetryPendingTasks(ctx)
+	}
+	return nil
+}
+
 func (m ManagerServiceImpl) RetryPendingTasks(ctx context.Context) error {
 	pendingList := m.store.GetPending()
+	metrics.PendingTasks.Set(float64(len(pendingList)))
 	if len(pendingList) > 0 {
-		log.Printf("[managerService] Найдено %d pending-задач, пробуем переотправить...", len(pendingList))
+		logging.Component(component).Info("Найдены pending-задачи, пробуем переотправить", "count", len(pendingList))
 	}
 	for _, req := range pendingList {
-		if req.Status == StatusInProgress {
-			fullAlphabet := []string{
-				"a", "b", "c", "d", "e", "f", "g", "h", "i", "j",
-				"k", "l", "m", "n", "o", "p", "q", "r", "s", "t",
-				"u", "v", "w", "x", "y", "z",
-				"0", "1", "2", "3", "4", "5", "6", "7", "8", "9",
-			}
+		if m.jobActive(req.ID) {
 			task := types.CrackHashManagerRequest{
 				RequestId:  req.ID,
 				PartNumber: 0,
@@ -132,18 +566,271 @@ func (m ManagerServiceImpl) RetryPendingTasks(ctx context.Context) error {
 				Hash:       req.Hash,
 				MaxLength:  req.MaxLength,
 				Alphabet: types.Alphabet{
-					Symbols: fullAlphabet,
+					Symbols: defaultAlphabet(),
 				},
 			}
-			log.Printf("[managerService] Переотправляем pending-задачу requestID=%s (hash=%s, maxLength=%d)",
-				req.ID, req.Hash, req.MaxLength)
-			if err := m.rabbitClient.PublishTask(task); err != nil {
-				log.Printf("[managerService] Ошибка при повторной отправке %s: %v", req.ID, err)
+			logger := logging.Part(component, req.ID, task.PartNumber)
+			logger.Debug("Переотправляем pending-задачу", "hash", req.Hash, "maxLength", req.MaxLength)
+			metrics.PendingRetries.Inc()
+			if err := m.rabbitClient.PublishTask(ctx, task); err != nil {
+				logger.Error("Ошибка при повторной отправке", "error", err)
+				metrics.QueuePublishFailures.Inc()
 			} else {
-				m.store.MarkPending(req.ID, false)
-				log.Printf("[managerService] Успешно переотправили requestID=%s", req.ID)
+				m.store.MarkPending(ctx, req.ID, false)
+				logger.Info("Pending-задача переотправлена")
 			}
 		}
 	}
 	return nil
 }
+
+func (m ManagerServiceImpl) CancelTask(ctx context.Context, requestID string) error {
+	m.jobMu.Lock()
+	defer m.jobMu.Unlock()
+
+	// Чужие запросы неотличимы от несуществующих, чтобы не раскрывать их ID.
+	state, ok := m.store.Get(requestID)
+	if !ok || !auth.CanAccess(ctx, state.Tenant) {
+		return ErrRequestNotFound
+	}
+	if state.Status != StatusInProgress {
+		return ErrNotCancellable
+	}
+	state.Status = StatusCancelled
+	m.store.Update(ctx, requestID, state)
+	m.publishStatus(requestID, state)
+	m.notifyCompletion(requestID, state)
+	logging.Request(component, requestID).Info("Запрос отменён клиентом")
+	m.audit.Record(ctx, store.AuditCancel, requestID, nil)
+
+	jobID := jobOf(requestID, state)
+	if m.jobActive(jobID) {
+		return nil
+	}
+	if job, ok := m.store.Get(jobID); ok && job.Timer != nil {
+		job.Timer.Stop()
+	}
+	m.store.MarkPending(ctx, jobID, false)
+	m.forgetRequeues(jobID)
+	logging.Request(component, jobID).Info("Все подписчики отменили задачу, задача отменена")
+	return nil
+}
+
+// HandleWorkerResponse продолжает трассу, в которой воркер отправил ответ в RabbitMQ; для
+// ответов по HTTP родителем остаётся спан из ctx.
+func (m ManagerServiceImpl) HandleWorkerResponse(ctx context.Context, resp types.CrackHashWorkerResponse) {
+	if resp.SpanContext.IsValid() {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, resp.SpanContext)
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "ManagerService.HandleWorkerResponse", trace.WithAttributes(
+		attribute.String(logging.KeyRequestID, resp.RequestId),
+		attribute.Int(logging.KeyPartNumber, resp.PartNumber),
+		attribute.String("workerId", resp.WorkerId),
+	))
+	defer span.End()
+
+	m.jobMu.Lock()
+	defer m.jobMu.Unlock()
+
+	logger := logging.Part(component, resp.RequestId, resp.PartNumber)
+	job, ok := m.store.Get(resp.RequestId)
+	if !ok {
+		logger.Warn("Ответ для неизвестного запроса, пропускаем")
+		return
+	}
+	if !job.StartTime.IsZero() {
+		metrics.ResponseLatency.Observe(time.Since(job.StartTime).Seconds())
+	}
+
+	// WorkerId из тела может указать любой узел с ключом подписи, поэтому карантин ведётся
+	// по ID проверенного ключа, а WorkerId попадает только в логи.
+	workerID := resp.SigningKeyID
+	if workerID == "" {
+		workerID = UnknownWorker
+	}
+	logger = logger.With("workerId", workerID, "reportedWorkerId", resp.WorkerId)
+	if m.workers.IsQuarantined(workerID) {
+		logger.Warn("Воркер в карантине, ответ отброшен")
+		if m.jobActive(resp.RequestId) {
+			m.requeuePart(ctx, resp.RequestId, resp.PartNumber)
+		}
+		return
+	}
+
+	algorithm := job.Algorithm
+	if algorithm == "" {
+		algorithm = AlgorithmMD5
+	}
+	// Воркеру не доверяем: каждое слово перепроверяется, неверные отбрасываются,
+	// а часть переотправляется, чтобы её пересчитал другой воркер.
+	words, invalid := verifyWords(algorithm, job.Hash, resp.Answers.Words)
+	resp.Answers.Words = words
+	if m.workers.Record(workerID, len(words), len(invalid)) {
+		logger.Error("Воркер помещён в карантин после повторных неверных ответов")
+	}
+	if len(invalid) > 0 {
+		logger.Warn("Воркер прислал неверные слова, они отклонены", "rejected", len(invalid))
+	}
+
+	m.updateJob(ctx, resp.RequestId, func(id string, state *store.RequestState) bool {
+		if state.Status != StatusInProgress {
+			return false
+		}
+		if state.Data == nil {
+			state.Data = []string{}
+		}
+		state.Data = append(state.Data, resp.Answers.Words...)
+		m.publish(events.Event{
+			Type:       events.TypePartial,
+			RequestID:  id,
+			Status:     state.Status,
+			Progress:   Progress(*state),
+			PartNumber: resp.PartNumber,
+			Words:      resp.Answers.Words,
+		})
+		if len(state.Data) > 0 {
+			state.Status = StatusReady
+			if state.Timer != nil {
+				state.Timer.Stop()
+			}
+		}
+		return true
+	})
+	if !m.jobActive(resp.RequestId) {
+		m.store.MarkPending(ctx, resp.RequestId, false)
+		m.forgetRequeues(resp.RequestId)
+	} else if len(invalid) > 0 {
+		m.requeuePart(ctx, resp.RequestId, resp.PartNumber)
+	}
+
+	if m.potfile != nil && len(resp.Answers.Words) > 0 && job.Hash != "" {
+		for _, word := range resp.Answers.Words {
+			m.potfile.Add(store.PotfileEntry{
+				Algorithm: algorithm,
+				Hash:      job.Hash,
+				Plaintext: word,
+				Tenant:    job.Tenant,
+			})
+		}
+	}
+}
+
+// requeuePart возвращает часть в очередь после отброшенного ответа. Если часть уже
+// возвращалась maxRequeues раз, задача переводится в ERROR: иначе воркер в карантине,
+// который продолжает забирать задачи, гонял бы её по очереди до таймаута. Вызывается
+// под jobMu.
+func (m ManagerServiceImpl) requeuePart(ctx context.Context, jobID string, partNumber int) {
+	key := partKey{requestID: jobID, partNumber: partNumber}
+	m.requeues[key]++
+	if m.requeues[key] <= m.maxRequeues {
+		m.store.MarkPending(ctx, jobID, true)
+		return
+	}
+	m.updateJob(ctx, jobID, func(id string, state *store.RequestState) bool {
+		if state.Status != StatusInProgress {
+			return false
+		}
+		state.Status = StatusError
+		if state.Timer != nil {
+			state.Timer.Stop()
+		}
+		logging.Part(component, id, partNumber).Error("Задача переведена в ERROR: часть слишком часто возвращалась в очередь",
+			"requeues", m.maxRequeues)
+		return true
+	})
+	m.store.MarkPending(ctx, jobID, false)
+	m.forgetRequeues(jobID)
+}
+
+// forgetRequeues сбрасывает счётчики завершённой задачи. Вызывается под jobMu.
+func (m ManagerServiceImpl) forgetRequeues(jobID string) {
+	for key := range m.requeues {
+		if key.requestID == jobID {
+			delete(m.requeues, key)
+		}
+	}
+}
+
+func jobOf(requestID string, state store.RequestState) string {
+	if state.JobID == "" {
+		return requestID
+	}
+	return state.JobID
+}
+
+func (m ManagerServiceImpl) findActiveJob(tenant, fingerprint string) (string, store.RequestState, bool) {
+	id, state, ok := m.store.FindActiveByFingerprint(tenant, fingerprint)
+	if !ok {
+		return "", store.RequestState{}, false
+	}
+	return jobOf(id, state), state, true
+}
+
+func (m ManagerServiceImpl) jobActive(jobID string) bool {
+	for _, id := range m.store.ListByJob(jobID) {
+		if s, ok := m.store.Get(id); ok && s.Status == StatusInProgress {
+			return true
+		}
+	}
+	return false
+}
+
+func (m ManagerServiceImpl) updateJob(ctx context.Context, jobID string, fn func(id string, state *store.RequestState) bool) {
+	for _, id := range m.store.ListByJob(jobID) {
+		state, ok := m.store.Get(id)
+		if !ok {
+			continue
+		}
+		prevStatus := state.Status
+		if fn(id, &state) {
+			m.store.Update(ctx, id, state)
+			if prevStatus == StatusInProgress && state.Status != StatusInProgress {
+				m.publishStatus(id, state)
+				m.notifyCompletion(id, state)
+			}
+		}
+	}
+}
+
+func (m ManagerServiceImpl) notifyCompletion(requestID string, state store.RequestState) {
+	if m.notifier != nil && state.CallbackURL != "" {
+		m.notifier.NotifyCompletion(requestID, state)
+	}
+}
+
+func (m ManagerServiceImpl) publishStatus(requestID string, state store.RequestState) {
+	m.publish(events.Event{
+		Type:      events.TypeStatus,
+		RequestID: requestID,
+		Status:    state.Status,
+		Progress:  Progress(state),
+		Words:     state.Data,
+	})
+}
+
+func (m ManagerServiceImpl) publish(e events.Event) {
+	if m.events != nil {
+		m.events.Publish(e)
+	}
+}
+
+func Progress(state store.RequestState) int {
+	switch state.Status {
+	case StatusReady, StatusError, StatusCancelled:
+		return 100
+	case StatusInProgress:
+		if state.StartTime.IsZero() || state.Timeout <= 0 {
+			return 0
+		}
+		elapsed := time.Since(state.StartTime)
+		if elapsed >= state.Timeout {
+			return 100
+		}
+		progress := int(float64(elapsed) / float64(state.Timeout) * 100)
+		if progress < 1 && elapsed > 0 {
+			progress = 1
+		}
+		return progress
+	}
+	return 0
+}
@@ -0,0 +1,500 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"CrackHash/internal/tracing"
+	"CrackHash/manager/internal/auth"
+	"CrackHash/manager/internal/service"
+	"CrackHash/manager/internal/store"
+	"CrackHash/manager/internal/types"
+)
+
+func TestManagerService_CreateTask_PotfileHit(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	potStore := store.NewPotfileStore()
+	potStore.Add(store.PotfileEntry{
+		Algorithm: service.AlgorithmMD5,
+		Hash:      "0cc175b9c0f1b6a831c399e269772661",
+		Plaintext: "a",
+	})
+	queue := &stubTaskQueue{connected: true}
+
+	svc := service.NewManagerService(reqStore, queue, 5*time.Second, service.WithPotfile(potStore))
+	id, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: "0CC175B9C0F1B6A831C399E269772661", MaxLength: 1})
+	require.NoError(t, err)
+
+	state, ok := reqStore.Get(id)
+	require.True(t, ok)
+	require.Equal(t, service.StatusReady, state.Status)
+	require.Equal(t, []string{"a"}, state.Data)
+
+	time.Sleep(50 * time.Millisecond)
+	require.Empty(t, queue.publishedTasks())
+}
+
+func TestManagerService_HandleWorkerResponse_FillsPotfile(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	potStore := store.NewPotfileStore()
+	queue := &stubTaskQueue{connected: true}
+
+	svc := service.NewManagerService(reqStore, queue, 5*time.Second, service.WithPotfile(potStore))
+	id, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3})
+	require.NoError(t, err)
+
+	resp := types.CrackHashWorkerResponse{RequestId: id}
+	resp.Answers.Words = []string{"abc"}
+	svc.HandleWorkerResponse(context.Background(), resp)
+
+	state, ok := reqStore.Get(id)
+	require.True(t, ok)
+	require.Equal(t, service.StatusReady, state.Status)
+
+	plain, ok := potStore.Lookup("", service.AlgorithmMD5, "900150983cd24fb0d6963f7d28e17f72")
+	require.True(t, ok)
+	require.Equal(t, "abc", plain)
+
+	repeatID, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3})
+	require.NoError(t, err)
+	repeat, ok := reqStore.Get(repeatID)
+	require.True(t, ok)
+	require.Equal(t, service.StatusReady, repeat.Status)
+	require.Equal(t, []string{"abc"}, repeat.Data)
+}
+
+func TestManagerService_CreateTask_DeduplicatesInFlight(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	queue := &stubTaskQueue{connected: true}
+	svc := service.NewManagerService(reqStore, queue, 5*time.Second)
+
+	firstID, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3})
+	require.NoError(t, err)
+	secondID, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983CD24FB0D6963F7D28E17F72", MaxLength: 3})
+	require.NoError(t, err)
+	otherID, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 4})
+	require.NoError(t, err)
+	require.NotEqual(t, firstID, secondID)
+
+	time.Sleep(50 * time.Millisecond)
+	published := queue.publishedTasks()
+	require.Len(t, published, 2)
+
+	second, ok := reqStore.Get(secondID)
+	require.True(t, ok)
+	require.Equal(t, firstID, second.JobID)
+
+	resp := types.CrackHashWorkerResponse{RequestId: firstID}
+	resp.Answers.Words = []string{"abc"}
+	svc.HandleWorkerResponse(context.Background(), resp)
+
+	for _, id := range []string{firstID, secondID} {
+		state, ok := reqStore.Get(id)
+		require.True(t, ok)
+		require.Equal(t, service.StatusReady, state.Status)
+		require.Equal(t, []string{"abc"}, state.Data)
+	}
+	other, ok := reqStore.Get(otherID)
+	require.True(t, ok)
+	require.Equal(t, service.StatusInProgress, other.Status)
+}
+
+func TestManagerService_CreateTask_IsolatesTenants(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	potStore := store.NewPotfileStore()
+	potStore.Add(store.PotfileEntry{Algorithm: service.AlgorithmMD5, Hash: "0cc175b9c0f1b6a831c399e269772661", Plaintext: "a"})
+	queue := &stubTaskQueue{connected: true}
+	svc := service.NewManagerService(reqStore, queue, 5*time.Second, service.WithPotfile(potStore))
+	teamA := auth.WithPrincipal(context.Background(), auth.Principal{KeyID: "a", Tenant: "team-a", Role: auth.RoleSubmitter})
+	teamB := auth.WithPrincipal(context.Background(), auth.Principal{KeyID: "b", Tenant: "team-b", Role: auth.RoleSubmitter})
+	req := types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3}
+
+	idA, err := svc.CreateTask(teamA, req)
+	require.NoError(t, err)
+	idB, err := svc.CreateTask(teamB, req)
+	require.NoError(t, err)
+	stateB, _ := reqStore.Get(idB)
+	require.Equal(t, idB, stateB.JobID, "запрос другого тенанта не присоединяется к чужой задаче")
+
+	resp := types.CrackHashWorkerResponse{RequestId: idA}
+	resp.Answers.Words = []string{"abc"}
+	svc.HandleWorkerResponse(context.Background(), resp)
+	stateB, _ = reqStore.Get(idB)
+	require.Equal(t, service.StatusInProgress, stateB.Status)
+
+	// Взломанный командой A хэш отвечается из potfile только ей.
+	againA, err := svc.CreateTask(teamA, req)
+	require.NoError(t, err)
+	state, _ := reqStore.Get(againA)
+	require.Equal(t, service.StatusReady, state.Status)
+	_, ok := potStore.Lookup("team-b", service.AlgorithmMD5, req.Hash)
+	require.False(t, ok)
+
+	// Записи, загруженные администратором, общие.
+	shared, err := svc.CreateTask(teamB, types.CrackRequest{Hash: "0cc175b9c0f1b6a831c399e269772661", MaxLength: 1})
+	require.NoError(t, err)
+	state, _ = reqStore.Get(shared)
+	require.Equal(t, service.StatusReady, state.Status)
+}
+
+func TestManagerService_CancelTask_SharedJob(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	queue := &stubTaskQueue{connected: true}
+	svc := service.NewManagerService(reqStore, queue, 5*time.Second)
+
+	firstID, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3})
+	require.NoError(t, err)
+	secondID, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.CancelTask(context.Background(), firstID))
+
+	first, _ := reqStore.Get(firstID)
+	require.Equal(t, service.StatusCancelled, first.Status)
+	second, _ := reqStore.Get(secondID)
+	require.Equal(t, service.StatusInProgress, second.Status)
+
+	resp := types.CrackHashWorkerResponse{RequestId: firstID}
+	resp.Answers.Words = []string{"abc"}
+	svc.HandleWorkerResponse(context.Background(), resp)
+
+	first, _ = reqStore.Get(firstID)
+	require.Equal(t, service.StatusCancelled, first.Status)
+	second, _ = reqStore.Get(secondID)
+	require.Equal(t, service.StatusReady, second.Status)
+
+	require.ErrorIs(t, svc.CancelTask(context.Background(), secondID), service.ErrNotCancellable)
+	require.ErrorIs(t, svc.CancelTask(context.Background(), "missing"), service.ErrRequestNotFound)
+}
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events map[string]string
+}
+
+func (r *recordingNotifier) NotifyCompletion(requestID string, state store.RequestState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events[requestID] = state.Status
+}
+
+func TestManagerService_NotifiesCallbacksOnCompletion(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	queue := &stubTaskQueue{connected: true}
+	notifier := &recordingNotifier{events: map[string]string{}}
+	svc := service.NewManagerService(reqStore, queue, 5*time.Second, service.WithCompletionNotifier(notifier))
+
+	withCallback, err := svc.CreateTask(context.Background(), types.CrackRequest{
+		Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3, CallbackURL: "http://example.com/hook",
+	})
+	require.NoError(t, err)
+	cancelled, err := svc.CreateTask(context.Background(), types.CrackRequest{
+		Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3, CallbackURL: "http://example.com/other",
+	})
+	require.NoError(t, err)
+	silent, err := svc.CreateTask(context.Background(), types.CrackRequest{
+		Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, svc.CancelTask(context.Background(), cancelled))
+
+	resp := types.CrackHashWorkerResponse{RequestId: withCallback}
+	resp.Answers.Words = []string{"abc"}
+	svc.HandleWorkerResponse(context.Background(), resp)
+
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	require.Equal(t, map[string]string{
+		withCallback: service.StatusReady,
+		cancelled:    service.StatusCancelled,
+	}, notifier.events)
+	require.NotContains(t, notifier.events, silent)
+}
+
+func TestKeyspace(t *testing.T) {
+	require.Equal(t, int64(36+36*36), service.Keyspace(36, 2))
+	require.Equal(t, int64(0), service.Keyspace(36, 0))
+	require.Equal(t, int64(math.MaxInt64), service.Keyspace(36, 100))
+}
+
+func TestManagerService_CreateTask_KeyspaceChargedOnlyForNewJobs(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	keys := store.NewAPIKeyStore()
+	svc := service.NewManagerService(reqStore, &stubTaskQueue{connected: true}, 5*time.Second,
+		service.WithKeyspaceQuota(keys))
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{KeyID: "k1", DailyKeyspace: 2000})
+	day := time.Now().UTC().Format(time.DateOnly)
+
+	first, err := svc.CreateTask(ctx, types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 2})
+	require.NoError(t, err)
+	_, err = svc.CreateTask(ctx, types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 2})
+	require.NoError(t, err)
+	require.Equal(t, service.Keyspace(36, 2), keys.KeyspaceUsed("k1", day))
+
+	state, _ := reqStore.Get(first)
+	require.Equal(t, "k1", state.Owner)
+
+	_, err = svc.CreateTask(ctx, types.CrackRequest{Hash: "0cc175b9c0f1b6a831c399e269772661", MaxLength: 2})
+	require.ErrorIs(t, err, service.ErrKeyspaceQuota)
+
+	_, err = svc.CreateTask(context.Background(), types.CrackRequest{Hash: "0cc175b9c0f1b6a831c399e269772661", MaxLength: 2})
+	require.NoError(t, err)
+}
+
+func TestManagerService_CreateTask_ConcurrentQuota(t *testing.T) {
+	svc := service.NewManagerService(store.NewRequestStore(), &stubTaskQueue{connected: true}, 5*time.Second)
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{KeyID: "k1", MaxConcurrent: 1})
+
+	id, err := svc.CreateTask(ctx, types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 2})
+	require.NoError(t, err)
+	_, err = svc.CreateTask(ctx, types.CrackRequest{Hash: "0cc175b9c0f1b6a831c399e269772661", MaxLength: 2})
+	require.ErrorIs(t, err, service.ErrConcurrentQuota)
+
+	require.NoError(t, svc.CancelTask(ctx, id))
+	_, err = svc.CreateTask(ctx, types.CrackRequest{Hash: "0cc175b9c0f1b6a831c399e269772661", MaxLength: 2})
+	require.NoError(t, err)
+}
+
+func TestManagerService_HandleWorkerResponse_RejectsWrongWords(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	potStore := store.NewPotfileStore()
+	queue := &stubTaskQueue{connected: true}
+	tracker := service.NewWorkerTracker(2, time.Hour)
+
+	svc := service.NewManagerService(reqStore, queue, 5*time.Second,
+		service.WithPotfile(potStore), service.WithWorkerTracker(tracker))
+	id, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3})
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	resp := types.CrackHashWorkerResponse{RequestId: id, WorkerId: "w1", SigningKeyID: "w1"}
+	resp.Answers.Words = []string{"abd"}
+	svc.HandleWorkerResponse(context.Background(), resp)
+
+	state, ok := reqStore.Get(id)
+	require.True(t, ok)
+	require.Equal(t, service.StatusInProgress, state.Status)
+	require.Empty(t, state.Data)
+	require.True(t, state.Pending, "часть с неверным ответом должна уйти на переотправку")
+	_, ok = potStore.Lookup("", service.AlgorithmMD5, "900150983cd24fb0d6963f7d28e17f72")
+	require.False(t, ok)
+
+	// Верные слова из того же ответа принимаются, неверные отбрасываются.
+	resp.Answers.Words = []string{"zzz", "abc"}
+	svc.HandleWorkerResponse(context.Background(), resp)
+	state, _ = reqStore.Get(id)
+	require.Equal(t, service.StatusReady, state.Status)
+	require.Equal(t, []string{"abc"}, state.Data)
+
+	stats := tracker.List()
+	require.Len(t, stats, 1)
+	require.Equal(t, 2, stats[0].Responses)
+	require.Equal(t, 1, stats[0].AcceptedWords)
+	require.Equal(t, 2, stats[0].RejectedWords)
+	require.True(t, stats[0].Quarantined)
+}
+
+func TestManagerService_HandleWorkerResponse_IdentifiesWorkerBySigningKey(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	tracker := service.NewWorkerTracker(1, time.Hour)
+	svc := service.NewManagerService(reqStore, &stubTaskQueue{connected: true}, time.Minute,
+		service.WithWorkerTracker(tracker))
+	id, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3})
+	require.NoError(t, err)
+
+	// Воркер с ключом w2 выдаёт себя за w1: карантин получает w2, а не w1.
+	resp := types.CrackHashWorkerResponse{RequestId: id, WorkerId: "w1", SigningKeyID: "w2"}
+	resp.Answers.Words = []string{"abd"}
+	svc.HandleWorkerResponse(context.Background(), resp)
+	require.True(t, tracker.IsQuarantined("w2"))
+	require.False(t, tracker.IsQuarantined("w1"))
+
+	// Сменить WorkerId мало, чтобы выйти из карантина.
+	resp.WorkerId = "w3"
+	resp.Answers.Words = []string{"abc"}
+	svc.HandleWorkerResponse(context.Background(), resp)
+	state, _ := reqStore.Get(id)
+	require.Equal(t, service.StatusInProgress, state.Status)
+}
+
+func TestManagerService_HandleWorkerResponse_CapsRequeues(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	queue := &stubTaskQueue{connected: true}
+	tracker := service.NewWorkerTracker(1, time.Hour)
+
+	svc := service.NewManagerService(reqStore, queue, time.Minute,
+		service.WithWorkerTracker(tracker), service.WithMaxPartRequeues(3))
+	id, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3})
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	// Неверный ответ отправляет w1 в карантин, а он продолжает забирать ту же часть.
+	resp := types.CrackHashWorkerResponse{RequestId: id, WorkerId: "w1", SigningKeyID: "w1"}
+	resp.Answers.Words = []string{"abd"}
+	for i := 0; i < 3; i++ {
+		svc.HandleWorkerResponse(context.Background(), resp)
+		state, _ := reqStore.Get(id)
+		require.Equal(t, service.StatusInProgress, state.Status)
+		require.True(t, state.Pending, "попытка %d: часть возвращается в очередь", i+1)
+	}
+
+	svc.HandleWorkerResponse(context.Background(), resp)
+	state, _ := reqStore.Get(id)
+	require.Equal(t, service.StatusError, state.Status, "после лимита возвратов задача завершается ошибкой")
+	require.False(t, state.Pending)
+}
+
+func TestWorkerTracker_QuarantineExpires(t *testing.T) {
+	tracker := service.NewWorkerTracker(2, 50*time.Millisecond)
+
+	require.False(t, tracker.Record("w1", 0, 1))
+	require.False(t, tracker.Record("w1", 1, 0), "верный ответ сбрасывает серию")
+	require.False(t, tracker.Record("w1", 0, 1))
+	require.True(t, tracker.Record("w1", 0, 1))
+	require.True(t, tracker.IsQuarantined("w1"))
+	require.False(t, tracker.IsQuarantined("w2"))
+
+	time.Sleep(60 * time.Millisecond)
+	require.False(t, tracker.IsQuarantined("w1"))
+
+	var nilTracker *service.WorkerTracker
+	require.False(t, nilTracker.Record("w1", 0, 1))
+	require.Empty(t, nilTracker.List())
+}
+
+func TestManagerService_Drain_WaitsForPublishes(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	queue := &stubTaskQueue{connected: true}
+	svc := service.NewManagerService(reqStore, queue, 5*time.Second)
+
+	for _, hash := range []string{"900150983cd24fb0d6963f7d28e17f72", "0cc175b9c0f1b6a831c399e269772661"} {
+		_, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: hash, MaxLength: 3})
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, svc.Drain(ctx))
+	require.Len(t, queue.publishedTasks(), 2)
+	require.Empty(t, reqStore.GetPending())
+}
+
+func TestManagerService_TaskPendingUntilBrokerAck(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	queue := &stubTaskQueue{connected: true}
+	svc := service.NewManagerService(reqStore, queue, 5*time.Second)
+
+	// Пока публикация не завершилась, задача должна быть записана как pending.
+	queue.mu.Lock()
+	id, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3})
+	require.NoError(t, err)
+	state, ok := reqStore.Get(id)
+	queue.mu.Unlock()
+	require.True(t, ok)
+	require.True(t, state.Pending, "задача не считается отправленной до подтверждения брокера")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, svc.Drain(ctx))
+	require.Empty(t, reqStore.GetPending())
+}
+
+func TestManagerService_RejectedPublishStaysPending(t *testing.T) {
+	reqStore := store.NewRequestStore()
+	queue := &stubTaskQueue{connected: true, publishErr: errors.New("брокер не принял задачу (nack или канал закрыт)")}
+	svc := service.NewManagerService(reqStore, queue, 5*time.Second)
+
+	id, err := svc.CreateTask(context.Background(), types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, svc.Drain(ctx))
+	require.Empty(t, queue.publishedTasks())
+	state, ok := reqStore.Get(id)
+	require.True(t, ok)
+	require.True(t, state.Pending, "задача, не подтверждённая брокером, не считается отправленной")
+
+	queue.setPublishErr(nil)
+	require.NoError(t, svc.RetryPendingTasks(ctx))
+	require.Len(t, queue.publishedTasks(), 1)
+	require.Empty(t, reqStore.GetPending())
+}
+
+func TestManagerService_TraceSpansRequestLifecycle(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	tp := tracing.Start("manager-test", sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() {
+		tp.Shutdown(context.Background())
+		otel.SetTracerProvider(prev)
+	})
+
+	reqStore := store.NewRequestStore()
+	queue := &stubTaskQueue{connected: true}
+	svc := service.NewManagerService(reqStore, queue, 5*time.Second)
+
+	ctx, root := tp.Tracer("test").Start(context.Background(), "POST /api/hash/crack")
+	id, err := svc.CreateTask(ctx, types.CrackRequest{Hash: "900150983cd24fb0d6963f7d28e17f72", MaxLength: 3})
+	require.NoError(t, err)
+	root.End()
+	require.NoError(t, svc.Drain(context.Background()))
+
+	traceID := root.SpanContext().TraceID()
+	published := queue.publishedSpans()
+	require.Len(t, published, 1)
+	require.Equal(t, traceID, published[0].TraceID(), "публикация должна продолжать трассу HTTP-запроса")
+
+	// Ответ воркера приходит с контекстом трассы из заголовков RabbitMQ.
+	_, workerSpan := tp.Tracer("test").Start(trace.ContextWithSpanContext(context.Background(), published[0]), "worker_responses publish")
+	workerSpan.End()
+	resp := types.CrackHashWorkerResponse{RequestId: id, SpanContext: workerSpan.SpanContext()}
+	resp.Answers.Words = []string{"abc"}
+	svc.HandleWorkerResponse(context.Background(), resp)
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, s := range exporter.GetSpans() {
+		require.Equal(t, traceID, s.SpanContext.TraceID(), s.Name)
+		spans[s.Name] = s
+	}
+	require.Equal(t, root.SpanContext().SpanID(), spans["ManagerService.CreateTask"].Parent.SpanID())
+	require.Equal(t, workerSpan.SpanContext().SpanID(), spans["ManagerService.HandleWorkerResponse"].Parent.SpanID())
+}
+
+func TestManagerService_UpdateSettings_ConcurrentFieldsNotLost(t *testing.T) {
+	svc := service.NewManagerService(store.NewRequestStore(), nil, time.Minute)
+
+	// PATCH и SIGHUP меняют разные поля одновременно: ни одно изменение не теряется.
+	var wg sync.WaitGroup
+	for i := 1; i <= 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, svc.UpdateSettings(context.Background(), func(s *service.Settings) { s.MaxQueueSize++ }, "api"))
+		}()
+		go func() {
+			defer wg.Done()
+			require.NoError(t, svc.UpdateSettings(context.Background(), func(s *service.Settings) { s.ResponseTimeout += time.Second }, "sighup"))
+		}()
+	}
+	wg.Wait()
+
+	s := svc.Settings()
+	require.Equal(t, service.DefaultMaxQueueSize+100, s.MaxQueueSize)
+	require.Equal(t, time.Minute+100*time.Second, s.ResponseTimeout)
+
+	err := svc.UpdateSettings(context.Background(), func(s *service.Settings) { s.MaxQueueSize = 0 }, "api")
+	require.Error(t, err)
+	require.Equal(t, s, svc.Settings(), "неверные настройки не применяются")
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"CrackHash/internal/logging"
+	"CrackHash/manager/internal/store"
+	"CrackHash/manager/internal/types"
+)
+
+// Settings — параметры, которые меняются без перезапуска (SIGHUP или PATCH /api/admin/settings).
+// Заменяются целиком: операция, прочитавшая настройки, работает с одним согласованным набором.
+type Settings struct {
+	ResponseTimeout time.Duration
+	MaxQueueSize    int
+	MaxLengthLimit  int
+}
+
+func (s Settings) Validate() error {
+	invalid := func(field, message string) error {
+		return &ValidationError{Code: types.ErrCodeInvalidParameter, Field: field, Message: message}
+	}
+	switch {
+	case s.ResponseTimeout <= 0:
+		return invalid("responseTimeout", "responseTimeout должен быть больше нуля")
+	case s.MaxQueueSize < 1:
+		return invalid("maxQueueSize", "maxQueueSize должен быть не меньше 1")
+	case s.MaxLengthLimit < MinMaxLength:
+		return invalid("maxLengthLimit", fmt.Sprintf("maxLengthLimit должен быть не меньше %d", MinMaxLength))
+	}
+	return nil
+}
+
+// changes перечисляет отличающиеся от prev значения: параметр -> "старое -> новое".
+func (s Settings) changes(prev Settings) map[string]string {
+	diff := map[string]string{}
+	if s.ResponseTimeout != prev.ResponseTimeout {
+		diff["responseTimeout"] = prev.ResponseTimeout.String() + " -> " + s.ResponseTimeout.String()
+	}
+	if s.MaxQueueSize != prev.MaxQueueSize {
+		diff["maxQueueSize"] = strconv.Itoa(prev.MaxQueueSize) + " -> " + strconv.Itoa(s.MaxQueueSize)
+	}
+	if s.MaxLengthLimit != prev.MaxLengthLimit {
+		diff["maxLengthLimit"] = strconv.Itoa(prev.MaxLengthLimit) + " -> " + strconv.Itoa(s.MaxLengthLimit)
+	}
+	return diff
+}
+
+func defaultSettings(timeout time.Duration) *atomic.Pointer[Settings] {
+	p := &atomic.Pointer[Settings]{}
+	p.Store(&Settings{
+		ResponseTimeout: timeout,
+		MaxQueueSize:    DefaultMaxQueueSize,
+		MaxLengthLimit:  DefaultMaxLengthLimit,
+	})
+	return p
+}
+
+// Settings возвращает текущие настройки.
+func (m ManagerServiceImpl) Settings() Settings {
+	return *m.settings.Load()
+}
+
+// UpdateSettings атомарно применяет update к действующим настройкам и записывает изменения
+// в лог и журнал аудита; source — откуда пришло изменение (sighup, api). Если настройки
+// успели заменить параллельно, update применяется заново к свежим, поэтому он не должен
+// иметь побочных эффектов. Новый таймаут действует на задачи, созданные после замены,
+// лимиты — на следующие запросы.
+func (m ManagerServiceImpl) UpdateSettings(ctx context.Context, update func(*Settings), source string) error {
+	var prev, next Settings
+	for {
+		cur := m.settings.Load()
+		prev, next = *cur, *cur
+		update(&next)
+		if err := next.Validate(); err != nil {
+			return err
+		}
+		if m.settings.CompareAndSwap(cur, &next) {
+			break
+		}
+	}
+	diff := next.changes(prev)
+	if len(diff) == 0 {
+		return nil
+	}
+	logger := logging.Component(component)
+	for name, change := range diff {
+		logger.Info("Настройка изменена", "setting", name, "change", change, "source", source)
+	}
+	diff["source"] = source
+	m.audit.Record(ctx, store.AuditSettings, "", diff)
+	return nil
+}
+
+func WithMaxQueueSize(size int) Option {
+	return func(m *ManagerServiceImpl) {
+		s := m.Settings()
+		s.MaxQueueSize = size
+		m.settings.Store(&s)
+	}
+}
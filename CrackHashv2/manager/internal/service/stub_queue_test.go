@@ -0,0 +1,64 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+
+	"CrackHash/manager/internal/types"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type stubTaskQueue struct {
+	mu        sync.Mutex
+	connected bool
+	published []types.CrackHashManagerRequest
+	spans     []trace.SpanContext
+	// publishErr имитирует nack или возврат задачи брокером.
+	publishErr error
+}
+
+func (s *stubTaskQueue) IsConnected() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connected
+}
+
+func (s *stubTaskQueue) PublishTask(ctx context.Context, task types.CrackHashManagerRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.publishErr != nil {
+		return s.publishErr
+	}
+	s.published = append(s.published, task)
+	s.spans = append(s.spans, trace.SpanContextFromContext(ctx))
+	return nil
+}
+
+func (s *stubTaskQueue) StartConsumeResponses() (<-chan types.CrackHashWorkerResponse, error) {
+	return make(chan types.CrackHashWorkerResponse), nil
+}
+
+func (s *stubTaskQueue) AckMessage(resp types.CrackHashWorkerResponse) {}
+
+func (s *stubTaskQueue) StopConsuming() error { return nil }
+
+func (s *stubTaskQueue) Close() error { return nil }
+
+func (s *stubTaskQueue) setPublishErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.publishErr = err
+}
+
+func (s *stubTaskQueue) publishedTasks() []types.CrackHashManagerRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]types.CrackHashManagerRequest(nil), s.published...)
+}
+
+func (s *stubTaskQueue) publishedSpans() []trace.SpanContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]trace.SpanContext(nil), s.spans...)
+}
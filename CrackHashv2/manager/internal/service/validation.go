@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"CrackHash/manager/internal/hashalg"
+	"CrackHash/manager/internal/types"
+)
+
+const (
+	MinMaxLength          = 1
+	DefaultMaxLengthLimit = 6
+)
+
+type ValidationError struct {
+	Code    string
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func (m ManagerServiceImpl) ValidateRequest(ctx context.Context, req types.CrackRequest) error {
+	algorithm := req.Algorithm
+	if algorithm == "" {
+		algorithm = AlgorithmMD5
+	}
+	alg, ok := hashalg.Lookup(algorithm)
+	if !ok {
+		return &ValidationError{
+			Code:    types.ErrCodeUnsupportedAlgorithm,
+			Field:   "algorithm",
+			Message: fmt.Sprintf("алгоритм %q не поддерживается, доступны: %s", req.Algorithm, strings.Join(hashalg.Supported(), ", ")),
+		}
+	}
+	if !alg.ValidHash(req.Hash) {
+		return &ValidationError{
+			Code:    types.ErrCodeInvalidHash,
+			Field:   "hash",
+			Message: fmt.Sprintf("hash должен состоять из %d шестнадцатеричных символов для %s", alg.HexLength, alg.Name),
+		}
+	}
+	limit := m.Settings().MaxLengthLimit
+	if req.MaxLength < MinMaxLength || req.MaxLength > limit {
+		return &ValidationError{
+			Code:    types.ErrCodeInvalidMaxLength,
+			Field:   "maxLength",
+			Message: fmt.Sprintf("maxLength должен быть в диапазоне от %d до %d", MinMaxLength, limit),
+		}
+	}
+	if req.CallbackURL != "" {
+		u, err := url.Parse(req.CallbackURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &ValidationError{
+				Code:    types.ErrCodeInvalidCallbackURL,
+				Field:   "callbackUrl",
+				Message: "callbackUrl должен быть абсолютным http(s) URL",
+			}
+		}
+		if err := m.callbacks.CheckURL(ctx, req.CallbackURL); err != nil {
+			return &ValidationError{
+				Code:    types.ErrCodeInvalidCallbackURL,
+				Field:   "callbackUrl",
+				Message: "callbackUrl не может указывать во внутреннюю сеть: " + err.Error(),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"CrackHash/manager/internal/audit"
+	"CrackHash/manager/internal/auth"
+	"CrackHash/manager/internal/events"
+	"CrackHash/manager/internal/store"
+)
+
+// LookupRequest читает запрос с проверкой тенанта: чужой запрос выглядит как несуществующий.
+func LookupRequest(ctx context.Context, reqStore store.RequestStore, requestID string) (store.RequestState, bool) {
+	state, ok := reqStore.Get(requestID)
+	if !ok || !auth.CanAccess(ctx, state.Tenant) {
+		return store.RequestState{}, false
+	}
+	return state, true
+}
+
+type EventSubscriber interface {
+	Subscribe(requestID string) (<-chan events.Event, func())
+}
+
+// WatchStatus отправляет текущий статус запроса, затем события шины и периодический прогресс,
+// пока запрос не перейдёт в финальный статус, ctx не будет отменён или send не вернёт ошибку.
+func WatchStatus(
+	ctx context.Context,
+	requestID string,
+	reqStore store.RequestStore,
+	bus EventSubscriber,
+	progressInterval time.Duration,
+	send func(events.Event) error,
+) {
+	ch, unsubscribe := bus.Subscribe(requestID)
+	defer unsubscribe()
+
+	state, ok := reqStore.Get(requestID)
+	if !ok {
+		return
+	}
+	if err := send(StatusEvent(requestID, state)); err != nil || IsFinal(state.Status) {
+		return
+	}
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := send(e); err != nil {
+				return
+			}
+			if e.Type == events.TypeStatus && IsFinal(e.Status) {
+				return
+			}
+		case <-ticker.C:
+			state, ok := reqStore.Get(requestID)
+			if !ok {
+				return
+			}
+			if IsFinal(state.Status) {
+				send(StatusEvent(requestID, state))
+				return
+			}
+			if err := send(events.Event{
+				Type:      events.TypeProgress,
+				RequestID: requestID,
+				Status:    state.Status,
+				Progress:  Progress(state),
+				Timestamp: time.Now().UTC(),
+			}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func StatusEvent(requestID string, state store.RequestState) events.Event {
+	return events.Event{
+		Type:      events.TypeStatus,
+		RequestID: requestID,
+		Status:    state.Status,
+		Progress:  Progress(state),
+		Words:     state.Data,
+		Timestamp: time.Now().UTC(),
+	}
+}
+
+func IsFinal(status string) bool {
+	return status == StatusReady || status == StatusError || status == StatusCancelled
+}
+
+// AuditedSend оборачивает send так, что первое доставленное событие с найденными словами
+// попадает в журнал аудита как чтение результата через channel.
+func AuditedSend(
+	ctx context.Context,
+	auditLog *audit.Logger,
+	requestID, channel string,
+	send func(events.Event) error,
+) func(events.Event) error {
+	recorded := false
+	return func(e events.Event) error {
+		err := send(e)
+		if err == nil && !recorded && len(e.Words) > 0 {
+			recorded = true
+			auditLog.Record(ctx, store.AuditStatusRead, requestID, map[string]string{"channel": channel})
+		}
+		return err
+	}
+}
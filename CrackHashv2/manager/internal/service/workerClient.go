@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"CrackHash/manager/internal/types"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 //go:generate mockgen -destination=./mocks/mock_worker_client.go -package=mocks CrackHash/manager/internal/service WorkerClient
@@ -26,7 +28,7 @@ type multiWorkerClient struct {
 func NewMultiWorkerClient(urls []string) WorkerClient {
 	return multiWorkerClient{
 		workerURLs: urls,
-		client:     http.Client{Timeout: 2 * time.Minute},
+		client:     http.Client{Timeout: 2 * time.Minute, Transport: otelhttp.NewTransport(http.DefaultTransport)},
 	}
 }
 
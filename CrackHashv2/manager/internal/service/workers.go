@@ -0,0 +1,162 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"CrackHash/manager/internal/hashalg"
+)
+
+// UnknownWorker — идентификатор для ответов, принятых без проверки подписи.
+const UnknownWorker = "unknown"
+
+// WorkerStats — счётчики проверки ответов одного воркера; WorkerID — ID его ключа подписи.
+type WorkerStats struct {
+	WorkerID         string    `json:"workerId"`
+	Responses        int       `json:"responses"`
+	AcceptedWords    int       `json:"acceptedWords"`
+	RejectedWords    int       `json:"rejectedWords"`
+	BadResponses     int       `json:"badResponses"`
+	ConsecutiveBad   int       `json:"consecutiveBad"`
+	LastSeen         time.Time `json:"lastSeen"`
+	Quarantined      bool      `json:"quarantined"`
+	QuarantinedUntil time.Time `json:"quarantinedUntil,omitempty"`
+}
+
+// WorkerTracker считает отклонённые слова по воркерам и помещает в карантин воркер,
+// приславший threshold неверных ответов подряд. Ответы воркера в карантине не принимаются
+// до истечения duration или ручного снятия. Методы безопасны для nil.
+//
+// Воркер опознаётся по ID ключа MESSAGE_SIGNING_KEYS, которым подписан ответ: это
+// единственное, что он не может подделать. Поэтому отличать воркеры друг от друга трекер
+// может, только если у каждого свой ключ; при общем ключе все воркеры для него один воркер,
+// и карантин одного останавливает приём ответов от всех.
+type WorkerTracker struct {
+	mu        sync.Mutex
+	threshold int
+	duration  time.Duration
+	workers   map[string]*WorkerStats
+	now       func() time.Time
+}
+
+func NewWorkerTracker(threshold int, duration time.Duration) *WorkerTracker {
+	return &WorkerTracker{
+		threshold: threshold,
+		duration:  duration,
+		workers:   make(map[string]*WorkerStats),
+		now:       time.Now,
+	}
+}
+
+func (t *WorkerTracker) stats(workerID string) *WorkerStats {
+	s, ok := t.workers[workerID]
+	if !ok {
+		s = &WorkerStats{WorkerID: workerID}
+		t.workers[workerID] = s
+	}
+	return s
+}
+
+// refresh снимает карантин, срок которого истёк.
+func (t *WorkerTracker) refresh(s *WorkerStats) {
+	if s.Quarantined && !s.QuarantinedUntil.IsZero() && !t.now().Before(s.QuarantinedUntil) {
+		s.Quarantined = false
+		s.QuarantinedUntil = time.Time{}
+		s.ConsecutiveBad = 0
+	}
+}
+
+func (t *WorkerTracker) IsQuarantined(workerID string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.workers[workerID]
+	if !ok {
+		return false
+	}
+	t.refresh(s)
+	return s.Quarantined
+}
+
+// Record учитывает проверенный ответ и сообщает, попал ли воркер в карантин именно сейчас.
+func (t *WorkerTracker) Record(workerID string, accepted, rejected int) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.stats(workerID)
+	t.refresh(s)
+	s.Responses++
+	s.AcceptedWords += accepted
+	s.RejectedWords += rejected
+	s.LastSeen = t.now()
+	if rejected == 0 {
+		s.ConsecutiveBad = 0
+		return false
+	}
+	s.BadResponses++
+	s.ConsecutiveBad++
+	if s.Quarantined || t.threshold <= 0 || s.ConsecutiveBad < t.threshold {
+		return false
+	}
+	s.Quarantined = true
+	if t.duration > 0 {
+		s.QuarantinedUntil = t.now().Add(t.duration)
+	}
+	return true
+}
+
+// Release снимает карантин вручную; false, если воркер неизвестен.
+func (t *WorkerTracker) Release(workerID string) (WorkerStats, bool) {
+	if t == nil {
+		return WorkerStats{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.workers[workerID]
+	if !ok {
+		return WorkerStats{}, false
+	}
+	s.Quarantined = false
+	s.QuarantinedUntil = time.Time{}
+	s.ConsecutiveBad = 0
+	return *s, true
+}
+
+// List возвращает счётчики всех воркеров, отсортированные по идентификатору.
+func (t *WorkerTracker) List() []WorkerStats {
+	list := []WorkerStats{}
+	if t == nil {
+		return list
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, s := range t.workers {
+		t.refresh(s)
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].WorkerID < list[j].WorkerID })
+	return list
+}
+
+// verifyWords заново хэширует слова алгоритмом запроса и делит их на совпавшие с hash и нет.
+// Соль в запросах пока не поддерживается, поэтому хэш считается от слова целиком.
+func verifyWords(algorithm, hash string, words []string) (valid, invalid []string) {
+	alg, ok := hashalg.Lookup(algorithm)
+	if !ok {
+		return nil, words
+	}
+	for _, word := range words {
+		if strings.EqualFold(alg.Sum(word), hash) {
+			valid = append(valid, word)
+		} else {
+			invalid = append(invalid, word)
+		}
+	}
+	return valid, invalid
+}
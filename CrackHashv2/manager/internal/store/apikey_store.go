@@ -0,0 +1,105 @@
+package store
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// APIKey хранит только SHA-256 от ключа; сам ключ возвращается клиенту один раз при создании.
+// Нулевые лимиты означают отсутствие ограничения.
+type APIKey struct {
+	ID            string    `json:"id" bson:"_id"`
+	Name          string    `json:"name" bson:"name"`
+	KeyHash       string    `json:"-" bson:"keyHash"`
+	Prefix        string    `json:"prefix" bson:"prefix"`
+	Tenant        string    `json:"tenant" bson:"tenant"`
+	Role          string    `json:"role" bson:"role"`
+	MaxConcurrent int       `json:"maxConcurrent" bson:"maxConcurrent"`
+	DailyKeyspace int64     `json:"dailyKeyspace" bson:"dailyKeyspace"`
+	Revoked       bool      `json:"revoked" bson:"revoked"`
+	CreatedAt     time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+type APIKeyStore interface {
+	Create(k APIKey)
+	Update(k APIKey)
+	Get(id string) (APIKey, bool)
+	GetByHash(keyHash string) (APIKey, bool)
+	List() []APIKey
+	// ConsumeKeyspace атомарно списывает amount из дневного лимита ключа;
+	// возвращает false, если после списания лимит был бы превышен.
+	ConsumeKeyspace(keyID, day string, amount, limit int64) bool
+	KeyspaceUsed(keyID, day string) int64
+}
+
+type apiKeyStoreImpl struct {
+	mu    sync.RWMutex
+	keys  map[string]APIKey
+	usage map[string]int64
+}
+
+func NewAPIKeyStore() APIKeyStore {
+	return &apiKeyStoreImpl{
+		keys:  make(map[string]APIKey),
+		usage: make(map[string]int64),
+	}
+}
+
+func (a *apiKeyStoreImpl) Create(k APIKey) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keys[k.ID] = k
+}
+
+func (a *apiKeyStoreImpl) Update(k APIKey) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keys[k.ID] = k
+}
+
+func (a *apiKeyStoreImpl) Get(id string) (APIKey, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	k, ok := a.keys[id]
+	return k, ok
+}
+
+func (a *apiKeyStoreImpl) GetByHash(keyHash string) (APIKey, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, k := range a.keys {
+		if k.KeyHash == keyHash {
+			return k, true
+		}
+	}
+	return APIKey{}, false
+}
+
+func (a *apiKeyStoreImpl) List() []APIKey {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	result := make([]APIKey, 0, len(a.keys))
+	for _, k := range a.keys {
+		result = append(result, k)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result
+}
+
+func (a *apiKeyStoreImpl) ConsumeKeyspace(keyID, day string, amount, limit int64) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := keyID + ":" + day
+	if limit > 0 && a.usage[key]+amount > limit {
+		return false
+	}
+	a.usage[key] += amount
+	return true
+}
+
+func (a *apiKeyStoreImpl) KeyspaceUsed(keyID, day string) int64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.usage[keyID+":"+day]
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	AuditSubmit     = "submit"
+	AuditStatusRead = "status_read"
+	AuditCancel     = "cancel"
+	AuditExport     = "export"
+	AuditSettings   = "settings_update"
+)
+
+// AuditEvent — запись журнала аудита. Журнал только пополняется: записи не изменяются и не удаляются.
+type AuditEvent struct {
+	ID        string            `json:"id" bson:"_id"`
+	Time      time.Time         `json:"time" bson:"time"`
+	Action    string            `json:"action" bson:"action"`
+	KeyID     string            `json:"keyId,omitempty" bson:"keyId,omitempty"`
+	KeyName   string            `json:"keyName,omitempty" bson:"keyName,omitempty"`
+	Tenant    string            `json:"tenant,omitempty" bson:"tenant,omitempty"`
+	Role      string            `json:"role,omitempty" bson:"role,omitempty"`
+	RequestID string            `json:"requestId,omitempty" bson:"requestId,omitempty"`
+	Details   map[string]string `json:"details,omitempty" bson:"details,omitempty"`
+}
+
+// AuditFilter ограничивает выборку; пустые поля не фильтруют, нулевой Limit — без ограничения.
+type AuditFilter struct {
+	Action    string
+	KeyID     string
+	Tenant    string
+	RequestID string
+	From      time.Time
+	To        time.Time
+	Limit     int
+}
+
+func (f AuditFilter) matches(e AuditEvent) bool {
+	return (f.Action == "" || e.Action == f.Action) &&
+		(f.KeyID == "" || e.KeyID == f.KeyID) &&
+		(f.Tenant == "" || e.Tenant == f.Tenant) &&
+		(f.RequestID == "" || e.RequestID == f.RequestID) &&
+		(f.From.IsZero() || !e.Time.Before(f.From)) &&
+		(f.To.IsZero() || e.Time.Before(f.To))
+}
+
+type AuditStore interface {
+	Append(e AuditEvent) error
+	// Query передаёт в fn события в хронологическом порядке, пока fn не вернёт ошибку
+	// или не истечёт ctx.
+	Query(ctx context.Context, filter AuditFilter, fn func(AuditEvent) error) error
+}
+
+type auditStoreImpl struct {
+	mu     sync.RWMutex
+	events []AuditEvent
+}
+
+func NewAuditStore() AuditStore {
+	return &auditStoreImpl{}
+}
+
+func (a *auditStoreImpl) Append(e AuditEvent) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, e)
+	return nil
+}
+
+func (a *auditStoreImpl) Query(ctx context.Context, filter AuditFilter, fn func(AuditEvent) error) error {
+	a.mu.RLock()
+	var matched []AuditEvent
+	for _, e := range a.events {
+		if filter.matches(e) {
+			matched = append(matched, e)
+		}
+	}
+	a.mu.RUnlock()
+
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].Time.Before(matched[j].Time) })
+	for i, e := range matched {
+		if filter.Limit > 0 && i >= filter.Limit {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+type BatchItem struct {
+	User      string `bson:"user,omitempty"`
+	Hash      string `bson:"hash"`
+	MaxLength int    `bson:"maxLength"`
+	RequestID string `bson:"requestId,omitempty"`
+	Error     string `bson:"error,omitempty"`
+}
+
+type Batch struct {
+	ID        string      `bson:"_id"`
+	Tenant    string      `bson:"tenant,omitempty"`
+	CreatedAt time.Time   `bson:"createdAt"`
+	Items     []BatchItem `bson:"items"`
+}
+
+type BatchStore interface {
+	Create(b Batch)
+	Get(id string) (Batch, bool)
+}
+
+type batchStoreImpl struct {
+	mu      sync.RWMutex
+	batches map[string]Batch
+}
+
+func NewBatchStore() BatchStore {
+	return &batchStoreImpl{
+		batches: make(map[string]Batch),
+	}
+}
+
+func (b *batchStoreImpl) Create(batch Batch) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batches[batch.ID] = batch
+}
+
+func (b *batchStoreImpl) Get(id string) (Batch, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	batch, ok := b.batches[id]
+	return batch, ok
+}
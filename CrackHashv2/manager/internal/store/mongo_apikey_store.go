@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"CrackHash/internal/logging"
+)
+
+type MongoAPIKeyStore struct {
+	keys  *mongo.Collection
+	usage *mongo.Collection
+}
+
+type keyspaceUsageDocument struct {
+	ID    string `bson:"_id"`
+	KeyID string `bson:"keyId"`
+	Day   string `bson:"day"`
+	Used  int64  `bson:"used"`
+}
+
+func NewMongoAPIKeyStore(db *mongo.Database) *MongoAPIKeyStore {
+	s := &MongoAPIKeyStore{
+		keys:  db.Collection("api_keys"),
+		usage: db.Collection("api_key_usage"),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := s.keys.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"keyHash": 1},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		logging.Component(component).Error("Ошибка при создании индекса api_keys.keyHash", "error", err)
+	}
+	return s
+}
+
+func (m *MongoAPIKeyStore) Create(k APIKey) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := m.keys.InsertOne(ctx, k); err != nil {
+		logging.Component(component).Error("Ошибка при сохранении API-ключа", "keyId", k.ID, "error", err)
+	}
+}
+
+func (m *MongoAPIKeyStore) Update(k APIKey) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	update := bson.M{
+		"name":          k.Name,
+		"tenant":        k.Tenant,
+		"role":          k.Role,
+		"maxConcurrent": k.MaxConcurrent,
+		"dailyKeyspace": k.DailyKeyspace,
+		"revoked":       k.Revoked,
+	}
+	if _, err := m.keys.UpdateByID(ctx, k.ID, bson.M{"$set": update}); err != nil {
+		logging.Component(component).Error("Ошибка при обновлении API-ключа", "keyId", k.ID, "error", err)
+	}
+}
+
+func (m *MongoAPIKeyStore) Get(id string) (APIKey, bool) {
+	return m.findOne(bson.M{"_id": id})
+}
+
+func (m *MongoAPIKeyStore) GetByHash(keyHash string) (APIKey, bool) {
+	return m.findOne(bson.M{"keyHash": keyHash})
+}
+
+func (m *MongoAPIKeyStore) findOne(filter bson.M) (APIKey, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var k APIKey
+	if err := m.keys.FindOne(ctx, filter).Decode(&k); err != nil {
+		if err != mongo.ErrNoDocuments {
+			logging.Component(component).Error("Ошибка при чтении API-ключа", "error", err)
+		}
+		return APIKey{}, false
+	}
+	return k, true
+}
+
+func (m *MongoAPIKeyStore) List() []APIKey {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	cursor, err := m.keys.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"createdAt": 1}))
+	if err != nil {
+		logging.Component(component).Error("Ошибка при чтении API-ключей", "error", err)
+		return nil
+	}
+	defer cursor.Close(ctx)
+	var result []APIKey
+	if err = cursor.All(ctx, &result); err != nil {
+		logging.Component(component).Error("Ошибка при cursor.All для API-ключей", "error", err)
+		return nil
+	}
+	return result
+}
+
+// ConsumeKeyspace сначала пытается увеличить счётчик с условием на остаток лимита,
+// а если документа за этот день ещё нет — вставляет его. Конкурентная вставка
+// того же дня упирается в _id и повторяется как обновление.
+func (m *MongoAPIKeyStore) ConsumeKeyspace(keyID, day string, amount, limit int64) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	id := keyID + ":" + day
+
+	for attempt := 0; attempt < 2; attempt++ {
+		filter := bson.M{"_id": id}
+		if limit > 0 {
+			filter["used"] = bson.M{"$lte": limit - amount}
+		}
+		res, err := m.usage.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"used": amount}})
+		if err != nil {
+			logging.Component(component).Error("Ошибка при списании keyspace", "keyId", keyID, "error", err)
+			return false
+		}
+		if res.MatchedCount > 0 {
+			return true
+		}
+		if limit > 0 && amount > limit {
+			return false
+		}
+		_, err = m.usage.InsertOne(ctx, keyspaceUsageDocument{ID: id, KeyID: keyID, Day: day, Used: amount})
+		if err == nil {
+			return true
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			logging.Component(component).Error("Ошибка при списании keyspace", "keyId", keyID, "error", err)
+			return false
+		}
+		// Документ уже есть: либо его только что вставил конкурент, либо лимит исчерпан.
+		if limit > 0 && m.KeyspaceUsed(keyID, day)+amount > limit {
+			return false
+		}
+	}
+	return false
+}
+
+func (m *MongoAPIKeyStore) KeyspaceUsed(keyID, day string) int64 {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var doc keyspaceUsageDocument
+	if err := m.usage.FindOne(ctx, bson.M{"_id": keyID + ":" + day}).Decode(&doc); err != nil {
+		if err != mongo.ErrNoDocuments {
+			logging.Component(component).Error("Ошибка при чтении расхода keyspace", "error", err)
+		}
+		return 0
+	}
+	return doc.Used
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"CrackHash/internal/logging"
+)
+
+// MongoAuditStore пишет в коллекцию audit_log только через InsertOne; методов изменения нет.
+type MongoAuditStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoAuditStore(db *mongo.Database) *MongoAuditStore {
+	s := &MongoAuditStore{
+		collection: db.Collection("audit_log"),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := s.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "time", Value: 1}}},
+		{Keys: bson.D{{Key: "requestId", Value: 1}, {Key: "time", Value: 1}}},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "time", Value: 1}}},
+	})
+	if err != nil {
+		logging.Component(component).Error("Ошибка при создании индексов audit_log", "error", err)
+	}
+	return s
+}
+
+func (m *MongoAuditStore) Append(e AuditEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := m.collection.InsertOne(ctx, e)
+	return err
+}
+
+func (m *MongoAuditStore) Query(ctx context.Context, filter AuditFilter, fn func(AuditEvent) error) error {
+	query := bson.M{}
+	for field, value := range map[string]string{
+		"action":    filter.Action,
+		"keyId":     filter.KeyID,
+		"tenant":    filter.Tenant,
+		"requestId": filter.RequestID,
+	} {
+		if value != "" {
+			query[field] = value
+		}
+	}
+	timeRange := bson.M{}
+	if !filter.From.IsZero() {
+		timeRange["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		timeRange["$lt"] = filter.To
+	}
+	if len(timeRange) > 0 {
+		query["time"] = timeRange
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "time", Value: 1}})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	// Срок задаёт вызывающий: выгрузка журнала идёт дольше обычного чтения.
+	cursor, err := m.collection.Find(ctx, query, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.WithoutCancel(ctx))
+	for cursor.Next(ctx) {
+		var e AuditEvent
+		if err := cursor.Decode(&e); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"CrackHash/internal/logging"
+)
+
+type MongoBatchStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoBatchStore(db *mongo.Database) *MongoBatchStore {
+	return &MongoBatchStore{
+		collection: db.Collection("batches"),
+	}
+}
+
+func (m *MongoBatchStore) Create(b Batch) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := m.collection.InsertOne(ctx, b); err != nil {
+		logging.Component(component).Error("Ошибка при сохранении batch", "batchId", b.ID, "error", err)
+	}
+}
+
+func (m *MongoBatchStore) Get(id string) (Batch, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var b Batch
+	if err := m.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&b); err != nil {
+		if err != mongo.ErrNoDocuments {
+			logging.Component(component).Error("Ошибка при чтении batch", "batchId", id, "error", err)
+		}
+		return Batch{}, false
+	}
+	return b, true
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"CrackHash/internal/logging"
+	"CrackHash/manager/internal/envelope"
+)
+
+type MongoPotfileStore struct {
+	collection *mongo.Collection
+	// keyring шифрует найденные слова так же, как data в requests; без него они открыты.
+	keyring *envelope.Keyring
+}
+
+type PotfileDocument struct {
+	ID        string    `bson:"_id"`
+	Algorithm string    `bson:"algorithm"`
+	Hash      string    `bson:"hash"`
+	Plaintext string    `bson:"plaintext,omitempty"`
+	Tenant    string    `bson:"tenant,omitempty"`
+	CreatedAt time.Time `bson:"createdAt"`
+	// EncryptedPlaintext заменяет Plaintext, когда включено шифрование.
+	EncryptedPlaintext *envelope.Sealed `bson:"encPlaintext,omitempty"`
+}
+
+func NewMongoPotfileStore(db *mongo.Database, keyring *envelope.Keyring) *MongoPotfileStore {
+	return &MongoPotfileStore{
+		collection: db.Collection("potfile"),
+		keyring:    keyring,
+	}
+}
+
+// seal переносит Plaintext в EncryptedPlaintext, если шифрование включено.
+func (m *MongoPotfileStore) seal(doc *PotfileDocument) error {
+	if m.keyring == nil || doc.Plaintext == "" {
+		return nil
+	}
+	sealed, err := m.keyring.Seal([]byte(doc.Plaintext), []byte(doc.ID))
+	if err != nil {
+		return err
+	}
+	doc.Plaintext, doc.EncryptedPlaintext = "", sealed
+	return nil
+}
+
+func (m *MongoPotfileStore) plaintext(doc PotfileDocument) (string, error) {
+	if doc.EncryptedPlaintext == nil {
+		return doc.Plaintext, nil
+	}
+	plain, err := openSealed(m.keyring, doc.ID, doc.EncryptedPlaintext)
+	return string(plain), err
+}
+
+func (m *MongoPotfileStore) Lookup(tenant, algorithm, hash string) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ids := bson.A{PotfileKey("", algorithm, hash)}
+	if tenant != "" {
+		ids = append(ids, PotfileKey(tenant, algorithm, hash))
+	}
+	cursor, err := m.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		logging.Component(component).Error("Ошибка при Lookup в potfile", "error", err)
+		return "", false
+	}
+	var docs []PotfileDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		logging.Component(component).Error("Ошибка при Lookup в potfile", "error", err)
+		return "", false
+	}
+	// Запись тенанта важнее общей.
+	found := false
+	var plain string
+	for _, doc := range docs {
+		if found && doc.Tenant == "" {
+			continue
+		}
+		p, err := m.plaintext(doc)
+		if err != nil {
+			logging.Component(component).Error("Ошибка расшифровки записи potfile", "entryId", doc.ID, "error", err)
+			continue
+		}
+		plain, found = p, true
+	}
+	return plain, found
+}
+
+func (m *MongoPotfileStore) Add(entry PotfileEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	doc := PotfileDocument{
+		ID:        PotfileKey(entry.Tenant, entry.Algorithm, entry.Hash),
+		Algorithm: strings.ToLower(entry.Algorithm),
+		Hash:      strings.ToLower(entry.Hash),
+		Plaintext: entry.Plaintext,
+		Tenant:    entry.Tenant,
+		CreatedAt: time.Now(),
+	}
+	if err := m.seal(&doc); err != nil {
+		logging.Component(component).Error("Ошибка шифрования записи potfile", "entryId", doc.ID, "error", err)
+		return
+	}
+	change := bson.M{"$set": doc}
+	if doc.EncryptedPlaintext != nil {
+		change["$unset"] = bson.M{"plaintext": ""}
+	} else {
+		change["$unset"] = bson.M{"encPlaintext": ""}
+	}
+	opts := options.Update().SetUpsert(true)
+	_, err := m.collection.UpdateByID(ctx, doc.ID, change, opts)
+	if err != nil {
+		logging.Component(component).Error("Ошибка при Add в potfile", "error", err)
+	}
+}
+
+func (m *MongoPotfileStore) All(algorithm string) []PotfileEntry {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.M{"hash": 1})
+	cursor, err := m.collection.Find(ctx, bson.M{"algorithm": strings.ToLower(algorithm)}, opts)
+	if err != nil {
+		logging.Component(component).Error("Ошибка при All в potfile", "error", err)
+		return nil
+	}
+	defer cursor.Close(ctx)
+
+	var docs []PotfileDocument
+	if err = cursor.All(ctx, &docs); err != nil {
+		logging.Component(component).Error("Ошибка при cursor.All в potfile", "error", err)
+		return nil
+	}
+
+	result := make([]PotfileEntry, 0, len(docs))
+	for _, d := range docs {
+		plain, err := m.plaintext(d)
+		if err != nil {
+			logging.Component(component).Error("Ошибка расшифровки записи potfile", "entryId", d.ID, "error", err)
+			continue
+		}
+		result = append(result, PotfileEntry{
+			Algorithm: d.Algorithm,
+			Hash:      d.Hash,
+			Plaintext: plain,
+			Tenant:    d.Tenant,
+		})
+	}
+	return result
+}
+
+// Reencrypt перешифровывает активным мастер-ключом записи, зашифрованные другим ключом
+// или записанные открыто. Возвращает число изменённых документов.
+func (m *MongoPotfileStore) Reencrypt(ctx context.Context) (int, error) {
+	if m.keyring == nil {
+		return 0, errors.New("не задан файл ключей")
+	}
+	cursor, err := m.collection.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"encPlaintext.keyId": bson.M{"$ne": m.keyring.ActiveKeyID(), "$exists": true}},
+		bson.M{"plaintext": bson.M{"$exists": true}},
+	}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var doc PotfileDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return updated, err
+		}
+		plain, err := m.plaintext(doc)
+		if err != nil {
+			return updated, fmt.Errorf("запись potfile %s: %w", doc.ID, err)
+		}
+		doc.Plaintext, doc.EncryptedPlaintext = plain, nil
+		if err := m.seal(&doc); err != nil {
+			return updated, fmt.Errorf("запись potfile %s: %w", doc.ID, err)
+		}
+		_, err = m.collection.UpdateByID(ctx, doc.ID, bson.M{
+			"$set":   bson.M{"encPlaintext": doc.EncryptedPlaintext},
+			"$unset": bson.M{"plaintext": ""},
+		})
+		if err != nil {
+			return updated, fmt.Errorf("запись potfile %s: %w", doc.ID, err)
+		}
+		updated++
+	}
+	return updated, cursor.Err()
+}
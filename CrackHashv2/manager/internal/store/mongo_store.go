@@ -2,37 +2,75 @@ package store
 
 import (
 	"context"
-	"log"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
+	"CrackHash/internal/logging"
+	"CrackHash/internal/tlsconfig"
 	"CrackHash/manager/internal/config"
+	"CrackHash/manager/internal/envelope"
+	"CrackHash/manager/internal/metrics"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
+// component — имя компонента в логах хранилищ MongoDB.
+const component = "mongoStore"
+
 type MongoRequestStore struct {
 	client     *mongo.Client
 	collection *mongo.Collection
+	// keyring включает шифрование найденных слов; без него data хранится открыто.
+	keyring *envelope.Keyring
+}
+
+type MongoOption func(*MongoRequestStore)
+
+// WithKeyring шифрует поле data каждого запроса собственным ключом данных.
+func WithKeyring(k *envelope.Keyring) MongoOption {
+	return func(m *MongoRequestStore) {
+		m.keyring = k
+	}
 }
 
 type RequestDocument struct {
-	ID        string   `bson:"_id"`
-	Status    string   `bson:"status"`
-	Data      []string `bson:"data"`
-	StartTime time.Time
-	Timeout   time.Duration
-	Pending   bool
-	Hash      string
-	MaxLength int
+	ID     string   `bson:"_id"`
+	Status string   `bson:"status"`
+	Data   []string `bson:"data"`
+	// EncryptedData заменяет Data, когда включено шифрование.
+	EncryptedData *envelope.Sealed `bson:"encData,omitempty"`
+	StartTime     time.Time
+	Timeout       time.Duration
+	Pending       bool
+	Hash          string
+	MaxLength     int
+	Algorithm     string `bson:"algorithm"`
+	Fingerprint   string `bson:"fingerprint,omitempty"`
+	JobID         string `bson:"jobId,omitempty"`
+	CallbackURL   string `bson:"callbackUrl,omitempty"`
+	Owner         string `bson:"owner,omitempty"`
+	Tenant        string `bson:"tenant,omitempty"`
 }
 
-func NewMongoRequestStore(cfg *config.Config) (*MongoRequestStore, error) {
+func NewMongoRequestStore(cfg *config.Config, opts ...MongoOption) (*MongoRequestStore, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	clientOpts := options.Client().ApplyURI(cfg.MongoURI).SetMonitor(otelmongo.NewMonitor())
+	if files := (tlsconfig.Files{CAFile: cfg.MongoCAFile, CertFile: cfg.MongoCertFile, KeyFile: cfg.MongoKeyFile}); files.Enabled() {
+		tlsCfg, err := tlsconfig.Client(files)
+		if err != nil {
+			return nil, err
+		}
+		clientOpts.SetTLSConfig(tlsCfg)
+	}
+	client, err := mongo.Connect(ctx, clientOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -45,30 +83,80 @@ func NewMongoRequestStore(cfg *config.Config) (*MongoRequestStore, error) {
 		client:     client,
 		collection: client.Database(cfg.MongoDatabase).Collection("requests"),
 	}
+	for _, opt := range opts {
+		opt(store)
+	}
+	// Индексы под выборки по состоянию: FindActiveByFingerprint, ListByJob, GetPending,
+	// CountActive и CountActiveByOwner.
+	_, err = store.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "fingerprint", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "jobId", Value: 1}}},
+		{Keys: bson.D{{Key: "pending", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "owner", Value: 1}}},
+	})
+	if err != nil {
+		logging.Component(component).Error("Ошибка при создании индексов requests", "error", err)
+	}
 	return store, nil
 }
 
-func (m *MongoRequestStore) Set(id string, state RequestState) {
+// Ping проверяет, что репликасет доступен и выбран primary.
+func (m *MongoRequestStore) Ping(ctx context.Context) error {
+	return m.client.Ping(ctx, readpref.Primary())
+}
+
+// Close отключает клиент MongoDB; после него хранилище использовать нельзя.
+func (m *MongoRequestStore) Close(ctx context.Context) error {
+	return m.client.Disconnect(ctx)
+}
+
+func (m *MongoRequestStore) Database() *mongo.Database {
+	return m.collection.Database()
+}
+
+func (m *MongoRequestStore) Set(ctx context.Context, id string, state RequestState) {
+	defer metrics.ObserveStore("set", time.Now())
 	doc := RequestDocument{
-		ID:        id,
-		Status:    state.Status,
-		Data:      state.Data,
-		StartTime: state.StartTime,
-		Timeout:   state.Timeout,
-		Pending:   false,
-		Hash:      "",
-		MaxLength: 0,
+		ID:          id,
+		Status:      state.Status,
+		Data:        state.Data,
+		StartTime:   state.StartTime,
+		Timeout:     state.Timeout,
+		Pending:     state.Pending,
+		Hash:        state.Hash,
+		MaxLength:   state.MaxLength,
+		Algorithm:   state.Algorithm,
+		Fingerprint: state.Fingerprint,
+		JobID:       state.JobID,
+		CallbackURL: state.CallbackURL,
+		Owner:       state.Owner,
+		Tenant:      state.Tenant,
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := m.sealData(&doc); err != nil {
+		logging.Request(component, id).Error("Ошибка шифрования данных", "error", err)
+		return
+	}
+	ctx, cancel := writeContext(ctx)
 	defer cancel()
 	opts := options.Update().SetUpsert(true)
-	_, err := m.collection.UpdateByID(ctx, id, bson.M{"$set": doc}, opts)
+	change := bson.M{"$set": doc}
+	if doc.EncryptedData == nil {
+		change["$unset"] = bson.M{"encData": ""}
+	}
+	_, err := m.collection.UpdateByID(ctx, id, change, opts)
 	if err != nil {
-		log.Printf("Ошибка при сохранении Set: %v", err)
+		logging.Request(component, id).Error("Ошибка при сохранении Set", "error", err)
 	}
 }
 
+// writeContext берёт из ctx трассу, но не отмену: запись, начатая HTTP-запросом,
+// должна завершиться и после того, как клиент получил ответ.
+func writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+}
+
 func (m *MongoRequestStore) Get(id string) (RequestState, bool) {
+	defer metrics.ObserveStore("get", time.Now())
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	var doc RequestDocument
@@ -76,65 +164,201 @@ func (m *MongoRequestStore) Get(id string) (RequestState, bool) {
 	if err != nil {
 		return RequestState{}, false
 	}
+	return m.documentToState(doc), true
+}
+
+// sealData переносит doc.Data в doc.EncryptedData, если шифрование включено.
+// ID документа входит в AAD, так что шифртекст нельзя подставить в чужой запрос.
+func (m *MongoRequestStore) sealData(doc *RequestDocument) error {
+	if m.keyring == nil || len(doc.Data) == 0 {
+		return nil
+	}
+	plaintext, err := json.Marshal(doc.Data)
+	if err != nil {
+		return err
+	}
+	sealed, err := m.keyring.Seal(plaintext, []byte(doc.ID))
+	if err != nil {
+		return err
+	}
+	doc.Data, doc.EncryptedData = nil, sealed
+	return nil
+}
+
+// openData возвращает найденные слова документа, расшифровывая их при необходимости.
+// Документы, записанные до включения шифрования, читаются как есть.
+func (m *MongoRequestStore) openData(doc RequestDocument) ([]string, error) {
+	if doc.EncryptedData == nil {
+		return doc.Data, nil
+	}
+	plaintext, err := openSealed(m.keyring, doc.ID, doc.EncryptedData)
+	if err != nil {
+		return nil, err
+	}
+	var data []string
+	if err := json.Unmarshal(plaintext, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// openSealed расшифровывает поле документа id. Так же, как в requests, в potfile и
+// webhook_deliveries ID документа входит в AAD.
+func openSealed(keyring *envelope.Keyring, id string, sealed *envelope.Sealed) ([]byte, error) {
+	if keyring == nil {
+		return nil, errors.New("данные зашифрованы, но ENCRYPTION_KEY_FILE не задан")
+	}
+	return keyring.Open(sealed, []byte(id))
+}
+
+// documentToState собирает состояние запроса из документа. Если слова не расшифровались
+// (например, в файле нет ключа, которым они зашифрованы), состояние помечается
+// DataUnreadable и Update его не запишет.
+func (m *MongoRequestStore) documentToState(doc RequestDocument) RequestState {
+	data, err := m.openData(doc)
+	if err != nil {
+		logging.Request(component, doc.ID).Error("Ошибка расшифровки данных", "error", err)
+	}
 	state := RequestState{
-		Status:    doc.Status,
-		Data:      doc.Data,
-		StartTime: doc.StartTime,
-		Timeout:   doc.Timeout,
-		Timer:     nil,
+		Status:      doc.Status,
+		Data:        data,
+		StartTime:   doc.StartTime,
+		Timeout:     doc.Timeout,
+		Timer:       nil,
+		Pending:     doc.Pending,
+		Hash:        doc.Hash,
+		MaxLength:   doc.MaxLength,
+		Algorithm:   doc.Algorithm,
+		Fingerprint: doc.Fingerprint,
+		JobID:       doc.JobID,
+		CallbackURL: doc.CallbackURL,
+		Owner:       doc.Owner,
+		Tenant:      doc.Tenant,
 	}
-	return state, true
+	state.DataUnreadable = err != nil
+	return state
 }
 
-func (m *MongoRequestStore) Update(id string, state RequestState) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (m *MongoRequestStore) Update(ctx context.Context, id string, state RequestState) {
+	defer metrics.ObserveStore("update", time.Now())
+	if state.DataUnreadable {
+		logging.Request(component, id).Error("Update отклонён: найденные слова не расшифрованы, запись уничтожила бы шифртекст")
+		return
+	}
+	ctx, cancel := writeContext(ctx)
 	defer cancel()
+	doc := RequestDocument{ID: id, Data: state.Data}
+	if err := m.sealData(&doc); err != nil {
+		logging.Request(component, id).Error("Ошибка шифрования данных", "error", err)
+		return
+	}
 	update := bson.M{
 		"status":    state.Status,
-		"data":      state.Data,
+		"data":      doc.Data,
 		"starttime": state.StartTime,
 		"timeout":   state.Timeout,
 	}
-	_, err := m.collection.UpdateByID(ctx, id, bson.M{"$set": update})
+	change := bson.M{"$set": update}
+	if doc.EncryptedData != nil {
+		update["encData"] = doc.EncryptedData
+	} else {
+		change["$unset"] = bson.M{"encData": ""}
+	}
+	_, err := m.collection.UpdateByID(ctx, id, change)
 	if err != nil {
-		log.Printf("Ошибка при Update: %v", err)
+		logging.Request(component, id).Error("Ошибка при Update", "error", err)
 	}
 }
 
 func (m *MongoRequestStore) Count() int {
+	defer metrics.ObserveStore("count", time.Now())
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	count, err := m.collection.CountDocuments(ctx, bson.M{})
 	if err != nil {
-		log.Printf("Ошибка при Count: %v", err)
+		logging.Component(component).Error("Ошибка при Count", "error", err)
+		return 0
+	}
+	return int(count)
+}
+
+func (m *MongoRequestStore) CountActive() int {
+	defer metrics.ObserveStore("count_active", time.Now())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	count, err := m.collection.CountDocuments(ctx, bson.M{"status": "IN_PROGRESS"})
+	if err != nil {
+		logging.Component(component).Error("Ошибка при CountActive", "error", err)
+		return 0
+	}
+	return int(count)
+}
+
+func (m *MongoRequestStore) CountActiveByOwner(owner string) int {
+	defer metrics.ObserveStore("count_active_by_owner", time.Now())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	count, err := m.collection.CountDocuments(ctx, bson.M{"status": "IN_PROGRESS", "owner": owner})
+	if err != nil {
+		logging.Component(component).Error("Ошибка при CountActiveByOwner", "error", err)
 		return 0
 	}
 	return int(count)
 }
 
-func (m *MongoRequestStore) MarkPending(id string, isPending bool) {
+func (m *MongoRequestStore) CountByStatus() map[string]int {
+	defer metrics.ObserveStore("count_by_status", time.Now())
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	cursor, err := m.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		logging.Component(component).Error("Ошибка при CountByStatus", "error", err)
+		return nil
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Status string `bson:"_id"`
+		Count  int    `bson:"count"`
+	}
+	if err = cursor.All(ctx, &groups); err != nil {
+		logging.Component(component).Error("Ошибка при cursor.All в CountByStatus", "error", err)
+		return nil
+	}
+	result := make(map[string]int, len(groups))
+	for _, g := range groups {
+		result[g.Status] = g.Count
+	}
+	return result
+}
+
+func (m *MongoRequestStore) MarkPending(ctx context.Context, id string, isPending bool) {
+	defer metrics.ObserveStore("mark_pending", time.Now())
+	ctx, cancel := writeContext(ctx)
+	defer cancel()
 	_, err := m.collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"pending": isPending}})
 	if err != nil {
-		log.Printf("Ошибка при MarkPending(%s): %v", id, err)
+		logging.Request(component, id).Error("Ошибка при MarkPending", "error", err)
 	}
 }
 
 func (m *MongoRequestStore) GetPending() []PendingTask {
+	defer metrics.ObserveStore("get_pending", time.Now())
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	cursor, err := m.collection.Find(ctx, bson.M{"pending": true})
 	if err != nil {
-		log.Printf("Ошибка при GetPending: %v", err)
+		logging.Component(component).Error("Ошибка при GetPending", "error", err)
 		return nil
 	}
 	defer cursor.Close(ctx)
 
 	var docs []RequestDocument
 	if err = cursor.All(ctx, &docs); err != nil {
-		log.Printf("Ошибка при cursor.All в GetPending: %v", err)
+		logging.Component(component).Error("Ошибка при cursor.All в GetPending", "error", err)
 		return nil
 	}
 
@@ -149,3 +373,128 @@ func (m *MongoRequestStore) GetPending() []PendingTask {
 	}
 	return result
 }
+
+func (m *MongoRequestStore) FindActiveByFingerprint(tenant, fingerprint string) (string, RequestState, bool) {
+	defer metrics.ObserveStore("find_active_by_fingerprint", time.Now())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var doc RequestDocument
+	// Пустой тенант не сохраняется (omitempty), а запрос с nil находит документы без поля.
+	var tenantQuery any = tenant
+	if tenant == "" {
+		tenantQuery = nil
+	}
+	err := m.collection.FindOne(ctx, bson.M{"fingerprint": fingerprint, "tenant": tenantQuery, "status": "IN_PROGRESS"}).Decode(&doc)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			logging.Component(component).Error("Ошибка при FindActiveByFingerprint", "error", err)
+		}
+		return "", RequestState{}, false
+	}
+	return doc.ID, m.documentToState(doc), true
+}
+
+func (m *MongoRequestStore) ListByJob(jobID string) []string {
+	defer metrics.ObserveStore("list_by_job", time.Now())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := m.collection.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"_id": jobID},
+		bson.M{"jobId": jobID},
+	}}, opts)
+	if err != nil {
+		logging.Component(component).Error("Ошибка при ListByJob", "jobId", jobID, "error", err)
+		return nil
+	}
+	defer cursor.Close(ctx)
+
+	var docs []RequestDocument
+	if err = cursor.All(ctx, &docs); err != nil {
+		logging.Component(component).Error("Ошибка при cursor.All в ListByJob", "error", err)
+		return nil
+	}
+	result := make([]string, 0, len(docs))
+	for _, d := range docs {
+		result = append(result, d.ID)
+	}
+	return result
+}
+
+func (m *MongoRequestStore) List(filter RequestFilter) []StoredRequest {
+	defer metrics.ObserveStore("list", time.Now())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query := bson.M{}
+	if filter.Tenant != "" {
+		query["tenant"] = filter.Tenant
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	opts := options.Find().SetSort(bson.M{"starttime": -1})
+	if filter.Limit > 0 {
+		opts.SetLimit(int64(filter.Limit))
+	}
+	cursor, err := m.collection.Find(ctx, query, opts)
+	if err != nil {
+		logging.Component(component).Error("Ошибка при List", "error", err)
+		return nil
+	}
+	defer cursor.Close(ctx)
+
+	var docs []RequestDocument
+	if err = cursor.All(ctx, &docs); err != nil {
+		logging.Component(component).Error("Ошибка при cursor.All в List", "error", err)
+		return nil
+	}
+	result := make([]StoredRequest, 0, len(docs))
+	for _, doc := range docs {
+		result = append(result, StoredRequest{ID: doc.ID, State: m.documentToState(doc)})
+	}
+	return result
+}
+
+// Reencrypt перешифровывает активным мастер-ключом все документы с найденными словами:
+// зашифрованные другим ключом и ещё не зашифрованные. Возвращает число изменённых документов.
+func (m *MongoRequestStore) Reencrypt(ctx context.Context) (int, error) {
+	if m.keyring == nil {
+		return 0, errors.New("не задан файл ключей")
+	}
+	cursor, err := m.collection.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"encData.keyId": bson.M{"$ne": m.keyring.ActiveKeyID(), "$exists": true}},
+		bson.M{"data.0": bson.M{"$exists": true}},
+	}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var doc RequestDocument
+		if err := cursor.Decode(&doc); err != nil {
+			return updated, err
+		}
+		data, err := m.openData(doc)
+		if err != nil {
+			return updated, fmt.Errorf("документ %s: %w", doc.ID, err)
+		}
+		doc.Data, doc.EncryptedData = data, nil
+		if err := m.sealData(&doc); err != nil {
+			return updated, fmt.Errorf("документ %s: %w", doc.ID, err)
+		}
+		_, err = m.collection.UpdateByID(ctx, doc.ID, bson.M{"$set": bson.M{
+			"data":    nil,
+			"encData": doc.EncryptedData,
+		}})
+		if err != nil {
+			return updated, fmt.Errorf("документ %s: %w", doc.ID, err)
+		}
+		updated++
+	}
+	return updated, cursor.Err()
+}
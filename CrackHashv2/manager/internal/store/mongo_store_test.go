@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/internal/tlsconfig/tlstest"
+	"CrackHash/manager/internal/config"
+	"CrackHash/manager/internal/envelope"
+)
+
+func TestMongoRequestStore_DataEncryption(t *testing.T) {
+	line, err := envelope.GenerateKeyLine("k1")
+	require.NoError(t, err)
+	keyring, err := envelope.ParseKeyring(strings.NewReader(line))
+	require.NoError(t, err)
+	m := &MongoRequestStore{keyring: keyring}
+
+	doc := RequestDocument{ID: "req-1", Data: []string{"abc", "abd"}}
+	require.NoError(t, m.sealData(&doc))
+	require.Nil(t, doc.Data)
+	require.Equal(t, "k1", doc.EncryptedData.KeyID)
+	require.Equal(t, []string{"abc", "abd"}, m.documentToState(doc).Data)
+
+	// Шифртекст привязан к документу: подстановка в другой запрос не расшифруется.
+	doc.ID = "req-2"
+	_, err = m.openData(doc)
+	require.Error(t, err)
+
+	legacy := RequestDocument{ID: "req-3", Data: []string{"old"}}
+	require.Equal(t, []string{"old"}, m.documentToState(legacy).Data)
+
+	plain := &MongoRequestStore{}
+	_, err = plain.openData(RequestDocument{ID: "req-1", EncryptedData: doc.EncryptedData})
+	require.Error(t, err, "без ключей зашифрованные данные не читаются")
+}
+
+func TestMongoRequestStore_MissingKeyKeepsCiphertext(t *testing.T) {
+	oldLine, err := envelope.GenerateKeyLine("k1")
+	require.NoError(t, err)
+	oldKeyring, err := envelope.ParseKeyring(strings.NewReader(oldLine))
+	require.NoError(t, err)
+	doc := RequestDocument{ID: "req-1", Status: "IN_PROGRESS", Data: []string{"abc"}}
+	require.NoError(t, (&MongoRequestStore{keyring: oldKeyring}).sealData(&doc))
+
+	// В файле ключей остался только новый ключ: слова, зашифрованные k1, не читаются.
+	newLine, err := envelope.GenerateKeyLine("k2")
+	require.NoError(t, err)
+	newKeyring, err := envelope.ParseKeyring(strings.NewReader(newLine))
+	require.NoError(t, err)
+	m := &MongoRequestStore{keyring: newKeyring}
+
+	state := m.documentToState(doc)
+	require.True(t, state.DataUnreadable)
+	require.Nil(t, state.Data)
+
+	// Коллекция не задана: если бы Update попытался записать состояние, тест упал бы.
+	state.Status = "ERROR"
+	require.NotPanics(t, func() { m.Update(context.Background(), doc.ID, state) })
+}
+
+func TestMongoPotfileStore_PlaintextEncryption(t *testing.T) {
+	line, err := envelope.GenerateKeyLine("k1")
+	require.NoError(t, err)
+	keyring, err := envelope.ParseKeyring(strings.NewReader(line))
+	require.NoError(t, err)
+	m := &MongoPotfileStore{keyring: keyring}
+
+	doc := PotfileDocument{ID: PotfileKey("", "md5", "900150983cd24fb0d6963f7d28e17f72"), Plaintext: "abc"}
+	require.NoError(t, m.seal(&doc))
+	require.Empty(t, doc.Plaintext)
+	require.Equal(t, "k1", doc.EncryptedPlaintext.KeyID)
+	plain, err := m.plaintext(doc)
+	require.NoError(t, err)
+	require.Equal(t, "abc", plain)
+
+	doc.ID = PotfileKey("", "md5", "0cc175b9c0f1b6a831c399e269772661")
+	_, err = m.plaintext(doc)
+	require.Error(t, err, "запись не переносится под чужой хэш")
+
+	plain, err = m.plaintext(PotfileDocument{ID: "md5:x", Plaintext: "old"})
+	require.NoError(t, err)
+	require.Equal(t, "old", plain)
+}
+
+func TestMongoWebhookStore_PayloadEncryption(t *testing.T) {
+	line, err := envelope.GenerateKeyLine("k1")
+	require.NoError(t, err)
+	keyring, err := envelope.ParseKeyring(strings.NewReader(line))
+	require.NoError(t, err)
+	m := &MongoWebhookStore{keyring: keyring}
+
+	d := WebhookDelivery{ID: "d-1", Payload: []byte(`{"data":["abc"]}`)}
+	require.NoError(t, m.seal(&d))
+	require.Nil(t, d.Payload)
+	require.Equal(t, "k1", d.EncryptedPayload.KeyID)
+	opened, err := m.open(d)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"data":["abc"]}`, string(opened.Payload))
+
+	d.ID = "d-2"
+	_, err = m.open(d)
+	require.Error(t, err)
+
+	_, err = (&MongoWebhookStore{}).open(WebhookDelivery{ID: "d-1", EncryptedPayload: d.EncryptedPayload})
+	require.Error(t, err, "без ключей зашифрованное тело не читается")
+}
+
+func TestNewMongoRequestStore_TLSClientCertificate(t *testing.T) {
+	certs := tlstest.Generate(t)
+	ln := tlstest.Listen(t, certs)
+
+	cfg := &config.Config{
+		MongoURI:      "mongodb://" + ln.Addr().String() + "/?directConnection=true&serverSelectionTimeoutMS=500",
+		MongoDatabase: "crackhash",
+		MongoCAFile:   certs.CAFile,
+		MongoCertFile: certs.Client.CertFile,
+		MongoKeyFile:  certs.Client.KeyFile,
+	}
+	// Слушатель не отвечает как MongoDB, поэтому Ping завершится ошибкой после рукопожатия.
+	_, err := NewMongoRequestStore(cfg)
+	require.Error(t, err)
+	select {
+	case name := <-ln.Clients:
+		require.Equal(t, "client", name)
+	case <-time.After(5 * time.Second):
+		t.Fatal("клиент MongoDB не предъявил сертификат")
+	}
+}
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"CrackHash/internal/logging"
+	"CrackHash/manager/internal/envelope"
+)
+
+type MongoWebhookStore struct {
+	collection *mongo.Collection
+	// keyring шифрует тело webhook так же, как data в requests; без него оно открыто.
+	keyring *envelope.Keyring
+}
+
+func NewMongoWebhookStore(db *mongo.Database, keyring *envelope.Keyring) *MongoWebhookStore {
+	return &MongoWebhookStore{
+		collection: db.Collection("webhook_deliveries"),
+		keyring:    keyring,
+	}
+}
+
+// seal переносит Payload в EncryptedPayload, если шифрование включено.
+func (m *MongoWebhookStore) seal(d *WebhookDelivery) error {
+	if m.keyring == nil || len(d.Payload) == 0 {
+		return nil
+	}
+	sealed, err := m.keyring.Seal(d.Payload, []byte(d.ID))
+	if err != nil {
+		return err
+	}
+	d.Payload, d.EncryptedPayload = nil, sealed
+	return nil
+}
+
+// open возвращает доставку с расшифрованным Payload.
+func (m *MongoWebhookStore) open(d WebhookDelivery) (WebhookDelivery, error) {
+	if d.EncryptedPayload == nil {
+		return d, nil
+	}
+	payload, err := openSealed(m.keyring, d.ID, d.EncryptedPayload)
+	if err != nil {
+		return d, err
+	}
+	d.Payload, d.EncryptedPayload = payload, nil
+	return d, nil
+}
+
+func (m *MongoWebhookStore) Create(d WebhookDelivery) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.seal(&d); err != nil {
+		logging.Component(component).Error("Ошибка шифрования webhook-доставки", "deliveryId", d.ID, "error", err)
+		return
+	}
+	if _, err := m.collection.InsertOne(ctx, d); err != nil {
+		logging.Component(component).Error("Ошибка при сохранении webhook-доставки", "deliveryId", d.ID, "error", err)
+	}
+}
+
+func (m *MongoWebhookStore) Update(d WebhookDelivery) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	update := bson.M{
+		"state":       d.State,
+		"attempts":    d.Attempts,
+		"nextAttempt": d.NextAttempt,
+	}
+	if _, err := m.collection.UpdateByID(ctx, d.ID, bson.M{"$set": update}); err != nil {
+		logging.Component(component).Error("Ошибка при обновлении webhook-доставки", "deliveryId", d.ID, "error", err)
+	}
+}
+
+func (m *MongoWebhookStore) Due(now time.Time) []WebhookDelivery {
+	filter := bson.M{"state": DeliveryPending, "nextAttempt": bson.M{"$lte": now}}
+	return m.find(filter, options.Find().SetSort(bson.M{"nextAttempt": 1}).SetLimit(100))
+}
+
+func (m *MongoWebhookStore) Claim(id string, now time.Time, lease time.Duration) (WebhookDelivery, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	filter := bson.M{"_id": id, "state": DeliveryPending, "nextAttempt": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"nextAttempt": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var d WebhookDelivery
+	if err := m.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&d); err != nil {
+		if err != mongo.ErrNoDocuments {
+			logging.Component(component).Error("Ошибка при захвате webhook-доставки", "deliveryId", id, "error", err)
+		}
+		return WebhookDelivery{}, false
+	}
+	d, err := m.open(d)
+	if err != nil {
+		logging.Component(component).Error("Ошибка расшифровки webhook-доставки", "deliveryId", id, "error", err)
+		return WebhookDelivery{}, false
+	}
+	return d, true
+}
+
+func (m *MongoWebhookStore) ListByRequest(requestID string) []WebhookDelivery {
+	return m.find(bson.M{"requestId": requestID}, options.Find().SetSort(bson.M{"createdAt": 1}))
+}
+
+func (m *MongoWebhookStore) find(filter bson.M, opts *options.FindOptions) []WebhookDelivery {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := m.collection.Find(ctx, filter, opts)
+	if err != nil {
+		logging.Component(component).Error("Ошибка при чтении webhook-доставок", "error", err)
+		return nil
+	}
+	defer cursor.Close(ctx)
+
+	var result []WebhookDelivery
+	if err = cursor.All(ctx, &result); err != nil {
+		logging.Component(component).Error("Ошибка при cursor.All для webhook-доставок", "error", err)
+		return nil
+	}
+	for i, d := range result {
+		if result[i], err = m.open(d); err != nil {
+			logging.Component(component).Error("Ошибка расшифровки webhook-доставки", "deliveryId", d.ID, "error", err)
+		}
+	}
+	return result
+}
+
+// Reencrypt перешифровывает активным мастер-ключом тела доставок, зашифрованные другим
+// ключом или записанные открыто. Возвращает число изменённых документов.
+func (m *MongoWebhookStore) Reencrypt(ctx context.Context) (int, error) {
+	if m.keyring == nil {
+		return 0, errors.New("не задан файл ключей")
+	}
+	cursor, err := m.collection.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"encPayload.keyId": bson.M{"$ne": m.keyring.ActiveKeyID(), "$exists": true}},
+		bson.M{"payload": bson.M{"$type": "binData"}},
+	}})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	updated := 0
+	for cursor.Next(ctx) {
+		var d WebhookDelivery
+		if err := cursor.Decode(&d); err != nil {
+			return updated, err
+		}
+		if d, err = m.open(d); err != nil {
+			return updated, fmt.Errorf("webhook-доставка %s: %w", d.ID, err)
+		}
+		if err := m.seal(&d); err != nil {
+			return updated, fmt.Errorf("webhook-доставка %s: %w", d.ID, err)
+		}
+		_, err = m.collection.UpdateByID(ctx, d.ID, bson.M{
+			"$set":   bson.M{"encPayload": d.EncryptedPayload},
+			"$unset": bson.M{"payload": ""},
+		})
+		if err != nil {
+			return updated, fmt.Errorf("webhook-доставка %s: %w", d.ID, err)
+		}
+		updated++
+	}
+	return updated, cursor.Err()
+}
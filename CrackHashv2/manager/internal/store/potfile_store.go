@@ -0,0 +1,75 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PotfileEntry с пустым Tenant — общая запись (загружена администратором), иначе — хэш,
+// взломанный задачей этого тенанта: другим тенантам она не видна.
+type PotfileEntry struct {
+	Algorithm string
+	Hash      string
+	Plaintext string
+	Tenant    string
+}
+
+type PotfileStore interface {
+	// Lookup ищет запись тенанта, затем общую.
+	Lookup(tenant, algorithm, hash string) (string, bool)
+	Add(entry PotfileEntry)
+	// All возвращает записи всех тенантов.
+	All(algorithm string) []PotfileEntry
+}
+
+func PotfileKey(tenant, algorithm, hash string) string {
+	key := strings.ToLower(algorithm) + ":" + strings.ToLower(hash)
+	if tenant != "" {
+		key = tenant + "/" + key
+	}
+	return key
+}
+
+type potfileStoreImpl struct {
+	mu      sync.RWMutex
+	entries map[string]PotfileEntry
+}
+
+func NewPotfileStore() PotfileStore {
+	return &potfileStoreImpl{
+		entries: make(map[string]PotfileEntry),
+	}
+}
+
+func (p *potfileStoreImpl) Lookup(tenant, algorithm, hash string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	e, ok := p.entries[PotfileKey(tenant, algorithm, hash)]
+	if !ok && tenant != "" {
+		e, ok = p.entries[PotfileKey("", algorithm, hash)]
+	}
+	return e.Plaintext, ok
+}
+
+func (p *potfileStoreImpl) Add(entry PotfileEntry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry.Algorithm = strings.ToLower(entry.Algorithm)
+	entry.Hash = strings.ToLower(entry.Hash)
+	p.entries[PotfileKey(entry.Tenant, entry.Algorithm, entry.Hash)] = entry
+}
+
+func (p *potfileStoreImpl) All(algorithm string) []PotfileEntry {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	algorithm = strings.ToLower(algorithm)
+	var result []PotfileEntry
+	for _, e := range p.entries {
+		if e.Algorithm == algorithm {
+			result = append(result, e)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Hash < result[j].Hash })
+	return result
+}
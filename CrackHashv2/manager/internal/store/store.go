@@ -1,6 +1,8 @@
 package store
 
 import (
+	"context"
+	"sort"
 	"sync"
 	"time"
 )
@@ -14,6 +16,30 @@ type RequestState struct {
 	Pending   bool
 	Hash      string
 	MaxLength int
+	Algorithm string
+	// Fingerprint описывает пространство перебора, JobID — запрос, чья задача реально выполняется.
+	Fingerprint string
+	JobID       string
+	CallbackURL string
+	// Owner — ID API-ключа, от имени которого создан запрос, Tenant — команда владельца ключа.
+	Owner  string
+	Tenant string
+	// DataUnreadable означает, что найденные слова не удалось расшифровать: Data пуст,
+	// и такое состояние нельзя записывать обратно, иначе шифртекст будет потерян.
+	DataUnreadable bool
+}
+
+// StoredRequest — запрос вместе с его ID для выборок списком.
+type StoredRequest struct {
+	ID    string
+	State RequestState
+}
+
+// RequestFilter ограничивает List; пустые поля не фильтруют.
+type RequestFilter struct {
+	Tenant string
+	Status string
+	Limit  int
 }
 
 type PendingTask struct {
@@ -25,11 +51,21 @@ type PendingTask struct {
 
 type RequestStore interface {
 	Get(id string) (RequestState, bool)
-	Update(id string, state RequestState)
-	Set(id string, state RequestState)
+	// Операции записи принимают контекст вызывающего, чтобы запросы к MongoDB попадали в его трассу.
+	Update(ctx context.Context, id string, state RequestState)
+	Set(ctx context.Context, id string, state RequestState)
 	Count() int
-	MarkPending(id string, isPending bool)
+	CountActive() int
+	CountActiveByOwner(owner string) int
+	CountByStatus() map[string]int
+	MarkPending(ctx context.Context, id string, isPending bool)
 	GetPending() []PendingTask
+	// FindActiveByFingerprint ищет выполняющуюся задачу тенанта: чужие задачи не находятся,
+	// чтобы по ответу нельзя было узнать, что хэш перебирает другой тенант.
+	FindActiveByFingerprint(tenant, fingerprint string) (string, RequestState, bool)
+	ListByJob(jobID string) []string
+	// List возвращает запросы от новых к старым.
+	List(filter RequestFilter) []StoredRequest
 }
 
 type requestStoreImpl struct {
@@ -43,7 +79,7 @@ func NewRequestStore() RequestStore {
 	}
 }
 
-func (r *requestStoreImpl) Set(id string, state RequestState) {
+func (r *requestStoreImpl) Set(_ context.Context, id string, state RequestState) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.store[id] = state
@@ -56,7 +92,7 @@ func (r *requestStoreImpl) Get(id string) (RequestState, bool) {
 	return s, ok
 }
 
-func (r *requestStoreImpl) Update(id string, state RequestState) {
+func (r *requestStoreImpl) Update(_ context.Context, id string, state RequestState) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.store[id] = state
@@ -68,7 +104,41 @@ func (r *requestStoreImpl) Count() int {
 	return len(r.store)
 }
 
-func (r *requestStoreImpl) MarkPending(id string, isPending bool) {
+func (r *requestStoreImpl) CountActive() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	count := 0
+	for _, s := range r.store {
+		if s.Status == "IN_PROGRESS" {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *requestStoreImpl) CountActiveByOwner(owner string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	count := 0
+	for _, s := range r.store {
+		if s.Owner == owner && s.Status == "IN_PROGRESS" {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *requestStoreImpl) CountByStatus() map[string]int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make(map[string]int)
+	for _, s := range r.store {
+		result[s.Status]++
+	}
+	return result
+}
+
+func (r *requestStoreImpl) MarkPending(_ context.Context, id string, isPending bool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
@@ -97,3 +167,49 @@ func (r *requestStoreImpl) GetPending() []PendingTask {
 	}
 	return result
 }
+
+func (r *requestStoreImpl) FindActiveByFingerprint(tenant, fingerprint string) (string, RequestState, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for id, s := range r.store {
+		if s.Fingerprint == fingerprint && s.Tenant == tenant && s.Status == "IN_PROGRESS" {
+			return id, s, true
+		}
+	}
+	return "", RequestState{}, false
+}
+
+func (r *requestStoreImpl) ListByJob(jobID string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []string
+	for id, s := range r.store {
+		if id == jobID || s.JobID == jobID {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func (r *requestStoreImpl) List(filter RequestFilter) []StoredRequest {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []StoredRequest
+	for id, s := range r.store {
+		if filter.Tenant != "" && s.Tenant != filter.Tenant {
+			continue
+		}
+		if filter.Status != "" && s.Status != filter.Status {
+			continue
+		}
+		result = append(result, StoredRequest{ID: id, State: s})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].State.StartTime.After(result[j].State.StartTime) })
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result
+}
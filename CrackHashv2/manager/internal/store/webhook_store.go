@@ -0,0 +1,110 @@
+package store
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"CrackHash/manager/internal/envelope"
+)
+
+const (
+	DeliveryPending   = "PENDING"
+	DeliveryDelivered = "DELIVERED"
+	DeliveryFailed    = "FAILED"
+)
+
+type DeliveryAttempt struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"statusCode,omitempty" bson:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+}
+
+type WebhookDelivery struct {
+	ID          string            `json:"id" bson:"_id"`
+	RequestID   string            `json:"requestId" bson:"requestId"`
+	URL         string            `json:"url" bson:"url"`
+	Event       string            `json:"event" bson:"event"`
+	Payload     []byte            `json:"-" bson:"payload,omitempty"`
+	State       string            `json:"state" bson:"state"`
+	Attempts    []DeliveryAttempt `json:"attempts" bson:"attempts"`
+	NextAttempt time.Time         `json:"nextAttempt,omitempty" bson:"nextAttempt"`
+	CreatedAt   time.Time         `json:"createdAt" bson:"createdAt"`
+	// EncryptedPayload заменяет Payload в MongoDB, когда включено шифрование: в теле
+	// webhook есть найденные слова.
+	EncryptedPayload *envelope.Sealed `json:"-" bson:"encPayload,omitempty"`
+}
+
+type WebhookStore interface {
+	Create(d WebhookDelivery)
+	Update(d WebhookDelivery)
+	Due(now time.Time) []WebhookDelivery
+	// Claim атомарно закрепляет подошедшую доставку за вызывающим, сдвигая nextAttempt
+	// на lease, и возвращает её текущее состояние; false — доставка уже закреплена,
+	// завершена или её время не пришло.
+	Claim(id string, now time.Time, lease time.Duration) (WebhookDelivery, bool)
+	ListByRequest(requestID string) []WebhookDelivery
+}
+
+type webhookStoreImpl struct {
+	mu         sync.RWMutex
+	deliveries map[string]WebhookDelivery
+}
+
+func NewWebhookStore() WebhookStore {
+	return &webhookStoreImpl{
+		deliveries: make(map[string]WebhookDelivery),
+	}
+}
+
+func (w *webhookStoreImpl) Create(d WebhookDelivery) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.deliveries[d.ID] = d
+}
+
+func (w *webhookStoreImpl) Update(d WebhookDelivery) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.deliveries[d.ID] = d
+}
+
+func (w *webhookStoreImpl) Due(now time.Time) []WebhookDelivery {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var result []WebhookDelivery
+	for _, d := range w.deliveries {
+		if d.State == DeliveryPending && !d.NextAttempt.After(now) {
+			result = append(result, d)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].NextAttempt.Before(result[j].NextAttempt) })
+	return result
+}
+
+func (w *webhookStoreImpl) Claim(id string, now time.Time, lease time.Duration) (WebhookDelivery, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	d, ok := w.deliveries[id]
+	if !ok || d.State != DeliveryPending || d.NextAttempt.After(now) {
+		return WebhookDelivery{}, false
+	}
+	d.NextAttempt = now.Add(lease)
+	w.deliveries[id] = d
+	return d, true
+}
+
+func (w *webhookStoreImpl) ListByRequest(requestID string) []WebhookDelivery {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var result []WebhookDelivery
+	for _, d := range w.deliveries {
+		if d.RequestID == requestID {
+			result = append(result, d)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result
+}
@@ -1,10 +1,83 @@
 package types
 
-import "encoding/xml"
+import (
+	"encoding/xml"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
 
 type CrackRequest struct {
+	Hash        string `json:"hash"`
+	MaxLength   int    `json:"maxLength"`
+	Algorithm   string `json:"algorithm,omitempty"`
+	CallbackURL string `json:"callbackUrl,omitempty"`
+}
+
+const (
+	ErrCodeMethodNotAllowed     = "METHOD_NOT_ALLOWED"
+	ErrCodeInvalidJSON          = "INVALID_JSON"
+	ErrCodeMissingParameter     = "MISSING_PARAMETER"
+	ErrCodeInvalidParameter     = "INVALID_PARAMETER"
+	ErrCodeInvalidHash          = "INVALID_HASH"
+	ErrCodeUnsupportedAlgorithm = "UNSUPPORTED_ALGORITHM"
+	ErrCodeInvalidMaxLength     = "INVALID_MAX_LENGTH"
+	ErrCodeInvalidCallbackURL   = "INVALID_CALLBACK_URL"
+	ErrCodeInvalidPotfile       = "INVALID_POTFILE"
+	ErrCodeInvalidHashList      = "INVALID_HASH_LIST"
+	ErrCodeInvalidXML           = "INVALID_XML"
+	ErrCodeBatchEmpty           = "BATCH_EMPTY"
+	ErrCodeBatchTooLarge        = "BATCH_TOO_LARGE"
+	ErrCodeNotFound             = "NOT_FOUND"
+	ErrCodeNotCancellable       = "NOT_CANCELLABLE"
+	ErrCodeQueueFull            = "QUEUE_FULL"
+	ErrCodeUnauthorized         = "UNAUTHORIZED"
+	ErrCodeForbidden            = "FORBIDDEN"
+	ErrCodeConcurrentQuota      = "CONCURRENT_QUOTA_EXCEEDED"
+	ErrCodeKeyspaceQuota        = "KEYSPACE_QUOTA_EXCEEDED"
+	ErrCodeInvalidSignature     = "INVALID_SIGNATURE"
+	ErrCodeInternal             = "INTERNAL_ERROR"
+)
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type BatchItem struct {
+	User      string `json:"user,omitempty"`
 	Hash      string `json:"hash"`
 	MaxLength int    `json:"maxLength"`
+	Algorithm string `json:"algorithm,omitempty"`
+}
+
+type BatchResponse struct {
+	BatchID string `json:"batchId"`
+}
+
+type BatchItemStatus struct {
+	User      string   `json:"user,omitempty"`
+	Hash      string   `json:"hash"`
+	RequestID string   `json:"requestId,omitempty"`
+	Status    string   `json:"status"`
+	Data      []string `json:"data,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+type BatchStatusResponse struct {
+	BatchID    string            `json:"batchId"`
+	Status     string            `json:"status"`
+	Total      int               `json:"total"`
+	Ready      int               `json:"ready"`
+	InProgress int               `json:"inProgress"`
+	Failed     int               `json:"failed"`
+	Rejected   int               `json:"rejected"`
+	Items      []BatchItemStatus `json:"items"`
 }
 
 type RequestResponse struct {
@@ -17,6 +90,59 @@ type StatusResponse struct {
 	Progress int      `json:"progress"`
 }
 
+type RequestSummary struct {
+	RequestID string    `json:"requestId"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Status    string    `json:"status"`
+	Hash      string    `json:"hash"`
+	MaxLength int       `json:"maxLength"`
+	Algorithm string    `json:"algorithm"`
+	Progress  int       `json:"progress"`
+	StartTime time.Time `json:"startTime"`
+}
+
+// HealthResponse — ответ /healthz и /readyz; Checks содержит "ok" или причину недоступности.
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+type RequestListResponse struct {
+	Requests []RequestSummary `json:"requests"`
+}
+
+type PotfileImportResponse struct {
+	Imported int `json:"imported"`
+}
+
+// APIKeyRequest — тело создания и изменения API-ключа; в PATCH nil-поля не меняются.
+type APIKeyRequest struct {
+	Name          *string `json:"name,omitempty"`
+	Tenant        *string `json:"tenant,omitempty"`
+	Role          *string `json:"role,omitempty"`
+	MaxConcurrent *int    `json:"maxConcurrent,omitempty"`
+	DailyKeyspace *int64  `json:"dailyKeyspace,omitempty"`
+}
+
+// APIKeyCreatedResponse содержит сам ключ; он возвращается только при создании.
+type APIKeyCreatedResponse struct {
+	Key string `json:"key"`
+	APIKeyInfo
+}
+
+type APIKeyInfo struct {
+	ID                string    `json:"id"`
+	Name              string    `json:"name"`
+	Prefix            string    `json:"prefix"`
+	Tenant            string    `json:"tenant"`
+	Role              string    `json:"role"`
+	MaxConcurrent     int       `json:"maxConcurrent"`
+	DailyKeyspace     int64     `json:"dailyKeyspace"`
+	KeyspaceUsedToday int64     `json:"keyspaceUsedToday"`
+	Revoked           bool      `json:"revoked"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
 type CrackHashManagerRequest struct {
 	XMLName    xml.Name `xml:"CrackHashManagerRequest"`
 	RequestId  string   `xml:"RequestId"`
@@ -36,8 +162,29 @@ type CrackHashWorkerResponse struct {
 	XMLName    xml.Name `xml:"CrackHashWorkerResponse"`
 	RequestId  string   `xml:"RequestId"`
 	PartNumber int      `xml:"PartNumber"`
+	WorkerId   string   `xml:"WorkerId,omitempty"`
 	Answers    struct {
 		Words []string `xml:"words"`
 	} `xml:"Answers"`
 	DeliveryTag uint64 `xml:"-"`
+	// SpanContext — спан, в котором воркер опубликовал ответ; связывает обработку ответа с трассой запроса.
+	SpanContext trace.SpanContext `xml:"-"`
+	// SigningKeyID — ID ключа, подпись которого проверена при приёме ответа. В отличие от
+	// WorkerId его нельзя указать произвольно, поэтому по нему менеджер опознаёт воркер.
+	SigningKeyID string `xml:"-"`
+}
+
+// Settings — изменяемые без перезапуска настройки менеджера. responseTimeout — длительность
+// Go вида "3m".
+type Settings struct {
+	ResponseTimeout string `json:"responseTimeout"`
+	MaxQueueSize    int    `json:"maxQueueSize"`
+	MaxLengthLimit  int    `json:"maxLengthLimit"`
+}
+
+// SettingsPatch — тело PATCH /api/admin/settings: незаданные поля не меняются.
+type SettingsPatch struct {
+	ResponseTimeout *string `json:"responseTimeout,omitempty"`
+	MaxQueueSize    *int    `json:"maxQueueSize,omitempty"`
+	MaxLengthLimit  *int    `json:"maxLengthLimit,omitempty"`
 }
@@ -0,0 +1,190 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"CrackHash/internal/logging"
+	"CrackHash/manager/internal/store"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SignatureHeader = "X-CrackHash-Signature"
+	TimestampHeader = "X-CrackHash-Timestamp"
+	DeliveryHeader  = "X-CrackHash-Delivery"
+)
+
+type Payload struct {
+	RequestID string    `json:"requestId"`
+	Status    string    `json:"status"`
+	Data      []string  `json:"data"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type Dispatcher struct {
+	store       store.WebhookStore
+	client      *http.Client
+	secret      []byte
+	maxAttempts int
+	baseDelay   time.Duration
+	guard       *Guard
+}
+
+// claimLease — на сколько доставка закрепляется за попыткой. Больше таймаута клиента,
+// поэтому за это время попытка успевает записать результат; если менеджер упал посреди
+// попытки, доставку по истечении аренды подхватит следующий проход.
+const claimLease = time.Minute
+
+const component = "webhook"
+
+type Option func(*Dispatcher)
+
+// WithGuard задаёт сети, в которые можно отправлять webhook; по умолчанию внутренние
+// адреса запрещены.
+func WithGuard(g *Guard) Option {
+	return func(d *Dispatcher) {
+		d.guard = g
+	}
+}
+
+func NewDispatcher(s store.WebhookStore, secret string, maxAttempts int, baseDelay time.Duration, opts ...Option) *Dispatcher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	d := &Dispatcher{
+		store:       s,
+		secret:      []byte(secret),
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	// Адрес проверяется после разрешения имени, при каждом соединении. Прокси отключён:
+	// через него соединение ушло бы к адресу, который guard не видит.
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: d.guard.control}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	d.client = &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	return d
+}
+
+// Sign считает HMAC-SHA256 от "timestamp.body"; получатель проверяет подпись тем же секретом.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) NotifyCompletion(requestID string, state store.RequestState) {
+	if state.CallbackURL == "" {
+		return
+	}
+	data := state.Data
+	if data == nil {
+		data = []string{}
+	}
+	body, err := json.Marshal(Payload{
+		RequestID: requestID,
+		Status:    state.Status,
+		Data:      data,
+		Timestamp: time.Now().UTC(),
+	})
+	if err != nil {
+		logging.Request(component, requestID).Error("Ошибка сериализации payload", "error", err)
+		return
+	}
+	delivery := store.WebhookDelivery{
+		ID:          uuid.New().String(),
+		RequestID:   requestID,
+		URL:         state.CallbackURL,
+		Event:       state.Status,
+		Payload:     body,
+		State:       store.DeliveryPending,
+		NextAttempt: time.Now(),
+		CreatedAt:   time.Now(),
+	}
+	d.store.Create(delivery)
+	go d.attempt(context.Background(), delivery.ID)
+}
+
+func (d *Dispatcher) ProcessDue(ctx context.Context) {
+	for _, delivery := range d.store.Due(time.Now()) {
+		if ctx.Err() != nil {
+			return
+		}
+		d.attempt(ctx, delivery.ID)
+	}
+}
+
+// attempt отправляет доставку, только если смог её закрепить: параллельный проход
+// ProcessDue или другой экземпляр менеджера получит false и её пропустит, а попытка
+// работает с состоянием, прочитанным при захвате, а не со снимком из Due.
+func (d *Dispatcher) attempt(ctx context.Context, id string) {
+	delivery, ok := d.store.Claim(id, time.Now(), claimLease)
+	if !ok {
+		return
+	}
+
+	attempt := store.DeliveryAttempt{At: time.Now()}
+	statusCode, err := d.post(ctx, delivery)
+	attempt.StatusCode = statusCode
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	delivery.Attempts = append(delivery.Attempts, attempt)
+
+	logger := logging.Request(component, delivery.RequestID).With("deliveryId", delivery.ID)
+	switch {
+	case err == nil:
+		delivery.State = store.DeliveryDelivered
+		logger.Info("Webhook доставлен", "event", delivery.Event, "url", delivery.URL)
+	case len(delivery.Attempts) >= d.maxAttempts:
+		delivery.State = store.DeliveryFailed
+		logger.Error("Доставка не удалась, попытки исчерпаны", "attempts", len(delivery.Attempts), "error", err)
+	default:
+		delivery.NextAttempt = time.Now().Add(d.backoff(len(delivery.Attempts)))
+		logger.Warn("Ошибка доставки, повторим", "attempt", len(delivery.Attempts), "error", err, "nextAttempt", delivery.NextAttempt)
+	}
+	d.store.Update(delivery)
+}
+
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	return d.baseDelay * time.Duration(1<<uint(attempts-1))
+}
+
+func (d *Dispatcher) post(ctx context.Context, delivery store.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(d.secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("получатель вернул %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
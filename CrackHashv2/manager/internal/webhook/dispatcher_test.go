@@ -0,0 +1,194 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"CrackHash/manager/internal/store"
+	"CrackHash/manager/internal/webhook"
+)
+
+// allowLoopback разрешает доставку на httptest-сервер: по умолчанию loopback запрещён.
+func allowLoopback(t *testing.T) webhook.Option {
+	g, err := webhook.NewGuard([]string{"127.0.0.0/8"})
+	require.NoError(t, err)
+	return webhook.WithGuard(g)
+}
+
+func TestDispatcher_DeliversSignedPayload(t *testing.T) {
+	secret := "s3cret"
+	received := make(chan webhook.Payload, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		expected := webhook.Sign([]byte(secret), r.Header.Get(webhook.TimestampHeader), body)
+		require.Equal(t, expected, r.Header.Get(webhook.SignatureHeader))
+
+		var p webhook.Payload
+		require.NoError(t, json.Unmarshal(body, &p))
+		received <- p
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	deliveries := store.NewWebhookStore()
+	d := webhook.NewDispatcher(deliveries, secret, 3, time.Hour, allowLoopback(t))
+	d.NotifyCompletion("req-1", store.RequestState{
+		Status:      "READY",
+		Data:        []string{"abc"},
+		CallbackURL: receiver.URL,
+	})
+
+	select {
+	case p := <-received:
+		require.Equal(t, "req-1", p.RequestID)
+		require.Equal(t, "READY", p.Status)
+		require.Equal(t, []string{"abc"}, p.Data)
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook не доставлен")
+	}
+
+	require.Eventually(t, func() bool {
+		list := deliveries.ListByRequest("req-1")
+		return len(list) == 1 && list[0].State == store.DeliveryDelivered
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	deliveries := store.NewWebhookStore()
+	d := webhook.NewDispatcher(deliveries, "secret", 5, 20*time.Millisecond, allowLoopback(t))
+	d.NotifyCompletion("req-2", store.RequestState{Status: "ERROR", CallbackURL: receiver.URL})
+
+	require.Eventually(t, func() bool {
+		d.ProcessDue(context.Background())
+		list := deliveries.ListByRequest("req-2")
+		return len(list) == 1 && list[0].State == store.DeliveryDelivered
+	}, 3*time.Second, 10*time.Millisecond)
+
+	delivery := deliveries.ListByRequest("req-2")[0]
+	require.Len(t, delivery.Attempts, 3)
+	require.Equal(t, http.StatusInternalServerError, delivery.Attempts[0].StatusCode)
+	require.GreaterOrEqual(t, delivery.Attempts[2].At.Sub(delivery.Attempts[1].At), 40*time.Millisecond)
+}
+
+func TestDispatcher_GivesUpAfterMaxAttempts(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+
+	deliveries := store.NewWebhookStore()
+	d := webhook.NewDispatcher(deliveries, "secret", 2, time.Millisecond, allowLoopback(t))
+	d.NotifyCompletion("req-3", store.RequestState{Status: "CANCELLED", CallbackURL: receiver.URL})
+
+	require.Eventually(t, func() bool {
+		d.ProcessDue(context.Background())
+		list := deliveries.ListByRequest("req-3")
+		return len(list) == 1 && list[0].State == store.DeliveryFailed
+	}, 3*time.Second, 10*time.Millisecond)
+	require.Len(t, deliveries.ListByRequest("req-3")[0].Attempts, 2)
+}
+
+func TestDispatcher_ConcurrentPassesSendOnce(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	deliveries := store.NewWebhookStore()
+	deliveries.Create(store.WebhookDelivery{
+		ID:          "d-1",
+		RequestID:   "req-4",
+		URL:         receiver.URL,
+		Event:       "READY",
+		State:       store.DeliveryPending,
+		NextAttempt: time.Now(),
+	})
+	d := webhook.NewDispatcher(deliveries, "secret", 3, time.Millisecond, allowLoopback(t))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.ProcessDue(context.Background())
+		}()
+	}
+	wg.Wait()
+	d.ProcessDue(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 1, calls, "доставку закрепляет один проход, остальные её пропускают")
+	require.Equal(t, store.DeliveryDelivered, deliveries.ListByRequest("req-4")[0].State)
+}
+
+func TestDispatcher_RefusesInternalAddresses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("запрос во внутреннюю сеть не должен уйти")
+	}))
+	defer receiver.Close()
+
+	deliveries := store.NewWebhookStore()
+	d := webhook.NewDispatcher(deliveries, "secret", 1, time.Millisecond)
+	d.NotifyCompletion("req-5", store.RequestState{Status: "READY", CallbackURL: receiver.URL})
+
+	require.Eventually(t, func() bool {
+		list := deliveries.ListByRequest("req-5")
+		return len(list) == 1 && list[0].State == store.DeliveryFailed
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Contains(t, deliveries.ListByRequest("req-5")[0].Attempts[0].Error, "во внутренней сети")
+}
+
+func TestGuard_CheckURL(t *testing.T) {
+	var strict *webhook.Guard
+	for _, raw := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.1.2.3/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/hook",
+		"http://[::ffff:192.168.0.1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		require.Error(t, strict.CheckURL(context.Background(), raw), raw)
+	}
+	require.NoError(t, strict.CheckURL(context.Background(), "https://93.184.216.34/hook"))
+
+	g, err := webhook.NewGuard([]string{"10.0.0.0/8", "192.168.1.5"})
+	require.NoError(t, err)
+	require.NoError(t, g.CheckURL(context.Background(), "http://10.1.2.3/hook"))
+	require.NoError(t, g.CheckURL(context.Background(), "http://192.168.1.5/hook"))
+	require.Error(t, g.CheckURL(context.Background(), "http://192.168.1.6/hook"))
+
+	_, err = webhook.NewGuard([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+}
@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Guard не даёт отправить webhook во внутреннюю сеть менеджера: loopback, частные,
+// link-local (в том числе 169.254.169.254 облачных метаданных) и служебные адреса
+// запрещены, если их нет в allowed. Нулевой *Guard запрещает все такие адреса.
+type Guard struct {
+	allowed []netip.Prefix
+}
+
+// NewGuard разрешает сети allowed (CIDR или одиночный адрес), например получателя
+// webhook в той же docker-сети.
+func NewGuard(allowed []string) (*Guard, error) {
+	g := &Guard{}
+	for _, raw := range allowed {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(raw)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%q не сеть и не адрес", raw)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		g.allowed = append(g.allowed, prefix.Masked())
+	}
+	return g, nil
+}
+
+func (g *Guard) permitted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if g != nil {
+		for _, p := range g.allowed {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+	}
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// sharedAddressSpace — адреса операторского NAT (RFC 6598), IsPrivate их не включает.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// CheckURL проверяет callbackUrl при создании задачи. Имя, которое сейчас не удаётся
+// разрешить, пропускается: адрес всё равно проверяется при каждом соединении.
+func (g *Guard) CheckURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return g.checkAddr(addr)
+	}
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return g.checkAddr(netip.AddrFrom4([4]byte{127, 0, 0, 1}))
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if err := g.checkAddr(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Guard) checkAddr(addr netip.Addr) error {
+	if !g.permitted(addr) {
+		return fmt.Errorf("адрес %s во внутренней сети", addr)
+	}
+	return nil
+}
+
+// control вызывается для уже разрешённого адреса перед каждым соединением, поэтому
+// имя, которое после проверки в CheckURL стало указывать во внутреннюю сеть, и
+// перенаправления туда тоже отклоняются.
+func (g *Guard) control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	return g.checkAddr(addr)
+}
@@ -3,45 +3,153 @@ package main
 import (
 	"CrackHash/worker/internal/queue"
 	"context"
+	"errors"
+	"flag"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
+	"CrackHash/internal/logging"
+	"CrackHash/internal/signing"
+	"CrackHash/internal/tracing"
 	"CrackHash/worker/internal/config"
 	"CrackHash/worker/internal/handlers"
+	"CrackHash/worker/internal/metrics"
 	"CrackHash/worker/internal/service"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 func main() {
 	ctx := context.Background()
 
-	cfg, err := config.LoadConfig()
+	cfg, printConfig, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+		log.Fatalf("Ошибка конфигурации:\n%v", err)
+	}
+	if printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Ошибка вывода конфигурации: %v", err)
+		}
+		return
+	}
+	if err := logging.Setup(os.Stdout, cfg.LogLevel); err != nil {
+		log.Fatalf("Ошибка в LOG_LEVEL: %v", err)
+	}
+	logger := logging.Component("main")
+	shutdownTracing, err := tracing.Setup(ctx, "crackhash-worker", cfg.TracingExporter)
+	if err != nil {
+		fatal(logger, "Ошибка в TRACING_EXPORTER", err)
 	}
 
-	workerSvc := service.NewWorkerService()
+	workerSvc := service.NewWorkerService(service.WithSettings(settingsFrom(cfg)))
 
-	rabbitConsumer, err := queue.NewRabbitConsumer(cfg)
+	signingKeys, err := signing.ParseKeys(cfg.MessageSigningKeys)
 	if err != nil {
-		log.Printf("Не удалось инициализировать RabbitConsumer: %v", err)
+		fatal(logger, "Ошибка в MESSAGE_SIGNING_KEYS", err)
+	}
+
+	rabbitConsumer, err := queue.NewRabbitConsumer(cfg, signingKeys)
+	if err != nil {
+		logger.Error("Не удалось инициализировать RabbitConsumer", "error", err)
 	} else {
 		err = rabbitConsumer.StartConsuming(ctx, workerSvc)
 		if err != nil {
-			log.Printf("Ошибка StartConsuming: %v", err)
+			logger.Error("Ошибка StartConsuming", "error", err)
 		}
 	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/internal/api/worker/hash/crack/task", handlers.TaskHandler(ctx, workerSvc))
+	mux.HandleFunc("/internal/api/worker/hash/crack/task", handlers.TaskHandler(ctx, workerSvc, cfg))
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/healthz", handlers.HealthHandler())
+	mux.HandleFunc("/readyz", handlers.ReadyHandler(readinessChecks(rabbitConsumer)...))
 
+	spanName := otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + r.URL.Path
+	})
 	srv := &http.Server{
 		Addr:         ":" + cfg.WorkerPort,
-		Handler:      mux,
+		Handler:      otelhttp.NewHandler(mux, "worker", spanName),
 		ReadTimeout:  2 * time.Minute,
 		WriteTimeout: 2 * time.Minute,
 	}
 
-	log.Printf("Worker запускается на порту %s", cfg.WorkerPort)
-	log.Fatal(srv.ListenAndServe())
+	go func() {
+		logger.Info("Worker запускается", "port", cfg.WorkerPort)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal(logger, "Ошибка HTTP-сервера", err)
+		}
+	}()
+
+	// Копии cfg уже переданы обработчикам, дальше SIGHUP меняет только изменяемые на лету поля.
+	watchReload(ctx, &cfg, workerSvc)
+
+	sigCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	<-sigCtx.Done()
+	stop()
+	logger.Info("Получен сигнал остановки, завершаем работу", "timeout", cfg.ShutdownTimeout.String())
+	shutdownLogger := logging.Component("shutdown")
+	shutdownCtx, cancel := context.WithTimeout(ctx, cfg.ShutdownTimeout)
+	defer cancel()
+
+	// HTTP-задачи и задача из очереди дорабатываются параллельно в пределах одного дедлайна.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			shutdownLogger.Warn("HTTP-сервер остановлен принудительно", "error", err)
+		}
+	}()
+	if rabbitConsumer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := rabbitConsumer.Shutdown(shutdownCtx); err != nil {
+				shutdownLogger.Warn("Ошибка остановки RabbitConsumer", "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		shutdownLogger.Error("Ошибка выгрузки спанов", "error", err)
+	}
+	shutdownLogger.Info("Worker остановлен")
+}
+
+// readinessChecks: воркер готов, только если подключен к RabbitMQ и читает очередь задач.
+// consumer равен nil, если подключиться при старте не удалось.
+func readinessChecks(consumer queue.TaskConsumer) []handlers.ReadinessCheck {
+	return []handlers.ReadinessCheck{
+		{Name: "rabbitmq", Check: func(context.Context) error {
+			if consumer == nil || !consumer.IsConnected() {
+				return errors.New("нет соединения с RabbitMQ")
+			}
+			return nil
+		}},
+		{Name: "consumer", Check: func(context.Context) error {
+			if consumer == nil || !consumer.Consuming() {
+				return errors.New("консьюмер задач не запущен")
+			}
+			return nil
+		}},
+	}
+}
+
+// fatal пишет в лог ошибку, после которой воркер не может работать, и завершает процесс.
+// До logging.Setup вместо него используется log.Fatalf.
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"CrackHash/internal/logging"
+	"CrackHash/worker/internal/config"
+	"CrackHash/worker/internal/service"
+)
+
+// reloadable — параметры, которые применяются по SIGHUP без перезапуска.
+var reloadable = map[string]bool{
+	"hashGoroutines": true,
+	"cpuLimit":       true,
+	"logLevel":       true,
+}
+
+func settingsFrom(cfg config.Config) service.Settings {
+	return service.Settings{HashGoroutines: cfg.HashGoroutines, CPULimit: cfg.CPULimit}
+}
+
+// watchReload по SIGHUP перечитывает конфигурацию теми же слоями, что и при старте,
+// пока ctx не отменён.
+func watchReload(ctx context.Context, cfg *config.Config, svc service.WorkerServiceImpl) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				reload(cfg, svc, os.Args[1:])
+			}
+		}
+	}()
+}
+
+// reload применяет изменяемые на лету параметры и переносит их в cfg. Если новая
+// конфигурация не проходит проверку, остаются прежние настройки; изменения остальных
+// параметров только логируются — для них нужен перезапуск.
+func reload(cfg *config.Config, svc service.WorkerServiceImpl, args []string) {
+	logger := logging.Component("config")
+	next, _, err := config.Load(args)
+	if err != nil {
+		logger.Error("Конфигурация не перечитана, действуют прежние настройки", "error", err)
+		return
+	}
+	if err := svc.UpdateSettings(settingsFrom(next), "sighup"); err != nil {
+		logger.Error("Настройки не применены", "error", err)
+		return
+	}
+	if next.LogLevel != cfg.LogLevel {
+		// Уровень уже проверен config.Load.
+		logging.SetLevel(next.LogLevel)
+		logger.Info("Настройка изменена", "setting", "logLevel", "change", cfg.LogLevel+" -> "+next.LogLevel, "source", "sighup")
+	}
+	var restart []string
+	for _, key := range cfg.Changed(&next) {
+		if !reloadable[key] {
+			restart = append(restart, key)
+		}
+	}
+	if len(restart) > 0 {
+		logger.Warn("Параметры изменены, но применятся только после перезапуска", "settings", restart)
+	}
+	cfg.HashGoroutines, cfg.CPULimit, cfg.LogLevel = next.HashGoroutines, next.CPULimit, next.LogLevel
+}
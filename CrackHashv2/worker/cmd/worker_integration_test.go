@@ -6,29 +6,41 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"github.com/stretchr/testify/require"
+	"io"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 
+	"CrackHash/internal/signing"
+	"CrackHash/worker/internal/config"
 	"CrackHash/worker/internal/handlers"
 	"CrackHash/worker/internal/service"
 	"CrackHash/worker/internal/types"
 )
 
+const testSigningKeys = "k1:worker-test-signing-secret"
+
 func TestWorkerIntegration_Success(t *testing.T) {
+	keys, err := signing.ParseKeys(testSigningKeys)
+	require.NoError(t, err)
 	managerServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if keys.Verify(r.Header.Get(signing.HeaderKeyID), r.Header.Get(signing.HeaderSignature), body) != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 	}))
 	defer managerServer.Close()
 
-	os.Setenv("MANAGER_URL", managerServer.URL)
-	defer os.Unsetenv("MANAGER_URL")
+	cfg := config.Default()
+	cfg.ManagerURL = managerServer.URL
+	cfg.MessageSigningKeys = testSigningKeys
 
 	workerSvc := service.NewWorkerService()
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/internal/api/worker/hash/crack/task", handlers.TaskHandler(context.Background(), workerSvc))
+	mux.HandleFunc("/internal/api/worker/hash/crack/task", handlers.TaskHandler(context.Background(), workerSvc, cfg))
 	server := httptest.NewServer(mux)
 	defer server.Close()
 
@@ -45,7 +57,17 @@ func TestWorkerIntegration_Success(t *testing.T) {
 	jsonData, err := json.Marshal(reqPayload)
 	require.NoError(t, err)
 
-	postResp, err := http.Post(server.URL+"/internal/api/worker/hash/crack/task", "application/json", bytes.NewReader(jsonData))
+	unsigned, err := http.Post(server.URL+"/internal/api/worker/hash/crack/task", "application/json", bytes.NewReader(jsonData))
+	require.NoError(t, err)
+	unsigned.Body.Close()
+	require.Equal(t, http.StatusForbidden, unsigned.StatusCode)
+
+	postReq, err := http.NewRequest(http.MethodPost, server.URL+"/internal/api/worker/hash/crack/task", bytes.NewReader(jsonData))
+	require.NoError(t, err)
+	keyID, signature := keys.Sign(jsonData)
+	postReq.Header.Set(signing.HeaderKeyID, keyID)
+	postReq.Header.Set(signing.HeaderSignature, signature)
+	postResp, err := http.DefaultClient.Do(postReq)
 	require.NoError(t, err)
 	require.Equal(t, http.StatusOK, postResp.StatusCode)
 	defer postResp.Body.Close()
@@ -58,3 +80,30 @@ func TestWorkerIntegration_Success(t *testing.T) {
 	require.Equal(t, 0, xmlResp.PartNumber)
 	require.Contains(t, xmlResp.Answers.Words, "a")
 }
+
+func TestWorkerReadiness_WithoutConsumer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", handlers.HealthHandler())
+	mux.HandleFunc("/readyz", handlers.ReadyHandler(readinessChecks(nil)...))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	live, err := http.Get(server.URL + "/healthz")
+	require.NoError(t, err)
+	live.Body.Close()
+	require.Equal(t, http.StatusOK, live.StatusCode)
+
+	// Воркер, не сумевший подключиться к RabbitMQ, жив, но не готов.
+	ready, err := http.Get(server.URL + "/readyz")
+	require.NoError(t, err)
+	defer ready.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, ready.StatusCode)
+	var body struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}
+	require.NoError(t, json.NewDecoder(ready.Body).Decode(&body))
+	require.Equal(t, "unavailable", body.Status)
+	require.Contains(t, body.Checks, "rabbitmq")
+	require.Contains(t, body.Checks, "consumer")
+}
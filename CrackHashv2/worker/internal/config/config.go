@@ -1,6 +1,14 @@
 package config
 
-import "os"
+import (
+	"io"
+	"os"
+	"time"
+
+	"CrackHash/internal/config/layers"
+	"CrackHash/internal/logging"
+	"CrackHash/internal/signing"
+)
 
 type Config struct {
 	WorkerPort       string
@@ -10,9 +18,36 @@ type Config struct {
 	TaskQueueName    string
 	ResponseExchange string
 	ResponseQueue    string
+	InternalToken    string
+	// WorkerID подписывает ответы, чтобы менеджер вёл счётчики неверных результатов по воркерам.
+	WorkerID string
+	// MessageSigningKeys — "id:секрет,..." для проверки задач и подписи ответов.
+	MessageSigningKeys string
+	// Файлы для amqps:// (CA и клиентский сертификат) и CA для HTTPS-адреса менеджера.
+	RabbitCAFile   string
+	RabbitCertFile string
+	RabbitKeyFile  string
+	ManagerCAFile  string
+	// ShutdownTimeout — сколько ждать текущую часть задачи после SIGTERM.
+	ShutdownTimeout time.Duration
+	// HashGoroutines — сколько горутин перебирают одну часть; 0 — по числу CPU.
+	HashGoroutines int
+	// ConcurrentParts — сколько частей из очереди считается одновременно; столько же
+	// сообщений брокер выдаёт воркеру без подтверждения (prefetch).
+	ConcurrentParts int
+	// TaskPrefetch — сколько задач брокер выдаёт воркеру без подтверждения; 0 — по
+	// CONCURRENT_PARTS. Больше CONCURRENT_PARTS — запас, чтобы не ждать следующую задачу.
+	TaskPrefetch int
+	// CPULimit — какую долю CPU машины в процентах может занять перебор; 0 — без ограничения.
+	CPULimit int
+	// LogLevel — минимальный уровень JSON-логов: debug, info, warn или error.
+	LogLevel string
+	// TracingExporter — none, otlp (адрес из OTEL_EXPORTER_OTLP_ENDPOINT) или stdout.
+	TracingExporter string
 }
 
-func LoadConfig() (Config, error) {
+// Default — значения по умолчанию для запуска в docker-compose.
+func Default() Config {
 	cfg := Config{
 		WorkerPort:       "8081",
 		ManagerURL:       "http://manager:8080/internal/api/manager/hash/crack/request",
@@ -21,16 +56,110 @@ func LoadConfig() (Config, error) {
 		TaskQueueName:    "task_queue",
 		ResponseExchange: "responses_direct",
 		ResponseQueue:    "worker_responses",
+		ShutdownTimeout:  30 * time.Second,
+		ConcurrentParts:  1,
+		LogLevel:         "info",
+		TracingExporter:  "none",
+	}
+	// По умолчанию — имя хоста: в docker это ID контейнера, он различается у реплик.
+	if host, err := os.Hostname(); err == nil {
+		cfg.WorkerID = host
+	}
+	return cfg
+}
+
+// fields перечисляет все параметры воркера; порядок совпадает с выводом --print-config.
+func (c *Config) fields() []layers.Field {
+	return []layers.Field{
+		{Key: "workerPort", Env: "WORKER_PORT", Usage: "порт HTTP", Ptr: &c.WorkerPort},
+		{Key: "managerURL", Env: "MANAGER_URL", Usage: "адрес менеджера для ответов по HTTP", Ptr: &c.ManagerURL},
+		{Key: "rabbitURI", Env: "RABBIT_URI", Usage: "адрес RabbitMQ", Ptr: &c.RabbitURI, Redact: layers.HidePassword},
+		{Key: "taskExchange", Env: "TASK_EXCHANGE", Usage: "exchange задач", Ptr: &c.TaskExchange},
+		{Key: "taskQueueName", Env: "TASK_QUEUE", Usage: "очередь задач", Ptr: &c.TaskQueueName},
+		{Key: "responseExchange", Env: "RESPONSE_EXCHANGE", Usage: "exchange ответов", Ptr: &c.ResponseExchange},
+		{Key: "responseQueue", Env: "RESPONSE_QUEUE", Usage: "очередь ответов", Ptr: &c.ResponseQueue},
+		{Key: "internalToken", Env: "INTERNAL_TOKEN", Usage: "токен внутреннего API менеджера", Ptr: &c.InternalToken, Redact: layers.HideValue},
+		{Key: "workerID", Env: "WORKER_ID", Usage: "идентификатор воркера в ответах", Ptr: &c.WorkerID},
+		{Key: "messageSigningKeys", Env: "MESSAGE_SIGNING_KEYS", Usage: "ключи подписи сообщений id:секрет через запятую", Ptr: &c.MessageSigningKeys, Redact: layers.HideValue},
+		{Key: "rabbitCAFile", Env: "RABBIT_CA_FILE", Usage: "CA для amqps://", Ptr: &c.RabbitCAFile},
+		{Key: "rabbitCertFile", Env: "RABBIT_CERT_FILE", Usage: "клиентский сертификат RabbitMQ", Ptr: &c.RabbitCertFile},
+		{Key: "rabbitKeyFile", Env: "RABBIT_KEY_FILE", Usage: "ключ клиентского сертификата RabbitMQ", Ptr: &c.RabbitKeyFile},
+		{Key: "managerCAFile", Env: "MANAGER_CA_FILE", Usage: "CA для HTTPS-адреса менеджера", Ptr: &c.ManagerCAFile},
+		{Key: "shutdownTimeout", Env: "SHUTDOWN_TIMEOUT", Usage: "сколько ждать текущую часть после SIGTERM", Ptr: &c.ShutdownTimeout},
+		{Key: "hashGoroutines", Env: "HASH_GOROUTINES", Usage: "горутин перебора на часть, 0 — по числу CPU", Ptr: &c.HashGoroutines},
+		{Key: "concurrentParts", Env: "CONCURRENT_PARTS", Usage: "сколько частей из очереди считать одновременно", Ptr: &c.ConcurrentParts},
+		{Key: "taskPrefetch", Env: "TASK_PREFETCH", Usage: "сколько задач брокер выдаёт без подтверждения, 0 — по CONCURRENT_PARTS", Ptr: &c.TaskPrefetch},
+		{Key: "cpuLimit", Env: "CPU_LIMIT", Usage: "доля CPU машины для перебора в процентах, 0 — без ограничения", Ptr: &c.CPULimit},
+		{Key: "logLevel", Env: "LOG_LEVEL", Usage: "уровень логов: debug, info, warn, error", Ptr: &c.LogLevel},
+		{Key: "tracingExporter", Env: "TRACING_EXPORTER", Usage: "экспортёр трассировки: none, otlp, stdout", Ptr: &c.TracingExporter},
+	}
+}
+
+// Load собирает конфигурацию слоями: значения по умолчанию < YAML-файл (--config или
+// CONFIG_FILE) < переменные окружения < флаги args, и проверяет результат. printConfig
+// означает, что запрошен --print-config.
+func Load(args []string) (cfg Config, printConfig bool, err error) {
+	cfg = Default()
+	c, err := layers.Load("worker", args, cfg.fields())
+	if err != nil {
+		return Config{}, false, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, false, err
 	}
+	return cfg, c.PrintConfig, nil
+}
 
-	if port := os.Getenv("WORKER_PORT"); port != "" {
-		cfg.WorkerPort = port
+// LoadConfig читает конфигурацию без флагов.
+func LoadConfig() (Config, error) {
+	cfg, _, err := Load(nil)
+	return cfg, err
+}
+
+// Validate проверяет значения и возвращает все найденные ошибки сразу.
+func (c *Config) Validate() error {
+	var v layers.Checks
+	v.Port("WORKER_PORT", c.WorkerPort)
+	v.Scheme("MANAGER_URL", c.ManagerURL, "http", "https")
+	v.Scheme("RABBIT_URI", c.RabbitURI, "amqp", "amqps")
+	v.NonEmpty("TASK_EXCHANGE", c.TaskExchange)
+	v.NonEmpty("TASK_QUEUE", c.TaskQueueName)
+	v.NonEmpty("RESPONSE_EXCHANGE", c.ResponseExchange)
+	v.NonEmpty("RESPONSE_QUEUE", c.ResponseQueue)
+	v.NonEmpty("WORKER_ID", c.WorkerID)
+	// Без ключей воркер отклонял бы все задачи в dead-letter очередь, а менеджер — все ответы.
+	v.NonEmpty("MESSAGE_SIGNING_KEYS", c.MessageSigningKeys)
+	if _, err := signing.ParseKeys(c.MessageSigningKeys); err != nil {
+		v.Add(false, "MESSAGE_SIGNING_KEYS: %v", err)
 	}
-	if url := os.Getenv("MANAGER_URL"); url != "" {
-		cfg.ManagerURL = url
+	v.Positive("SHUTDOWN_TIMEOUT", c.ShutdownTimeout)
+	v.Add(c.HashGoroutines >= 0, "HASH_GOROUTINES: не может быть отрицательным")
+	v.Add(c.ConcurrentParts >= 1, "CONCURRENT_PARTS: должен быть не меньше 1")
+	v.Add(c.TaskPrefetch == 0 || c.TaskPrefetch >= c.ConcurrentParts,
+		"TASK_PREFETCH: меньше CONCURRENT_PARTS, часть слотов простаивала бы")
+	v.Add(c.CPULimit >= 0 && c.CPULimit <= 100, "CPU_LIMIT: ожидается процент от 0 до 100")
+	v.Pair("RABBIT_CERT_FILE", c.RabbitCertFile, "RABBIT_KEY_FILE", c.RabbitKeyFile)
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		v.Add(false, "LOG_LEVEL: %v", err)
 	}
-	if rURI := os.Getenv("RABBIT_URI"); rURI != "" {
-		cfg.RabbitURI = rURI
+	v.OneOf("TRACING_EXPORTER", c.TracingExporter, "none", "otlp", "stdout")
+	return v.Err()
+}
+
+// Prefetch — prefetch канала задач с учётом значения по умолчанию.
+func (c *Config) Prefetch() int {
+	if c.TaskPrefetch > 0 {
+		return c.TaskPrefetch
 	}
-	return cfg, nil
+	return max(c.ConcurrentParts, 1)
+}
+
+// Changed возвращает ключи параметров, значения которых в next отличаются от c.
+func (c *Config) Changed(next *Config) []string {
+	return layers.Changed(c.fields(), next.fields())
+}
+
+// Print выводит конфигурацию в YAML, заменяя секреты на ***.
+func (c *Config) Print(w io.Writer) error {
+	return layers.WriteYAML(w, c.fields())
 }
@@ -7,23 +7,43 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"io"
-	"log"
 	"net/http"
+	"os"
 	"time"
 
+	"CrackHash/internal/logging"
+	"CrackHash/internal/signing"
+	"CrackHash/internal/tlsconfig"
 	"CrackHash/worker/internal/types"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+const component = "taskHandler"
+
 type WorkerService interface {
-	ProcessTask(hash string, maxLength int, alphabet []string, partNumber, partCount int) []string
+	ProcessTask(ctx context.Context, hash string, maxLength int, alphabet []string, partNumber, partCount int) ([]string, error)
 }
 
-func TaskHandler(ctx context.Context, svc WorkerService) http.HandlerFunc {
-	cfg, err := config.LoadConfig()
+func TaskHandler(ctx context.Context, svc WorkerService, cfg config.Config) http.HandlerFunc {
+	managerURL := cfg.ManagerURL
+	internalToken := cfg.InternalToken
+	keys, err := signing.ParseKeys(cfg.MessageSigningKeys)
 	if err != nil {
-		log.Fatalf("Ошибка загрузки конфигурации воркера: %v", err)
+		logging.Component(component).Error("Ошибка в MESSAGE_SIGNING_KEYS", "error", err)
+		os.Exit(1)
 	}
-	managerURL := cfg.ManagerURL
+	var transport http.RoundTripper = http.DefaultTransport
+	if cfg.ManagerCAFile != "" {
+		tlsCfg, err := tlsconfig.Client(tlsconfig.Files{CAFile: cfg.ManagerCAFile})
+		if err != nil {
+			logging.Component(component).Error("Ошибка настройки TLS для менеджера", "error", err)
+			os.Exit(1)
+		}
+		transport = &http.Transport{TLSClientConfig: tlsCfg}
+	}
+	// Транспорт передаёт менеджеру контекст трассы в заголовке traceparent.
+	client := &http.Client{Timeout: 10 * time.Second, Transport: otelhttp.NewTransport(transport)}
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -35,6 +55,11 @@ func TaskHandler(ctx context.Context, svc WorkerService) http.HandlerFunc {
 			http.Error(w, "Ошибка чтения запроса", http.StatusBadRequest)
 			return
 		}
+		if err := keys.Verify(r.Header.Get(signing.HeaderKeyID), r.Header.Get(signing.HeaderSignature), body); err != nil {
+			logging.Component(component).Warn("Задача отклонена", "error", err)
+			http.Error(w, "Подпись задачи не прошла проверку", http.StatusForbidden)
+			return
+		}
 		var req types.CrackHashManagerRequest
 		err = json.Unmarshal(body, &req)
 		if err != nil {
@@ -42,39 +67,51 @@ func TaskHandler(ctx context.Context, svc WorkerService) http.HandlerFunc {
 			return
 		}
 
-		results := svc.ProcessTask(req.Hash, req.MaxLength, req.Alphabet.Symbols, req.PartNumber, req.PartCount)
+		logger := logging.Part(component, req.RequestId, req.PartNumber)
+		logger.Info("Получили задачу по HTTP", "hash", req.Hash, "maxLength", req.MaxLength, "partCount", req.PartCount)
+		results, err := svc.ProcessTask(logging.WithLogger(r.Context(), logger), req.Hash, req.MaxLength, req.Alphabet.Symbols, req.PartNumber, req.PartCount)
+		if err != nil {
+			// Менеджер уже не ждёт ответа: соединение закрыто.
+			logger.Warn("Перебор прерван, ответ не отправляем", "error", err)
+			return
+		}
 
 		response := types.CrackHashWorkerResponse{
 			RequestId:  req.RequestId,
 			PartNumber: req.PartNumber,
+			WorkerId:   cfg.WorkerID,
 		}
 		response.Answers.Words = results
 
 		xmlData, err := xml.MarshalIndent(response, "", "  ")
 		if err != nil {
-			log.Printf("Ошибка маршалинга XML: %v", err)
+			logger.Error("Ошибка маршалинга XML", "error", err)
 			http.Error(w, "Ошибка обработки", http.StatusInternalServerError)
 			return
 		}
 		xmlData = append([]byte(xml.Header), xmlData...)
 
-		patchReq, err := http.NewRequest(http.MethodPatch, managerURL, bytes.NewReader(xmlData))
+		patchReq, err := http.NewRequestWithContext(r.Context(), http.MethodPatch, managerURL, bytes.NewReader(xmlData))
 		if err != nil {
-			log.Printf("Ошибка создания PATCH запроса: %v", err)
+			logger.Error("Ошибка создания PATCH запроса", "error", err)
 			http.Error(w, "Ошибка отправки результата", http.StatusInternalServerError)
 			return
 		}
 		patchReq.Header.Set("Content-Type", "application/xml")
-		client := &http.Client{Timeout: 10 * time.Second}
+		patchReq.Header.Set("X-Internal-Token", internalToken)
+		patchReq.Header.Set(logging.HeaderRequestID, req.RequestId)
+		keyID, signature := keys.Sign(xmlData)
+		patchReq.Header.Set(signing.HeaderKeyID, keyID)
+		patchReq.Header.Set(signing.HeaderSignature, signature)
 		patchResp, err := client.Do(patchReq)
 		if err != nil {
-			log.Printf("Ошибка отправки PATCH запроса: %v", err)
+			logger.Error("Ошибка отправки PATCH запроса", "error", err)
 			http.Error(w, "Ошибка отправки результата", http.StatusInternalServerError)
 			return
 		}
 		defer patchResp.Body.Close()
 		if patchResp.StatusCode != http.StatusOK {
-			log.Printf("Менеджер вернул ошибку на PATCH запрос: %s", patchResp.Status)
+			logger.Error("Менеджер вернул ошибку на PATCH запрос", "status", patchResp.Status)
 			http.Error(w, "Менеджер вернул ошибку", http.StatusInternalServerError)
 			return
 		}
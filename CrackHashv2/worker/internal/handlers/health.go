@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"CrackHash/internal/logging"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// ReadinessCheck проверяет одну зависимость; ошибка означает, что воркер не готов.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// HealthHandler — liveness: процесс жив и обслуживает HTTP.
+func HealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, http.StatusOK, healthResponse{Status: "ok"})
+	}
+}
+
+// ReadyHandler — readiness: 200, только если все проверки прошли, иначе 503. Воркер,
+// у которого не поднялся консьюмер RabbitMQ, жив, но задач из очереди не получает.
+func ReadyHandler(checks ...ReadinessCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		resp := healthResponse{Status: "ok", Checks: make(map[string]string, len(checks))}
+		code := http.StatusOK
+		for _, c := range checks {
+			if err := c.Check(ctx); err != nil {
+				resp.Checks[c.Name] = err.Error()
+				resp.Status = "unavailable"
+				code = http.StatusServiceUnavailable
+				continue
+			}
+			resp.Checks[c.Name] = "ok"
+		}
+		writeHealth(w, code, resp)
+	}
+}
+
+func writeHealth(w http.ResponseWriter, code int, resp healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logging.Component("health").Warn("Ошибка записи ответа health", "error", err)
+	}
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "crackhash_worker"
+
+var (
+	// Candidates — перебранные кандидаты; скорость считается как rate(...[1m]).
+	Candidates = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "candidates_total",
+		Help:      "Перебранные кандидаты.",
+	})
+	CandidatesPerSecond = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "candidates_per_second",
+		Help:      "Скорость перебора в последней обработанной части.",
+	})
+	PartsProcessed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "parts_processed_total",
+		Help:      "Обработанные части задач.",
+	})
+	ProcessTaskDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "process_task_duration_seconds",
+		Help:      "Длительность ProcessTask.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+	})
+	RabbitReconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rabbitmq_reconnects_total",
+		Help:      "Успешные переподключения к RabbitMQ.",
+	})
+	RabbitConnected = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rabbitmq_connected",
+		Help:      "1, если консьюмер RabbitMQ подключен.",
+	})
+)
+
+// ObservePart учитывает одну обработанную часть задачи.
+func ObservePart(candidates int, elapsed time.Duration) {
+	Candidates.Add(float64(candidates))
+	PartsProcessed.Inc()
+	ProcessTaskDuration.Observe(elapsed.Seconds())
+	if elapsed > 0 {
+		CandidatesPerSecond.Set(float64(candidates) / elapsed.Seconds())
+	}
+}
+
+func SetConnected(connected bool) {
+	if connected {
+		RabbitConnected.Set(1)
+	} else {
+		RabbitConnected.Set(0)
+	}
+}
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -1,13 +1,20 @@
 package queue
 
 import (
+	"CrackHash/internal/amqpmsg"
+	"CrackHash/internal/logging"
+	"CrackHash/internal/signing"
+	"CrackHash/internal/tlsconfig"
+	"CrackHash/internal/tracing"
 	"CrackHash/worker/internal/config"
 	"CrackHash/worker/internal/handlers"
+	"CrackHash/worker/internal/metrics"
 	"CrackHash/worker/internal/types"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,13 +23,25 @@ import (
 
 type TaskConsumer interface {
 	StartConsuming(ctx context.Context, svc handlers.WorkerService) error
+	IsConnected() bool
+	// Consuming сообщает, что цикл чтения задач запущен и не завершился.
+	Consuming() bool
+	// Shutdown прекращает получать задачи, дожидается текущей части до дедлайна ctx
+	// и закрывает соединение. Недообработанные задачи возвращаются в очередь.
+	Shutdown(ctx context.Context) error
 }
 
+const (
+	consumerTag = "crackhash-worker"
+	component   = "rabbitConsumer"
+)
+
 type rabbitConsumer struct {
 	mu        sync.Mutex
 	conn      *amqp091.Connection
 	channel   *amqp091.Channel
 	cfg       config.Config
+	keys      *signing.Keyring
 	connected bool
 
 	taskQueueName string
@@ -32,12 +51,29 @@ type rabbitConsumer struct {
 	ctx context.Context
 
 	consuming bool
+	// active — цикл чтения задач работает; сбрасывается, когда канал доставок закрыт.
+	active bool
+	// done закрывается при выходе цикла чтения задач.
+	done chan struct{}
+
+	stopping bool
+	// inFlight — задачи, которые сейчас считаются, и отмена их перебора.
+	inFlight map[*amqp091.Delivery]*inFlightPart
 }
 
-func NewRabbitConsumer(cfg config.Config) (TaskConsumer, error) {
+type inFlightPart struct {
+	cancel context.CancelFunc
+	// abandoned — задачу уже вернули в очередь при остановке, и ответ отправлять нельзя.
+	abandoned bool
+}
+
+// NewRabbitConsumer принимает только задачи с верной подписью keys и подписывает ими ответы.
+func NewRabbitConsumer(cfg config.Config, keys *signing.Keyring) (TaskConsumer, error) {
 	c := &rabbitConsumer{
 		cfg:       cfg,
+		keys:      keys,
 		connected: false,
+		inFlight:  make(map[*amqp091.Delivery]*inFlightPart),
 	}
 	if err := c.connectAndDeclare(); err != nil {
 		return nil, err
@@ -60,6 +96,86 @@ func (r *rabbitConsumer) StartConsuming(ctx context.Context, svc handlers.Worker
 	return r.consumeTasks()
 }
 
+func (r *rabbitConsumer) IsConnected() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.connected
+}
+
+func (r *rabbitConsumer) Consuming() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.active
+}
+
+func (r *rabbitConsumer) setActive(active bool) {
+	r.mu.Lock()
+	r.active = active
+	r.mu.Unlock()
+}
+
+func (r *rabbitConsumer) isStopping() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stopping
+}
+
+func (r *rabbitConsumer) setInFlight(d *amqp091.Delivery, cancel context.CancelFunc) {
+	r.mu.Lock()
+	r.inFlight[d] = &inFlightPart{cancel: cancel}
+	r.mu.Unlock()
+}
+
+// finishInFlight снимает отметку о задаче; false, если её уже вернули в очередь.
+func (r *rabbitConsumer) finishInFlight(d *amqp091.Delivery) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	part := r.inFlight[d]
+	delete(r.inFlight, d)
+	return part != nil && !part.abandoned
+}
+
+func (r *rabbitConsumer) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.stopping = true
+	ch, done := r.channel, r.done
+	connected := r.connected
+	r.mu.Unlock()
+
+	if connected {
+		if err := ch.Cancel(consumerTag, false); err != nil {
+			logging.Component(component).Error("Ошибка отмены подписки", "error", err)
+		}
+	}
+	if done != nil && connected {
+		select {
+		case <-done:
+			logging.Component(component).Info("Текущие задачи обработаны, цикл чтения остановлен")
+		case <-ctx.Done():
+			r.mu.Lock()
+			for d, part := range r.inFlight {
+				if part.abandoned {
+					continue
+				}
+				amqpmsg.DeliveryLogger(component, *d).Warn("Дедлайн остановки истёк, возвращаем задачу в очередь")
+				d.Nack(false, true)
+				part.abandoned = true
+				part.cancel()
+			}
+			r.mu.Unlock()
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.connected = false
+	metrics.SetConnected(false)
+	if r.conn == nil {
+		return nil
+	}
+	return r.conn.Close()
+}
+
 func (r *rabbitConsumer) consumeTasks() error {
 	r.mu.Lock()
 	if !r.connected {
@@ -71,10 +187,11 @@ func (r *rabbitConsumer) consumeTasks() error {
 	ctx := r.ctx
 	svc := r.svc
 	r.mu.Unlock()
+	concurrency := max(r.cfg.ConcurrentParts, 1)
 
 	msgs, err := ch.Consume(
 		queueName,
-		"",
+		consumerTag,
 		false,
 		false,
 		false,
@@ -85,50 +202,118 @@ func (r *rabbitConsumer) consumeTasks() error {
 		return fmt.Errorf("не удалось подписаться на очередь %s: %w", queueName, err)
 	}
 
+	done := make(chan struct{})
+	r.mu.Lock()
+	r.active = true
+	r.done = done
+	r.mu.Unlock()
 	go func() {
-		log.Printf("[rabbitConsumer] Старт consumeTasks для %s", queueName)
+		// slots ограничивает число одновременно считаемых частей; done закрывается,
+		// когда досчитаны все начатые.
+		slots := make(chan struct{}, concurrency)
+		var parts sync.WaitGroup
+		defer close(done)
+		defer parts.Wait()
+		defer r.setActive(false)
+		logging.Component(component).Info("Начинаем читать задачи", "queue", queueName, "concurrentParts", concurrency)
 		for {
 			select {
 			case <-ctx.Done():
-				log.Println("[rabbitConsumer] Context done, останавливаем consumeTasks...")
+				logging.Component(component).Info("Контекст отменён, прекращаем читать задачи")
 				return
 			case d, ok := <-msgs:
 				if !ok {
-					log.Println("[rabbitConsumer] Канал msgs закрыт, выходим из consumeTasks...")
+					logging.Component(component).Info("Канал доставок закрыт, прекращаем читать задачи")
 					return
 				}
-				var req types.CrackHashManagerRequest
-				if err := xml.Unmarshal(d.Body, &req); err != nil {
-					log.Printf("[rabbitConsumer] Ошибка парсинга XML: %v", err)
-					d.Nack(false, false)
+				// После Shutdown брокер ещё досылает уже выданные задачи: возвращаем их в очередь.
+				if r.isStopping() {
+					d.Nack(false, true)
 					continue
 				}
-				log.Printf("[rabbitConsumer] Получили задачу: requestID=%s, hash=%s, maxLength=%d, partNumber=%d, partCount=%d",
-					req.RequestId, req.Hash, req.MaxLength, req.PartNumber, req.PartCount)
-
-				results := svc.ProcessTask(req.Hash, req.MaxLength, req.Alphabet.Symbols, req.PartNumber, req.PartCount)
-
-				workerResp := types.CrackHashWorkerResponse{
-					RequestId:  req.RequestId,
-					PartNumber: req.PartNumber,
+				if err := amqpmsg.VerifyDelivery(r.keys, d); err != nil {
+					amqpmsg.DeliveryLogger(component, d).Warn("Задача отклонена", "error", err)
+					r.reject(d, err)
+					continue
 				}
-				workerResp.Answers.Words = results
-
-				if pubErr := r.publishResponse(workerResp); pubErr != nil {
-					log.Printf("[rabbitConsumer] Ошибка при отправке ответа requestID=%s: %v", req.RequestId, pubErr)
-					d.Nack(false, true)
+				var req types.CrackHashManagerRequest
+				if err := xml.Unmarshal(d.Body, &req); err != nil {
+					amqpmsg.DeliveryLogger(component, d).Warn("Ошибка разбора XML задачи", "error", err)
+					r.reject(d, err)
 					continue
 				}
-				log.Printf("[rabbitConsumer] Отправили ответ по requestID=%s, найдено слов=%d", req.RequestId, len(results))
-				d.Ack(false)
+				slots <- struct{}{}
+				parts.Add(1)
+				go func() {
+					defer parts.Done()
+					defer func() { <-slots }()
+					r.process(d, req, svc)
+				}()
 			}
 		}
 	}()
 	return nil
 }
 
+// process считает часть задачи в спане "<task_queue> process", продолжающем трассу
+// менеджера из заголовков сообщения, и публикует ответ в той же трассе.
+func (r *rabbitConsumer) process(d amqp091.Delivery, req types.CrackHashManagerRequest, svc handlers.WorkerService) {
+	ctx, span := amqpmsg.StartSpan(amqpmsg.DeliveryContext(context.Background(), d), "process", r.cfg.TaskQueueName, req.RequestId, req.PartNumber)
+	defer span.End()
+	logger := logging.Part(component, req.RequestId, req.PartNumber)
+	logger.Info("Получили задачу", "hash", req.Hash, "maxLength", req.MaxLength, "partCount", req.PartCount)
+
+	partCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r.setInFlight(&d, cancel)
+	results, err := svc.ProcessTask(logging.WithLogger(partCtx, logger), req.Hash, req.MaxLength, req.Alphabet.Symbols, req.PartNumber, req.PartCount)
+	if !r.finishInFlight(&d) {
+		logger.Warn("Дедлайн остановки истёк, перебор прерван, ответ не отправляем")
+		return
+	}
+	if err != nil {
+		tracing.RecordError(span, err)
+		logger.Error("Перебор прерван, задача возвращена в очередь", "error", err)
+		d.Nack(false, true)
+		return
+	}
+
+	workerResp := types.CrackHashWorkerResponse{
+		RequestId:  req.RequestId,
+		PartNumber: req.PartNumber,
+		WorkerId:   r.cfg.WorkerID,
+	}
+	workerResp.Answers.Words = results
+
+	if pubErr := r.publishResponse(ctx, workerResp); pubErr != nil {
+		tracing.RecordError(span, pubErr)
+		logger.Error("Ошибка отправки ответа, задача возвращена в очередь", "error", pubErr)
+		d.Nack(false, true)
+		return
+	}
+	logger.Info("Ответ отправлен", "words", len(results))
+	d.Ack(false)
+}
+
+// dial подключается по amqps://, если в URI указана эта схема; сертификаты берутся из
+// RABBIT_*_FILE. Без amqps:// файлы не используются, поэтому такая конфигурация считается ошибкой.
+func dial(cfg config.Config) (*amqp091.Connection, error) {
+	files := tlsconfig.Files{CAFile: cfg.RabbitCAFile, CertFile: cfg.RabbitCertFile, KeyFile: cfg.RabbitKeyFile}
+	if !strings.HasPrefix(cfg.RabbitURI, "amqps://") {
+		if files.Enabled() {
+			return nil, errors.New("заданы сертификаты RabbitMQ, но RABBIT_URI не amqps://")
+		}
+		return amqp091.Dial(cfg.RabbitURI)
+	}
+	tlsCfg, err := tlsconfig.Client(files)
+	if err != nil {
+		return nil, err
+	}
+	return amqp091.DialTLS(cfg.RabbitURI, tlsCfg)
+}
+
 func (r *rabbitConsumer) connectAndDeclare() error {
-	conn, err := amqp091.Dial(r.cfg.RabbitURI)
+	conn, err := dial(r.cfg)
 	if err != nil {
 		return fmt.Errorf("не удалось подключиться к RabbitMQ: %w", err)
 	}
@@ -137,6 +322,13 @@ func (r *rabbitConsumer) connectAndDeclare() error {
 		conn.Close()
 		return fmt.Errorf("не удалось открыть канал: %w", err)
 	}
+	// Брокер выдаёт воркеру не больше TASK_PREFETCH задач сверх подтверждённых: остальные
+	// достаются свободным воркерам, а не ждут в буфере занятого.
+	if err := ch.Qos(r.cfg.Prefetch(), 0, false); err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("не удалось настроить prefetch: %w", err)
+	}
 
 	if err := ch.ExchangeDeclare(
 		r.cfg.TaskExchange, "direct",
@@ -164,6 +356,12 @@ func (r *rabbitConsumer) connectAndDeclare() error {
 		return fmt.Errorf("не удалось bind %s: %w", taskQ.Name, err)
 	}
 
+	if _, err := ch.QueueDeclare(amqpmsg.DeadLetterQueue(taskQ.Name), true, false, false, false, nil); err != nil {
+		ch.Close()
+		conn.Close()
+		return fmt.Errorf("не удалось объявить очередь %s: %w", amqpmsg.DeadLetterQueue(taskQ.Name), err)
+	}
+
 	if err := ch.ExchangeDeclare(
 		r.cfg.ResponseExchange, "direct",
 		true, false, false, false, nil,
@@ -197,8 +395,9 @@ func (r *rabbitConsumer) connectAndDeclare() error {
 	r.respQueueName = respQ.Name
 	r.connected = true
 	r.mu.Unlock()
+	metrics.SetConnected(true)
 
-	log.Println("[rabbitConsumer] Успешно подключились к RabbitMQ и объявили exchange/queue")
+	logging.Component(component).Info("Подключились к RabbitMQ и объявили exchange/queue", "prefetch", r.cfg.Prefetch())
 	return nil
 }
 
@@ -212,39 +411,67 @@ func (r *rabbitConsumer) startCloseWatcher() {
 	go func() {
 		err, ok := <-closeCh
 		if !ok || err == nil {
-			log.Println("[rabbitConsumer] NotifyClose: канал закрыт без ошибки")
+			logging.Component(component).Info("Соединение с RabbitMQ закрыто без ошибки")
 			return
 		}
-		log.Printf("[rabbitConsumer] Соединение закрыто: %v", err)
+		logging.Component(component).Error("Соединение с RabbitMQ закрыто", "error", err)
 		r.reconnectLoop()
 	}()
 }
 
 func (r *rabbitConsumer) reconnectLoop() {
 	r.mu.Lock()
+	if r.stopping {
+		r.mu.Unlock()
+		return
+	}
 	r.connected = false
 	alreadyConsuming := r.consuming
 	r.mu.Unlock()
+	metrics.SetConnected(false)
 
 	for {
-		log.Println("[rabbitConsumer] Пытаемся переподключиться к RabbitMQ...")
+		logging.Component(component).Info("Пытаемся переподключиться к RabbitMQ")
 		if err := r.connectAndDeclare(); err == nil {
 			r.startCloseWatcher()
-			log.Println("[rabbitConsumer] Успешно переподключились к RabbitMQ!")
+			metrics.RabbitReconnects.Inc()
+			logging.Component(component).Info("Переподключились к RabbitMQ")
 			if alreadyConsuming {
-				log.Println("[rabbitConsumer] Повторный вызов consumeTasks() после reconnect")
+				logging.Component(component).Info("Повторно подписываемся на задачи после переподключения")
 				if err2 := r.consumeTasks(); err2 != nil {
-					log.Printf("[rabbitConsumer] Ошибка при consumeTasks: %v", err2)
+					logging.Component(component).Error("Ошибка повторной подписки на задачи", "error", err2)
 				}
 			}
 			return
 		}
-		log.Printf("[rabbitConsumer] Ошибка при reconnect. Ждём 5с...")
+		logging.Component(component).Warn("Ошибка переподключения, повторим через 5с")
 		time.Sleep(5 * time.Second)
+		if r.isStopping() {
+			return
+		}
+	}
+}
+
+// reject перекладывает задачу в dead-letter очередь; если это не удалось, сообщение
+// отклоняется без повторной доставки, чтобы не зациклиться.
+func (r *rabbitConsumer) reject(d amqp091.Delivery, reason error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := amqpmsg.DeadLetter(r.channel, r.taskQueueName, d, reason); err != nil {
+		amqpmsg.DeliveryLogger(component, d).Error("Задача не переложена в dead-letter очередь", "error", err)
+		d.Nack(false, false)
+		return
 	}
+	d.Ack(false)
 }
 
-func (r *rabbitConsumer) publishResponse(resp types.CrackHashWorkerResponse) error {
+func (r *rabbitConsumer) publishResponse(ctx context.Context, resp types.CrackHashWorkerResponse) (err error) {
+	ctx, span := amqpmsg.StartSpan(ctx, "publish", r.cfg.ResponseExchange, resp.RequestId, resp.PartNumber)
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if !r.connected {
@@ -257,16 +484,12 @@ func (r *rabbitConsumer) publishResponse(resp types.CrackHashWorkerResponse) err
 	}
 	xmlData = append([]byte(xml.Header), xmlData...)
 
-	log.Printf("[rabbitConsumer] Публикуем ответ в queue=%s (requestID=%s)", r.cfg.ResponseQueue, resp.RequestId)
+	logging.Part(component, resp.RequestId, resp.PartNumber).Debug("Публикуем ответ", "queue", r.cfg.ResponseQueue)
 	if err := r.channel.Publish(
 		r.cfg.ResponseExchange,
 		r.cfg.ResponseQueue,
 		false, false,
-		amqp091.Publishing{
-			ContentType:  "application/xml",
-			Body:         xmlData,
-			DeliveryMode: amqp091.Persistent,
-		},
+		amqpmsg.WithCorrelation(ctx, amqpmsg.SignedPublishing(r.keys, xmlData), resp.RequestId, resp.PartNumber),
 	); err != nil {
 		return fmt.Errorf("publishResponse error: %w", err)
 	}
@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -34,15 +35,16 @@ func (m *MockWorkerService) EXPECT() *MockWorkerServiceMockRecorder {
 }
 
 // ProcessTask mocks base method.
-func (m *MockWorkerService) ProcessTask(arg0 string, arg1 int, arg2 []string, arg3, arg4 int) []string {
+func (m *MockWorkerService) ProcessTask(arg0 context.Context, arg1 string, arg2 int, arg3 []string, arg4, arg5 int) ([]string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ProcessTask", arg0, arg1, arg2, arg3, arg4)
+	ret := m.ctrl.Call(m, "ProcessTask", arg0, arg1, arg2, arg3, arg4, arg5)
 	ret0, _ := ret[0].([]string)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // ProcessTask indicates an expected call of ProcessTask.
-func (mr *MockWorkerServiceMockRecorder) ProcessTask(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+func (mr *MockWorkerServiceMockRecorder) ProcessTask(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessTask", reflect.TypeOf((*MockWorkerService)(nil).ProcessTask), arg0, arg1, arg2, arg3, arg4)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessTask", reflect.TypeOf((*MockWorkerService)(nil).ProcessTask), arg0, arg1, arg2, arg3, arg4, arg5)
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
@@ -8,22 +9,116 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"CrackHash/internal/logging"
+	"CrackHash/internal/tracing"
 	"CrackHash/worker/internal/handlers"
+	"CrackHash/worker/internal/metrics"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type workerServiceImpl struct{}
+// Settings — параметры воркера, которые меняются без перезапуска по SIGHUP.
+type Settings struct {
+	// HashGoroutines — сколько горутин перебирают одну часть; 0 — по числу CPU.
+	HashGoroutines int
+	// CPULimit — какую долю CPU машины в процентах может занять перебор; 0 — без ограничения.
+	CPULimit int
+}
+
+func (s Settings) Validate() error {
+	if s.HashGoroutines < 0 {
+		return fmt.Errorf("hashGoroutines не может быть отрицательным")
+	}
+	if s.CPULimit < 0 || s.CPULimit > 100 {
+		return fmt.Errorf("cpuLimit должен быть от 0 до 100")
+	}
+	return nil
+}
+
+// changes перечисляет отличающиеся от prev значения: параметр -> "старое -> новое".
+func (s Settings) changes(prev Settings) map[string]string {
+	diff := map[string]string{}
+	if s.HashGoroutines != prev.HashGoroutines {
+		diff["hashGoroutines"] = fmt.Sprintf("%d -> %d", prev.HashGoroutines, s.HashGoroutines)
+	}
+	if s.CPULimit != prev.CPULimit {
+		diff["cpuLimit"] = fmt.Sprintf("%d -> %d", prev.CPULimit, s.CPULimit)
+	}
+	return diff
+}
+
+// goroutines — число горутин перебора с учётом значения по умолчанию.
+func (s Settings) goroutines() int {
+	if s.HashGoroutines > 0 {
+		return s.HashGoroutines
+	}
+	return max(runtime.NumCPU(), 1)
+}
+
+type WorkerServiceImpl struct {
+	// settings общие для всех копий сервиса; часть читает их один раз при старте.
+	settings *atomic.Pointer[Settings]
+	throttle *throttle
+}
+
+var _ handlers.WorkerService = WorkerServiceImpl{}
+
+type Option func(*WorkerServiceImpl)
+
+func WithSettings(s Settings) Option {
+	return func(w *WorkerServiceImpl) {
+		w.settings.Store(&s)
+	}
+}
+
+func NewWorkerService(opts ...Option) WorkerServiceImpl {
+	w := WorkerServiceImpl{settings: &atomic.Pointer[Settings]{}, throttle: newThrottle()}
+	w.settings.Store(&Settings{})
+	for _, opt := range opts {
+		opt(&w)
+	}
+	return w
+}
 
-func NewWorkerService() handlers.WorkerService {
-	return workerServiceImpl{}
+func (w WorkerServiceImpl) Settings() Settings {
+	return *w.settings.Load()
 }
 
-func (w workerServiceImpl) ProcessTask(
+// UpdateSettings атомарно заменяет настройки и логирует изменения; source — откуда они
+// пришли. Начатые части досчитываются с прежним числом горутин, а лимит CPU действует сразу.
+func (w WorkerServiceImpl) UpdateSettings(s Settings, source string) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	prev := *w.settings.Swap(&s)
+	for name, change := range s.changes(prev) {
+		logging.Component("workerService").Info("Настройка изменена", "setting", name, "change", change, "source", source)
+	}
+	return nil
+}
+
+// ProcessTask перебирает часть partNumber из partCount. Отмена ctx проверяется вместе
+// с лимитом CPU, раз в throttleEvery кандидатов: тогда перебор прерывается и
+// возвращается ctx.Err(), а найденные к этому моменту слова отбрасываются.
+func (w WorkerServiceImpl) ProcessTask(
+	ctx context.Context,
 	hash string,
 	maxLength int,
 	alphabet []string,
 	partNumber, partCount int,
-) []string {
+) ([]string, error) {
+	start := time.Now()
+	_, span := tracing.Tracer().Start(ctx, "WorkerService.ProcessTask", trace.WithAttributes(
+		attribute.String("hash", hash),
+		attribute.Int("maxLength", maxLength),
+		attribute.Int(logging.KeyPartNumber, partNumber),
+		attribute.Int("partCount", partCount),
+	))
+	defer span.End()
 
 	n := len(alphabet)
 	total := 0
@@ -36,10 +131,7 @@ func (w workerServiceImpl) ProcessTask(
 	targetHash := strings.ToLower(hash)
 	var results []string
 
-	numWorkers := runtime.NumCPU()
-	if numWorkers < 1 {
-		numWorkers = 1
-	}
+	numWorkers := w.Settings().goroutines()
 
 	rangeSize := endIndex - startIndex
 	segSize := rangeSize / numWorkers
@@ -47,8 +139,10 @@ func (w workerServiceImpl) ProcessTask(
 		segSize = 1
 	}
 
-	fmt.Printf("[workerService] Начинаем ProcessTask: hash=%s maxLength=%d partNumber=%d/%d total=%d startIndex=%d endIndex=%d\n",
-		hash, maxLength, partNumber, partCount, total, startIndex, endIndex)
+	logger := logging.FromContext(ctx).With(logging.KeyComponent, "workerService")
+	logger.Debug("Начинаем перебор части",
+		"hash", hash, "maxLength", maxLength, "partCount", partCount,
+		"total", total, "startIndex", startIndex, "endIndex", endIndex, "goroutines", numWorkers)
 
 	var wg sync.WaitGroup
 	resChan := make(chan string, 100)
@@ -62,7 +156,17 @@ func (w workerServiceImpl) ProcessTask(
 		wg.Add(1)
 		go func(s, e int) {
 			defer wg.Done()
+			w.throttle.running.Add(1)
+			defer w.throttle.running.Add(-1)
+			batchStart := time.Now()
 			for idx := s; idx < e; idx++ {
+				if (idx-s)%throttleEvery == throttleEvery-1 {
+					if ctx.Err() != nil {
+						return
+					}
+					w.throttle.pause(w.Settings().CPULimit, time.Since(batchStart))
+					batchStart = time.Now()
+				}
 				word := indexToWord(idx, maxLength, alphabet)
 				if word == "" {
 					continue
@@ -84,9 +188,16 @@ func (w workerServiceImpl) ProcessTask(
 	for w := range resChan {
 		results = append(results, w)
 	}
+	if err := ctx.Err(); err != nil {
+		tracing.RecordError(span, err)
+		logger.Warn("Перебор части прерван", "elapsed", time.Since(start), "error", err)
+		return nil, err
+	}
 
-	fmt.Printf("[workerService] Завершили ProcessTask: hash=%s, найдено %d слов\n", hash, len(results))
-	return results
+	metrics.ObservePart(rangeSize, time.Since(start))
+	span.SetAttributes(attribute.Int("candidates", rangeSize), attribute.Int("words", len(results)))
+	logger.Info("Перебор части завершён", "candidates", rangeSize, "words", len(results), "elapsed", time.Since(start))
+	return results, nil
 }
 
 func indexToWord(index int, maxLength int, alphabet []string) string {
@@ -1,12 +1,22 @@
 package service_test
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
+	"strings"
 	"testing"
+	"time"
 
+	"CrackHash/internal/tracing"
+	"CrackHash/worker/internal/metrics"
 	"CrackHash/worker/internal/service"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func TestWorkerService_ProcessTask_Success(t *testing.T) {
@@ -54,7 +64,8 @@ func TestWorkerService_ProcessTask_Success(t *testing.T) {
 	workerSvc := service.NewWorkerService()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := workerSvc.ProcessTask(tt.args.hash, tt.args.maxLength, tt.args.alphabet, tt.args.partNumber, tt.args.partCount)
+			got, err := workerSvc.ProcessTask(context.Background(), tt.args.hash, tt.args.maxLength, tt.args.alphabet, tt.args.partNumber, tt.args.partCount)
+			require.NoError(t, err)
 			if got == nil {
 				got = []string{}
 			}
@@ -94,7 +105,8 @@ func TestWorkerService_ProcessTask_NoMatch(t *testing.T) {
 	workerSvc := service.NewWorkerService()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := workerSvc.ProcessTask(tt.args.hash, tt.args.maxLength, tt.args.alphabet, tt.args.partNumber, tt.args.partCount)
+			got, err := workerSvc.ProcessTask(context.Background(), tt.args.hash, tt.args.maxLength, tt.args.alphabet, tt.args.partNumber, tt.args.partCount)
+			require.NoError(t, err)
 			if got == nil {
 				got = []string{}
 			}
@@ -102,3 +114,63 @@ func TestWorkerService_ProcessTask_NoMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestWorkerService_ProcessTask_Metrics(t *testing.T) {
+	parts := testutil.ToFloat64(metrics.PartsProcessed)
+	candidates := testutil.ToFloat64(metrics.Candidates)
+
+	// Вторая из двух частей пространства {a,b,c} длины до 2: кандидаты 6..11.
+	service.NewWorkerService().ProcessTask(context.Background(), "187ef4436122d1cc2f40dc2b92f0eba0", 2, []string{"a", "b", "c"}, 1, 2)
+
+	require.Equal(t, parts+1, testutil.ToFloat64(metrics.PartsProcessed))
+	require.Equal(t, candidates+6, testutil.ToFloat64(metrics.Candidates))
+	require.Equal(t, 1, testutil.CollectAndCount(metrics.ProcessTaskDuration))
+}
+
+func TestWorkerService_ProcessTask_Span(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	tp := tracing.Start("worker-test", sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() {
+		tp.Shutdown(context.Background())
+		otel.SetTracerProvider(prev)
+	})
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "task_queue process")
+	service.NewWorkerService().ProcessTask(ctx, "900150983cd24fb0d6963f7d28e17f72", 3, []string{"a", "b", "c"}, 0, 1)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	require.Equal(t, "WorkerService.ProcessTask", spans[0].Name)
+	require.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent.SpanID())
+	require.Contains(t, spans[0].Attributes, attribute.Int("words", 1))
+}
+
+func TestWorkerService_UpdateSettings(t *testing.T) {
+	workerSvc := service.NewWorkerService(service.WithSettings(service.Settings{HashGoroutines: 3}))
+	require.Equal(t, 3, workerSvc.Settings().HashGoroutines)
+
+	require.Error(t, workerSvc.UpdateSettings(service.Settings{HashGoroutines: -1}, "test"))
+	require.Error(t, workerSvc.UpdateSettings(service.Settings{CPULimit: 150}, "test"))
+	require.Equal(t, 3, workerSvc.Settings().HashGoroutines)
+
+	require.NoError(t, workerSvc.UpdateSettings(service.Settings{HashGoroutines: 1}, "test"))
+	require.Equal(t, 1, workerSvc.Settings().HashGoroutines)
+	// Результат не зависит от числа горутин.
+	got, err := workerSvc.ProcessTask(context.Background(), "900150983cd24fb0d6963f7d28e17f72", 3, []string{"a", "b", "c"}, 0, 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"abc"}, got)
+}
+
+func TestWorkerService_ProcessTask_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	// Пространство 36^1..36^6 не перебрать за секунду: результат даёт только отмена.
+	start := time.Now()
+	got, err := service.NewWorkerService().ProcessTask(ctx, "900150983cd24fb0d6963f7d28e17f72", 6, strings.Split("abcdefghijklmnopqrstuvwxyz0123456789", ""), 0, 1)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, got)
+	require.Less(t, time.Since(start), time.Second)
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// throttleEvery — через сколько кандидатов горутина перебора сверяется с лимитом CPU.
+const throttleEvery = 1 << 14
+
+// throttle делит бюджет CPU_LIMIT между всеми горутинами перебора воркера, включая
+// горутины параллельно считаемых частей: горутина, отработав пачку кандидатов, спит
+// столько, чтобы её доля времени на CPU уложилась в бюджет.
+type throttle struct {
+	running atomic.Int64
+	cpus    int
+	sleep   func(time.Duration)
+}
+
+func newThrottle() *throttle {
+	return &throttle{cpus: runtime.NumCPU(), sleep: time.Sleep}
+}
+
+// pause вызывается после пачки, занявшей busy; limit — CPU_LIMIT в процентах всей машины.
+func (t *throttle) pause(limit int, busy time.Duration) {
+	if limit <= 0 || limit >= 100 {
+		return
+	}
+	budget := float64(t.cpus) * float64(limit) / 100
+	duty := budget / float64(max(t.running.Load(), 1))
+	if duty >= 1 {
+		return
+	}
+	t.sleep(time.Duration(float64(busy) * (1 - duty) / duty))
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottle_SharesBudgetBetweenGoroutines(t *testing.T) {
+	var slept time.Duration
+	th := &throttle{cpus: 4, sleep: func(d time.Duration) { slept = d }}
+	pause := func(limit int, running int64) time.Duration {
+		slept = 0
+		th.running.Store(running)
+		th.pause(limit, 10*time.Millisecond)
+		return slept
+	}
+
+	require.Zero(t, pause(0, 8), "без лимита")
+	require.Zero(t, pause(100, 8), "лимит на всю машину")
+	// 50% от 4 CPU — 2 ядра: двум горутинам спать не нужно.
+	require.Zero(t, pause(50, 2))
+	// Четырём горутинам достаётся по половине ядра: сон равен времени работы.
+	require.Equal(t, 10*time.Millisecond, pause(50, 4))
+	// Восьми — по четверти: спят втрое дольше, чем работают.
+	require.Equal(t, 30*time.Millisecond, pause(50, 8))
+}
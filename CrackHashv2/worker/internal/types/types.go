@@ -21,6 +21,7 @@ type CrackHashWorkerResponse struct {
 	XMLName    xml.Name `xml:"CrackHashWorkerResponse"`
 	RequestId  string   `xml:"RequestId"`
 	PartNumber int      `xml:"PartNumber"`
+	WorkerId   string   `xml:"WorkerId,omitempty"`
 	Answers    struct {
 		Words []string `xml:"words"`
 	} `xml:"Answers"`